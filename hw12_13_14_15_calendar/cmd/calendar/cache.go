@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	cachestorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/cache"
+)
+
+const statsReadTimeout = 5 * time.Second
+
+// withCache wraps the storage with the cache unless its capacity is zero.
+// The cache statistics are served on StatsAddr until ctx is done.
+func withCache(
+	ctx context.Context,
+	conf config.CacheConf,
+	st storage.Control,
+	log *logger.Logger,
+) (storage.Control, error) {
+	if conf.Capacity <= 0 {
+		return st, nil
+	}
+
+	var ttl time.Duration
+	if conf.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(conf.TTL); err != nil {
+			return nil, fmt.Errorf("invalid cache ttl: %w", err)
+		}
+	}
+	cached := cachestorage.New(st, conf.Capacity, ttl)
+
+	if conf.StatsAddr != "" {
+		statsServer := &http.Server{
+			Addr:              conf.StatsAddr,
+			Handler:           cached.Handler(),
+			ReadHeaderTimeout: statsReadTimeout,
+		}
+		go func() {
+			if err := statsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to serve cache statistics: " + err.Error())
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			statsServer.Close()
+		}()
+	}
+	return cached, nil
+}
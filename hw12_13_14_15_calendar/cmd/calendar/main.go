@@ -3,10 +3,13 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// the events are checked in their time zones, the runtime image has no zone database
+	_ "time/tzdata"
 
 	app "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/app/calendar"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
@@ -15,10 +18,14 @@ import (
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
-var configFile string
+var (
+	configFile string
+	flags      *config.Flags
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "/etc/calendar/config.toml", "Path to configuration file")
+	flags = config.NewFlags(flag.CommandLine, config.CalendarConfig{})
 }
 
 func main() {
@@ -29,48 +36,101 @@ func main() {
 		return
 	}
 
-	config := config.NewCalendarConfig(configFile)
-	log := logger.New(config.Logger.Level, config.Logger.Output)
+	config, err := config.NewCalendarConfig(configFile, flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:\n"+err.Error())
+		os.Exit(1)
+	}
+	log, err := logger.NewFromConfig(config.Logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create logger: "+err.Error())
+		os.Exit(1)
+	}
 	defer log.Close()
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
-	storage := storage.NewStorage(config.Storage)
-	err := storage.Connect(ctx)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(ctx, config.Storage, flag.Arg(1)); err != nil {
+			log.Error("migration failed: " + err.Error())
+			log.Close()
+			os.Exit(1) //nolint:gocritic
+		}
+		return
+	}
+
+	storage, err := storage.NewStorage(config.Storage)
+	if err == nil {
+		err = storage.Connect(ctx)
+	}
 	if err != nil {
 		log.Error("failed to connect to storage: " + err.Error())
 		log.Close()
-		os.Exit(1) //nolint:gocritic
+		os.Exit(1)
 	}
 	defer storage.Close(ctx)
 
+	storage, err = withCache(ctx, config.Cache, storage, log)
+	if err != nil {
+		log.Error("failed to set up cache: " + err.Error())
+		log.Close()
+		os.Exit(1)
+	}
+
+	limiter, err := newRateLimiter(config.RateLimit)
+	if err != nil {
+		log.Error("failed to set up rate limiting: " + err.Error())
+		log.Close()
+		os.Exit(1)
+	}
+
+	tlsConf, err := newTLS(config.Endpoint.TLS)
+	if err != nil {
+		log.Error("failed to set up TLS: " + err.Error())
+		log.Close()
+		os.Exit(1)
+	}
+
+	shutdownTimeout, err := time.ParseDuration(config.Endpoint.ShutdownTimeout)
+	if err != nil {
+		log.Error("invalid shutdown timeout: " + err.Error())
+		log.Close()
+		os.Exit(1)
+	}
+
 	calendar := app.New(log, storage)
 	server := internalgrpc.NewServer(config.Endpoint.Host,
 		config.Endpoint.GRPCPort,
 		config.Endpoint.HTTPPort,
 		log,
 		calendar,
+		limiter,
+		tlsConf,
+		internalgrpc.Auth{
+			TrustUserHeader: config.Endpoint.TrustUserHeader,
+			AllowAnonymous:  config.Endpoint.AllowAnonymous,
+		},
 	)
 
-	go func() {
-		<-ctx.Done()
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-		defer cancel()
+	log.Info("Calendar is running...")
+	serveErr := server.Start(ctx)
+	if serveErr != nil {
+		log.Error("calendar server failed: " + serveErr.Error())
+	}
 
-		if err := server.Stop(ctx); err != nil {
-			log.Error("failed to stop calendar server: " + err.Error())
-		}
-		log.Info("Shutted down")
-	}()
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stopCancel()
+	if err := server.Stop(stopCtx); err != nil {
+		log.Error("failed to stop calendar server gracefully: " + err.Error())
+	}
+	log.Info("Calendar is stopped")
 
-	log.Info("Calendar is running...")
-	if err := server.Start(ctx); err != nil {
-		log.Error("failed to start calendar server: " + err.Error())
-		cancel()
+	if serveErr != nil {
+		stopCancel()
+		storage.Close(ctx)
+		log.Close()
 		os.Exit(1)
 	}
-	log.Info("Calendar is stopped")
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/migrate"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+var errNotMigratable = errors.New("storage mode has no schema to migrate")
+
+// runMigrate handles "migrate up|down|status", the migrations are applied explicitly regardless of autoMigrate.
+func runMigrate(ctx context.Context, conf config.StorageConf, command string) error {
+	conf.AutoMigrate = false
+	st, err := storage.NewStorage(conf)
+	if err != nil {
+		return err
+	}
+	migratable, ok := st.(storage.Migratable)
+	if !ok {
+		return fmt.Errorf("%w: %v", errNotMigratable, conf.Mode)
+	}
+
+	if err := st.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to storage: %w", err)
+	}
+	defer st.Close(ctx)
+
+	migrator, err := migratable.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no migrations to apply")
+		}
+		for _, m := range applied {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d %s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(statuses)
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+	return nil
+}
+
+func printMigrationStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	w.Flush()
+}
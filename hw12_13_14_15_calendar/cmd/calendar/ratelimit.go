@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/ratelimit"
+	internalgrpc "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc"
+)
+
+// newRateLimiter returns nil when neither the rate nor the method rates are set.
+func newRateLimiter(conf config.RateLimitConf) (internalgrpc.RateLimiter, error) {
+	if conf.Rate == "" && len(conf.Methods) == 0 {
+		return nil, nil
+	}
+
+	rate, err := ratelimit.ParseRate(conf.Rate, conf.Burst)
+	if err != nil {
+		return nil, fmt.Errorf("rate: %w", err)
+	}
+	methods := make(map[string]ratelimit.Rate, len(conf.Methods))
+	for i, m := range conf.Methods {
+		if methods[m.Method], err = ratelimit.ParseRate(m.Rate, m.Burst); err != nil {
+			return nil, fmt.Errorf("methods[%d].rate: %w", i, err)
+		}
+	}
+	return ratelimit.New(rate, methods, conf.MaxClients), nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	internalgrpc "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/tlsconfig"
+)
+
+// newTLS loads the certificates of the endpoint, it returns nil if TLS is disabled.
+func newTLS(conf config.TLSConf) (internalgrpc.TLS, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	server, err := tlsconfig.NewServer(conf)
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/humantime"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/ical"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type cli struct {
+	client pb.EventsClient
+	// user is the caller email, the default owner of the events
+	user string
+	json bool
+	now  time.Time
+	in   io.Reader
+	out  io.Writer
+}
+
+type command struct {
+	name     string
+	synopsis string
+	summary  string
+	run      func(c *cli, ctx context.Context, args []string) error
+}
+
+// commands are filled in init, the commands look their usage up in them.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"create", "[event flags] [-file event.yaml]", "create an event", (*cli).create},
+		{"get", "ID...", "show the events", (*cli).get},
+		{"update", "ID [event flags] [-file event.yaml]", "change the given fields of the event", (*cli).update},
+		{"delete", "ID...", "delete the events", (*cli).remove},
+		{"list", "day|week|month [-date DATE] [-owner EMAIL | -calendar ID]", "list the events of the period", (*cli).list},
+		{"search", "QUERY [-from TIME] [-to TIME] [-limit N]", "search the events by the words", (*cli).search},
+		{"import", "FILE.ics|- [-owner EMAIL | -calendar ID] [-atomic]", "add the events of a .ics file", (*cli).importICS},
+		{"export", "[-from TIME] [-to TIME] [-owner EMAIL | -calendar ID] [-o FILE]", "write a .ics file", (*cli).exportICS},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	i := slices.IndexFunc(commands, func(cmd command) bool { return cmd.name == name })
+	if i < 0 {
+		return command{}, false
+	}
+	return commands[i], true
+}
+
+// newFlagSet creates the flags of the command, their usage shows the synopsis.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		cmd, _ := findCommand(name)
+		fmt.Fprintf(fs.Output(), "Usage: client %v %v\n", name, cmd.synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs lets the flags follow the arguments, like list week -date tomorrow. The arguments after -- are kept as is.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func (c *cli) parseTime(name, value string) (time.Time, error) {
+	t, err := humantime.Parse(value, c.now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%v: %w", name, err)
+	}
+	return t, nil
+}
+
+func (c *cli) create(ctx context.Context, args []string) error {
+	fs := newFlagSet("create")
+	var f eventFlags
+	f.define(fs)
+	if rest, err := parseArgs(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+
+	input, _, err := c.readEvent(fs, f)
+	if err != nil {
+		return err
+	}
+	if input.Start == "" {
+		return errors.New("-start is required")
+	}
+	if input.End == "" && input.Duration == "" {
+		input.Duration = "1h"
+	}
+	if input.Owner == "" {
+		input.Owner = c.user
+	}
+	event, err := c.toTransient(input, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.CreateEvent(ctx, &pb.NewEventRequest{Event: event})
+	if err != nil {
+		return err
+	}
+	return c.printEvent(resp.GetEvent())
+}
+
+func (c *cli) get(ctx context.Context, args []string) error {
+	fs := newFlagSet("get")
+	ids, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return errors.New("the event id is required")
+	}
+
+	events := make([]*pb.PersistedEvent, 0, len(ids))
+	for _, id := range ids {
+		resp, err := c.client.GetEvent(ctx, &pb.EventIdRequest{Id: id})
+		if err != nil {
+			return err
+		}
+		events = append(events, resp.GetEvent())
+	}
+	if len(events) == 1 {
+		return c.printEvent(events[0])
+	}
+	return c.printEvents(events)
+}
+
+func (c *cli) update(ctx context.Context, args []string) error {
+	fs := newFlagSet("update")
+	var f eventFlags
+	f.define(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("one event id is required")
+	}
+
+	input, fields, err := c.readEvent(fs, f)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return errors.New("nothing to update, set the event flags or -file")
+	}
+
+	var current *pb.PersistedEvent
+	if input.Duration != "" && input.Start == "" {
+		// the duration counts from the current start
+		resp, err := c.client.GetEvent(ctx, &pb.EventIdRequest{Id: rest[0]})
+		if err != nil {
+			return err
+		}
+		current = resp.GetEvent()
+	}
+	event, err := c.toTransient(input, current)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+		Id:         rest[0],
+		Event:      event,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+	})
+	if err != nil {
+		return err
+	}
+	return c.printEvent(resp.GetEvent())
+}
+
+func (c *cli) remove(ctx context.Context, args []string) error {
+	fs := newFlagSet("delete")
+	ids, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return errors.New("the event id is required")
+	}
+
+	for _, id := range ids {
+		if _, err := c.client.DeleteEvent(ctx, &pb.EventIdRequest{Id: id}); err != nil {
+			return err
+		}
+		if !c.json {
+			fmt.Fprintf(c.out, "Deleted %v\n", id)
+		}
+	}
+	return nil
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	fs := newFlagSet("list")
+	date := fs.String("date", "today", "the day of the period, the week starts on Monday")
+	owner := fs.String("owner", c.user, "the owner of the events")
+	calendar := fs.String("calendar", "", "the calendar of the events instead of the owner")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("the period is required: day, week or month")
+	}
+
+	day, err := c.parseTime("date", *date)
+	if err != nil {
+		return err
+	}
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	var from, to time.Time
+	switch rest[0] {
+	case "day":
+		from, to = day, day.AddDate(0, 0, 1)
+	case "week":
+		from = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		to = from.AddDate(0, 0, 7)
+	case "month":
+		from = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		to = from.AddDate(0, 1, 0)
+	default:
+		return fmt.Errorf("%q is not a period, use day, week or month", rest[0])
+	}
+
+	events, err := c.listRange(ctx, *owner, *calendar, from, to)
+	if err != nil {
+		return err
+	}
+	return c.printEvents(events)
+}
+
+// listRange lists the events starting before to, the server lists them by the days and the weeks.
+func (c *cli) listRange(ctx context.Context, owner, calendar string, from, to time.Time) ([]*pb.PersistedEvent, error) {
+	if owner == "" && calendar == "" {
+		return nil, errors.New("-owner or -calendar is required, or server.user in the configuration")
+	}
+
+	var events []*pb.PersistedEvent
+	seen := make(map[string]bool)
+	for start := from; start.Before(to); start = start.AddDate(0, 0, 7) {
+		req := &pb.DateRequest{Owner: owner, CalendarId: calendar, Date: start.Unix()}
+		var resp *pb.VectorEventResponse
+		var err error
+		if to.Sub(start) <= 24*time.Hour {
+			resp, err = c.client.GetEventsForDay(ctx, req)
+		} else {
+			resp, err = c.client.GetEventsForWeek(ctx, req)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range resp.GetEvents() {
+			if !seen[e.GetId()] && e.GetStartTime() < to.Unix() {
+				seen[e.GetId()] = true
+				events = append(events, e)
+			}
+		}
+	}
+	slices.SortStableFunc(events, func(a, b *pb.PersistedEvent) int {
+		return int(a.GetStartTime() - b.GetStartTime())
+	})
+	return events, nil
+}
+
+func (c *cli) search(ctx context.Context, args []string) error {
+	fs := newFlagSet("search")
+	owner := fs.String("owner", c.user, "the owner of the events")
+	from := fs.String("from", "", "the events starting from the time")
+	to := fs.String("to", "", "the events starting before the time")
+	limit := fs.Int("limit", 50, "the number of the matches shown")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return errors.New("the query is required")
+	}
+
+	req := &pb.SearchRequest{Owner: *owner, Query: strings.Join(rest, " ")}
+	for _, bound := range []struct {
+		name  string
+		value string
+		dest  *int64
+	}{{"from", *from, &req.From}, {"to", *to, &req.To}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := c.parseTime(bound.name, bound.value)
+		if err != nil {
+			return err
+		}
+		*bound.dest = t.Unix()
+	}
+
+	var matches []*pb.EventMatch
+	for len(matches) < *limit {
+		req.PageSize = int32(min(*limit-len(matches), 100)) //nolint:gosec
+		resp, err := c.client.SearchEvents(ctx, req)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, resp.GetMatches()...)
+		if req.PageToken = resp.GetNextPageToken(); req.PageToken == "" || len(resp.GetMatches()) == 0 {
+			break
+		}
+	}
+	return c.printMatches(matches)
+}
+
+func (c *cli) importICS(ctx context.Context, args []string) error {
+	fs := newFlagSet("import")
+	owner := fs.String("owner", "", "the owner of the events, the organizer or the caller by default")
+	calendar := fs.String("calendar", "", "the calendar the events are added to")
+	atomic := fs.Bool("atomic", false, "create either all the events or none")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("one file is required, - reads the standard input")
+	}
+
+	r := c.in
+	if rest[0] != "-" {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	decoded, err := ical.Decode(r, c.now.Location())
+	if err != nil {
+		return fmt.Errorf("%v: %w", rest[0], err)
+	}
+	if len(decoded) == 0 {
+		return fmt.Errorf("%v: no events", rest[0])
+	}
+
+	events := make([]*pb.TransientEvent, 0, len(decoded))
+	for _, e := range decoded {
+		events = append(events, fromICS(e, firstSet(*owner, e.Organizer, c.user), *calendar))
+	}
+	resp, err := c.client.BatchCreateEvents(ctx, &pb.BatchCreateEventsRequest{Events: events, Atomic: *atomic})
+	if err != nil {
+		return err
+	}
+	return c.printResults(decoded, resp.GetResults())
+}
+
+func (c *cli) exportICS(ctx context.Context, args []string) error {
+	fs := newFlagSet("export")
+	from := fs.String("from", "", "the start of the period, the first day of this month by default")
+	to := fs.String("to", "", "the end of the period, a month after the start by default")
+	owner := fs.String("owner", c.user, "the owner of the events")
+	calendar := fs.String("calendar", "", "the calendar of the events instead of the owner")
+	output := fs.String("o", "-", "the file written, - writes the standard output")
+	if rest, err := parseArgs(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+
+	start := time.Date(c.now.Year(), c.now.Month(), 1, 0, 0, 0, 0, c.now.Location())
+	if *from != "" {
+		var err error
+		if start, err = c.parseTime("from", *from); err != nil {
+			return err
+		}
+	}
+	end := start.AddDate(0, 1, 0)
+	if *to != "" {
+		var err error
+		if end, err = c.parseTime("to", *to); err != nil {
+			return err
+		}
+	}
+
+	events, err := c.listRange(ctx, *owner, *calendar, start, end)
+	if err != nil {
+		return err
+	}
+	converted := make([]ical.Event, 0, len(events))
+	for _, e := range events {
+		converted = append(converted, toICS(e))
+	}
+
+	w := c.out
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return ical.Encode(w, converted, c.now)
+}
+
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/humantime"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/ical"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"gopkg.in/yaml.v3"
+)
+
+// eventInput is the event as typed in the flags or the YAML and JSON files, the times are parsed by humantime.
+type eventInput struct {
+	Title       string          `yaml:"title"`
+	Start       string          `yaml:"start"`
+	End         string          `yaml:"end"`
+	Duration    string          `yaml:"duration"`
+	Description string          `yaml:"description"`
+	Owner       string          `yaml:"owner"`
+	Calendar    string          `yaml:"calendar"`
+	Notify      string          `yaml:"notify"`
+	Reminders   []reminderInput `yaml:"reminders"`
+}
+
+type reminderInput struct {
+	Before  string `yaml:"before"`
+	Channel string `yaml:"channel"`
+	Target  string `yaml:"target"`
+}
+
+// maskPaths are the fields of the update mask the input keys change.
+var maskPaths = map[string]string{
+	"title":       "title",
+	"start":       "start_time",
+	"end":         "end_time",
+	"duration":    "end_time",
+	"description": "description",
+	"owner":       "owner_email",
+	"calendar":    "calendar_id",
+	"notify":      "notify",
+	"reminders":   "reminders",
+}
+
+type eventFlags struct {
+	input eventInput
+	file  string
+}
+
+func (f *eventFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&f.input.Title, "title", "", "the title")
+	fs.StringVar(&f.input.Start, "start", "", "the start, like 2024-11-25 10:00, tomorrow 10:00 or +2h")
+	fs.StringVar(&f.input.End, "end", "", "the end, the start plus -duration by default")
+	fs.StringVar(&f.input.Duration, "duration", "", "the duration, like 30m or 1h30m, 1h by default")
+	fs.StringVar(&f.input.Description, "description", "", "the description")
+	fs.StringVar(&f.input.Owner, "owner", "", "the owner email, server.user by default")
+	fs.StringVar(&f.input.Calendar, "calendar", "", "the calendar id")
+	fs.StringVar(&f.input.Notify, "notify", "", "the time before the start an email reminder is sent, like 15m")
+	fs.Var((*remindersFlag)(&f.input.Reminders), "reminder",
+		"the reminder as before[:email|webhook[:target]], like 1h:webhook:https://example.com/hook; repeatable")
+	fs.StringVar(&f.file, "file", "", "the YAML or JSON file of the event, - reads the standard input; flags override it")
+}
+
+type remindersFlag []reminderInput
+
+func (r *remindersFlag) String() string {
+	if r == nil {
+		return ""
+	}
+	s := make([]string, 0, len(*r))
+	for _, reminder := range *r {
+		s = append(s, reminder.Before)
+	}
+	return strings.Join(s, ",")
+}
+
+func (r *remindersFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	reminder := reminderInput{Before: parts[0]}
+	if len(parts) > 1 {
+		reminder.Channel = parts[1]
+	}
+	if len(parts) > 2 {
+		reminder.Target = parts[2]
+	}
+	*r = append(*r, reminder)
+	return nil
+}
+
+// readEvent merges the file and the flags set, it returns the update mask paths of the keys given.
+func (c *cli) readEvent(fs *flag.FlagSet, f eventFlags) (eventInput, []string, error) {
+	var input eventInput
+	keys := make(map[string]bool)
+	if f.file != "" {
+		var err error
+		if input, keys, err = c.readEventFile(f.file); err != nil {
+			return eventInput{}, nil, err
+		}
+	}
+
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "title":
+			input.Title = f.input.Title
+		case "start":
+			input.Start = f.input.Start
+		case "end":
+			input.End = f.input.End
+		case "duration":
+			input.Duration = f.input.Duration
+		case "description":
+			input.Description = f.input.Description
+		case "owner":
+			input.Owner = f.input.Owner
+		case "calendar":
+			input.Calendar = f.input.Calendar
+		case "notify":
+			input.Notify = f.input.Notify
+		case "reminder":
+			input.Reminders = f.input.Reminders
+			keys["reminders"] = true
+			return
+		}
+		keys[fl.Name] = true
+	})
+
+	var paths []string
+	for key := range keys {
+		if path, ok := maskPaths[key]; ok && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	return input, paths, nil
+}
+
+func (c *cli) readEventFile(name string) (eventInput, map[string]bool, error) {
+	r := c.in
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return eventInput{}, nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return eventInput{}, nil, err
+	}
+
+	// YAML reads JSON as well
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return eventInput{}, nil, fmt.Errorf("%v: %w", name, err)
+	}
+	if len(node.Content) == 0 {
+		return eventInput{}, nil, fmt.Errorf("%v: the file is empty", name)
+	}
+	mapping := node.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return eventInput{}, nil, fmt.Errorf("%v: the event must be a mapping of the fields", name)
+	}
+	keys := make(map[string]bool)
+	for i := 0; i < len(mapping.Content); i += 2 {
+		key := mapping.Content[i].Value
+		if _, ok := maskPaths[key]; !ok {
+			return eventInput{}, nil, fmt.Errorf("%v: unknown field %q", name, key)
+		}
+		keys[key] = true
+	}
+
+	var input eventInput
+	if err := mapping.Decode(&input); err != nil {
+		return eventInput{}, nil, fmt.Errorf("%v: %w", name, err)
+	}
+	return input, keys, nil
+}
+
+// toTransient parses the times of the input. The duration counts from the start of the input or else of current.
+func (c *cli) toTransient(input eventInput, current *pb.PersistedEvent) (*pb.TransientEvent, error) {
+	event := &pb.TransientEvent{
+		Title:       input.Title,
+		Description: input.Description,
+		OwnerEmail:  input.Owner,
+		CalendarId:  input.Calendar,
+		Notify:      input.Notify,
+	}
+
+	var start time.Time
+	switch {
+	case input.Start != "":
+		var err error
+		if start, err = c.parseTime("start", input.Start); err != nil {
+			return nil, err
+		}
+		event.StartTime = start.Unix()
+	case current != nil:
+		start = time.Unix(current.GetStartTime(), 0)
+	}
+
+	switch {
+	case input.End != "":
+		end, err := c.parseTime("end", input.End)
+		if err != nil {
+			return nil, err
+		}
+		event.EndTime = end.Unix()
+	case input.Duration != "":
+		if start.IsZero() {
+			return nil, errors.New("-duration needs -start")
+		}
+		d, err := humantime.ParseDuration(input.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("-duration: %w", err)
+		}
+		event.EndTime = start.Add(d).Unix()
+	}
+
+	if input.Notify != "" {
+		before, err := humantime.ParseDuration(input.Notify)
+		if err != nil {
+			return nil, fmt.Errorf("-notify: %w", err)
+		}
+		event.Notify = before.String()
+	}
+	for _, r := range input.Reminders {
+		reminder, err := toReminder(r)
+		if err != nil {
+			return nil, err
+		}
+		event.Reminders = append(event.Reminders, reminder)
+	}
+	return event, nil
+}
+
+func toReminder(r reminderInput) (*pb.Reminder, error) {
+	before, err := humantime.ParseDuration(r.Before)
+	if err != nil {
+		return nil, fmt.Errorf("reminder: %w", err)
+	}
+	reminder := &pb.Reminder{Before: before.String(), Target: r.Target}
+	if r.Channel != "" {
+		channel, ok := pb.Reminder_Channel_value[strings.ToUpper(r.Channel)]
+		if !ok || channel == int32(pb.Reminder_CHANNEL_UNSPECIFIED) {
+			return nil, fmt.Errorf("reminder: %q is not a channel, use email or webhook", r.Channel)
+		}
+		reminder.Channel = pb.Reminder_Channel(channel)
+	}
+	return reminder, nil
+}
+
+func fromICS(e ical.Event, owner, calendar string) *pb.TransientEvent {
+	event := &pb.TransientEvent{
+		Title:       e.Summary,
+		StartTime:   e.Start.Unix(),
+		EndTime:     e.End.Unix(),
+		Description: e.Description,
+		OwnerEmail:  owner,
+		CalendarId:  calendar,
+	}
+	for _, before := range e.Alarms {
+		event.Reminders = append(event.Reminders, &pb.Reminder{Before: before.String()})
+	}
+	return event
+}
+
+func toICS(e *pb.PersistedEvent) ical.Event {
+	event := ical.Event{
+		UID:         e.GetId(),
+		Summary:     e.GetTitle(),
+		Description: e.GetDescription(),
+		Organizer:   e.GetOwnerEmail(),
+		Start:       time.Unix(e.GetStartTime(), 0),
+		End:         time.Unix(e.GetEndTime(), 0),
+	}
+	befores := make([]string, 0, len(e.GetReminders())+1)
+	for _, r := range e.GetReminders() {
+		befores = append(befores, r.GetBefore())
+	}
+	if e.GetNotify() != "" {
+		befores = append(befores, e.GetNotify())
+	}
+	for _, b := range befores {
+		if before, err := humantime.ParseDuration(b); err == nil && !slices.Contains(event.Alarms, before) {
+			event.Alarms = append(event.Alarms, before)
+		}
+	}
+	return event
+}
@@ -2,131 +2,137 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
-	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/tlsconfig"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-var configFile string
+var (
+	configFile string
+	flags      *config.Flags
+)
 
 func init() {
-	flag.StringVar(&configFile, "config", "/etc/calendar/config.toml", "Path to configuration file")
+	flag.StringVar(&configFile, "config", "", "Path to configuration file, ~/.config/calendar/client.toml if it exists")
+	flags = config.NewFlags(flag.CommandLine, config.ClientConfig{})
+	flag.Usage = usage
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: client [flags] <command> [command flags] [arguments]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "  version  print the version")
+	fmt.Fprintln(out, "\nRun client <command> -h for the command flags. Flags:")
+	flag.PrintDefaults()
 }
 
 func main() {
 	flag.Parse()
 
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
 	if flag.Arg(0) == "version" {
 		printVersion()
 		return
 	}
-
-	config := config.NewCalendarConfig(configFile)
-	ctx := context.Background()
-	defer ctx.Done()
-
-	log := logger.New(config.Logger.Level, config.Logger.Output)
-	defer log.Close()
-
-	addr := fmt.Sprintf("%v:%v", config.Endpoint.Host, config.Endpoint.GRPCPort)
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Error(err.Error())
-		os.Exit(1) //nolint:gocritic
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	defer conn.Close()
-
-	client := pb.NewEventsClient(conn)
-	log.Info("Sending CREATE request")
-	createReq := &pb.NewEventRequest{
-		Event: &pb.TransientEvent{
-			Title:       "event 1",
-			Description: "some description",
-			StartTime:   time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
-			EndTime:     time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC).Unix(),
-			OwnerEmail:  "user1@example.com",
-			Notify:      "",
-		},
-	}
-	scalarResponse, err := client.CreateEvent(ctx, createReq)
+	conf, err := config.NewClientConfig(clientConfigFile(), flags)
 	if err != nil {
-		log.Error(err.Error())
+		fmt.Fprintln(os.Stderr, "Invalid configuration:\n"+err.Error())
 		os.Exit(1)
 	}
-	event := scalarResponse.GetEvent()
-	if event == nil {
-		log.Error("Received response but payload is empty")
+
+	if err := run(conf, cmd, flag.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "Error: "+describe(err))
 		os.Exit(1)
 	}
+}
 
-	log.Info("Success",
-		"Id", event.Id,
-		"Title", event.Title,
-		"StartTime", event.StartTime,
-		"EndTime", event.EndTime,
-		"Description", event.Description,
-		"OwnerEmail", event.OwnerEmail)
-
-	log.Info("Sending GET request")
-	getRequest := &pb.EventIdRequest{Id: event.Id}
-	scalarResponse, err = client.GetEvent(ctx, getRequest)
+// clientConfigFile returns the -config flag or the file in the user configuration directory, "" if it is missing.
+func clientConfigFile() string {
+	if configFile != "" {
+		return configFile
+	}
+	dir, err := os.UserConfigDir()
 	if err != nil {
-		log.Error(err.Error())
-		os.Exit(1)
+		return ""
 	}
-	event = scalarResponse.GetEvent()
-	if event == nil {
-		log.Error("Received response but payload is empty")
-		os.Exit(1)
+	path := filepath.Join(dir, "calendar", "client.toml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
 	}
-	log.Info("Success")
+	return path
+}
 
-	log.Info("Sending UPDATE request")
-	updateReq := &pb.UpdateEventRequest{
-		Id: event.Id,
-		Event: &pb.TransientEvent{
-			Title:       event.Title,
-			Description: "new description",
-			StartTime:   event.StartTime,
-			EndTime:     event.EndTime,
-			OwnerEmail:  event.OwnerEmail,
-			Notify:      event.Notify,
-		},
+func run(conf config.ClientConfig, cmd command, args []string) error {
+	creds := insecure.NewCredentials()
+	if conf.Server.TLS.Enabled {
+		tlsConfig, err := tlsconfig.NewClientConfig(conf.Server.TLS)
+		if err != nil {
+			return err
+		}
+		creds = credentials.NewTLS(tlsConfig)
 	}
-
-	scalarResponse, err = client.UpdateEvent(ctx, updateReq)
+	conn, err := grpc.NewClient(conf.Server.Address, grpc.WithTransportCredentials(creds))
 	if err != nil {
-		log.Error(err.Error())
-		os.Exit(1)
+		return err
 	}
-	event = scalarResponse.GetEvent()
-	if event == nil {
-		log.Error("Received response but payload is empty")
-		os.Exit(1)
+	defer conn.Close()
+
+	timeout, _ := time.ParseDuration(conf.Server.Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if conf.Server.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+conf.Server.Token)
+	}
+	if conf.Server.User != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, identity.MetadataKey, conf.Server.User)
 	}
 
-	log.Info("Success",
-		"Id", event.Id,
-		"Title", event.Title,
-		"StartTime", event.StartTime,
-		"EndTime", event.EndTime,
-		"Description", event.Description,
-		"OwnerEmail", event.OwnerEmail)
+	c := &cli{
+		client: pb.NewEventsClient(conn),
+		user:   conf.Server.User,
+		json:   conf.Output == "json",
+		now:    time.Now(),
+		in:     os.Stdin,
+		out:    os.Stdout,
+	}
+	return cmd.run(c, ctx, args)
+}
 
-	log.Info("Sending DELETE request")
-	getRequest = &pb.EventIdRequest{Id: event.Id}
-	_, err = client.DeleteEvent(ctx, getRequest)
-	if err != nil {
-		log.Error(err.Error())
-		os.Exit(1)
+// describe shortens the errors returned by the server to their message.
+func describe(err error) string {
+	if s, ok := status.FromError(err); ok {
+		return fmt.Sprintf("%v (%v)", s.Message(), strings.ToLower(s.Code().String()))
 	}
-	log.Info("Success")
+	return err.Error()
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/ical"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+)
+
+const tableTimeLayout = "2006-01-02 15:04"
+
+type eventView struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Start       string         `json:"start"`
+	End         string         `json:"end"`
+	Description string         `json:"description,omitempty"`
+	Owner       string         `json:"owner"`
+	Calendar    string         `json:"calendar,omitempty"`
+	Notify      string         `json:"notify,omitempty"`
+	Reminders   []reminderView `json:"reminders,omitempty"`
+}
+
+type reminderView struct {
+	ID       string `json:"id"`
+	Before   string `json:"before"`
+	Channel  string `json:"channel"`
+	Target   string `json:"target,omitempty"`
+	Notified bool   `json:"notified"`
+}
+
+type matchView struct {
+	Event eventView `json:"event"`
+	Rank  float64   `json:"rank"`
+}
+
+type resultView struct {
+	Title string     `json:"title"`
+	Event *eventView `json:"event,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+func newEventView(e *pb.PersistedEvent) eventView {
+	v := eventView{
+		ID:          e.GetId(),
+		Title:       e.GetTitle(),
+		Start:       time.Unix(e.GetStartTime(), 0).Format(time.RFC3339),
+		End:         time.Unix(e.GetEndTime(), 0).Format(time.RFC3339),
+		Description: e.GetDescription(),
+		Owner:       e.GetOwnerEmail(),
+		Calendar:    e.GetCalendarId(),
+		Notify:      e.GetNotify(),
+	}
+	for _, r := range e.GetReminders() {
+		v.Reminders = append(v.Reminders, reminderView{
+			ID:       r.GetId(),
+			Before:   r.GetBefore(),
+			Channel:  channelName(r.GetChannel()),
+			Target:   r.GetTarget(),
+			Notified: r.GetNotified(),
+		})
+	}
+	return v
+}
+
+func channelName(c pb.Reminder_Channel) string {
+	if c == pb.Reminder_CHANNEL_UNSPECIFIED {
+		c = pb.Reminder_EMAIL
+	}
+	return strings.ToLower(c.String())
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).Format(tableTimeLayout)
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printEvent shows all the fields of the event.
+func (c *cli) printEvent(e *pb.PersistedEvent) error {
+	v := newEventView(e)
+	if c.json {
+		return c.printJSON(v)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", v.ID)
+	fmt.Fprintf(w, "Title:\t%s\n", v.Title)
+	fmt.Fprintf(w, "Start:\t%s\n", formatTime(e.GetStartTime()))
+	fmt.Fprintf(w, "End:\t%s\n", formatTime(e.GetEndTime()))
+	fmt.Fprintf(w, "Owner:\t%s\n", v.Owner)
+	if v.Calendar != "" {
+		fmt.Fprintf(w, "Calendar:\t%s\n", v.Calendar)
+	}
+	if v.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", v.Description)
+	}
+	if v.Notify != "" {
+		fmt.Fprintf(w, "Notify:\t%s before\n", v.Notify)
+	}
+	for _, r := range v.Reminders {
+		target := ""
+		if r.Target != "" {
+			target = " to " + r.Target
+		}
+		fmt.Fprintf(w, "Reminder:\t%s before by %s%s\n", r.Before, r.Channel, target)
+	}
+	return w.Flush()
+}
+
+func (c *cli) printEvents(events []*pb.PersistedEvent) error {
+	if c.json {
+		views := make([]eventView, 0, len(events))
+		for _, e := range events {
+			views = append(views, newEventView(e))
+		}
+		return c.printJSON(views)
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(c.out, "No events")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTART\tEND\tTITLE\tOWNER")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.GetId(), formatTime(e.GetStartTime()), formatTime(e.GetEndTime()), e.GetTitle(), e.GetOwnerEmail())
+	}
+	return w.Flush()
+}
+
+func (c *cli) printMatches(matches []*pb.EventMatch) error {
+	if c.json {
+		views := make([]matchView, 0, len(matches))
+		for _, m := range matches {
+			views = append(views, matchView{Event: newEventView(m.GetEvent()), Rank: m.GetRank()})
+		}
+		return c.printJSON(views)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintln(c.out, "No events")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTART\tEND\tTITLE\tOWNER\tRANK")
+	for _, m := range matches {
+		e := m.GetEvent()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.3f\n", e.GetId(), formatTime(e.GetStartTime()),
+			formatTime(e.GetEndTime()), e.GetTitle(), e.GetOwnerEmail(), m.GetRank())
+	}
+	return w.Flush()
+}
+
+// printResults shows the outcome of each imported event, the results follow the order of the events.
+func (c *cli) printResults(events []ical.Event, results []*pb.BatchEventResult) error {
+	views := make([]resultView, 0, len(results))
+	for i, r := range results {
+		v := resultView{}
+		if i < len(events) {
+			v.Title = events[i].Summary
+		}
+		if r.GetError() != nil {
+			v.Error = r.GetError().GetMessage()
+		} else {
+			event := newEventView(r.GetEvent())
+			v.Event = &event
+		}
+		views = append(views, v)
+	}
+	if c.json {
+		return c.printJSON(views)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tRESULT")
+	for _, v := range views {
+		result := "failed: " + v.Error
+		if v.Event != nil {
+			result = "created " + v.Event.ID
+		}
+		fmt.Fprintf(w, "%s\t%s\n", v.Title, result)
+	}
+	return w.Flush()
+}
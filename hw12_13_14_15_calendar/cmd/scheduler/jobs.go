@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/jobs"
+)
+
+func newJob(name string, c config.JobConf, defaultInterval time.Duration) (jobs.Job, error) {
+	schedule, jitter, err := newJobSchedule(c, defaultInterval)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	return jobs.Job{Name: name, Schedule: schedule, Jitter: jitter}, nil
+}
+
+func newJobSchedule(c config.JobConf, defaultInterval time.Duration) (jobs.Schedule, time.Duration, error) {
+	var jitter time.Duration
+	if c.Jitter != "" {
+		var err error
+		if jitter, err = time.ParseDuration(c.Jitter); err != nil {
+			return nil, 0, fmt.Errorf("jitter: %w", err)
+		}
+	}
+
+	switch {
+	case c.Cron != "" && c.Interval != "":
+		return nil, 0, errors.New("either cron or interval must be set")
+	case c.Cron != "":
+		schedule, err := jobs.Parse(c.Cron)
+		if err != nil {
+			return nil, 0, fmt.Errorf("cron: %w", err)
+		}
+		return schedule, jitter, nil
+	case c.Interval != "":
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, 0, fmt.Errorf("interval: %w", err)
+		}
+		if interval <= 0 {
+			return nil, 0, errors.New("interval must be positive")
+		}
+		return jobs.Every(interval), jitter, nil
+	default:
+		return jobs.Every(defaultInterval), jitter, nil
+	}
+}
@@ -2,25 +2,33 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 	"time"
 
 	app "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/app/scheduler"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/jobs"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/queue"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
-var configFile string
+const statusReadTimeout = 5 * time.Second
+
+var (
+	configFile string
+	flags      *config.Flags
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "/etc/calendar/sender_config.toml", "Path to configuration file")
+	flags = config.NewFlags(flag.CommandLine, config.SchedulerConfig{})
 }
 
 func main() {
@@ -31,8 +39,16 @@ func main() {
 		return
 	}
 
-	config := config.NewSchedulerConfig(configFile)
-	log := logger.New(config.Logger.Level, config.Logger.Output)
+	config, err := config.NewSchedulerConfig(configFile, flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:\n"+err.Error())
+		os.Exit(1)
+	}
+	log, err := logger.NewFromConfig(config.Logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create logger: "+err.Error())
+		os.Exit(1)
+	}
 	defer log.Close()
 
 	scanInterval, err := time.ParseDuration(config.Schedule.Interval)
@@ -42,25 +58,50 @@ func main() {
 		os.Exit(1) //nolint:gocritic
 	}
 
-	retentionPeriod, err := time.ParseDuration(config.Schedule.RetentionPeriod)
+	var claimTimeout time.Duration
+	if config.Schedule.ClaimTimeout != "" {
+		if claimTimeout, err = time.ParseDuration(config.Schedule.ClaimTimeout); err != nil {
+			log.Error(fmt.Sprintf("failed to parse claim timeout: %s", err.Error()))
+			log.Close()
+			os.Exit(1)
+		}
+	}
+
+	notificationsJob, err := newJob("notifications", config.Schedule.Notifications, scanInterval)
 	if err != nil {
-		log.Error(fmt.Sprintf("failed to parse interval value: %s", err.Error()))
+		log.Error(fmt.Sprintf("failed to schedule notifications: %s", err.Error()))
+		log.Close()
+		os.Exit(1)
+	}
+	purgeJob, err := newJob("purge", config.Schedule.Purge, scanInterval)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to schedule purge: %s", err.Error()))
 		log.Close()
 		os.Exit(1)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	storage := storage.NewStorage(config.Storage)
-	if err := storage.Connect(ctx); err != nil {
+	storage, err := storage.NewStorage(config.Storage)
+	if err == nil {
+		err = storage.Connect(ctx)
+	}
+	if err != nil {
 		log.Error("failed to connect to storage: " + err.Error())
 		log.Close()
 		os.Exit(1)
 	}
 	defer storage.Close(ctx)
 
+	purgePolicy, err := newPurgePolicy(config.Schedule, config.Purge, storage)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to parse purge settings: %s", err.Error()))
+		storage.Close(ctx)
+		log.Close()
+		os.Exit(1)
+	}
+
 	queueConn := queue.NewConnection(config.Queue.QueueServerConf)
 	if err := queueConn.Connect(); err != nil {
 		log.Error(fmt.Sprintf("failed to connect to queue: %s", err.Error()))
@@ -80,39 +121,31 @@ func main() {
 	}
 	defer producer.Close()
 
-	schedulerApp := app.New(log, storage, producer, scanInterval, retentionPeriod)
+	schedulerApp := app.New(log, storage, producer, scanInterval, claimTimeout, purgePolicy)
 
-	log.Info("Scheduler is started")
-	ticker := time.NewTicker(scanInterval)
-	defer ticker.Stop()
-
-LOOP:
-	for {
-		select {
-		case <-ctx.Done():
-			break LOOP
-		case <-ticker.C:
-			var wg sync.WaitGroup
-
-			wg.Add(1)
-			go func() {
-				if err := schedulerApp.ProcessNotifications(ctx); err != nil {
-					log.Error("failed to process notifications: %s", err)
-				}
-				wg.Done()
-			}()
-
-			wg.Add(1)
-			go func() {
-				if err := schedulerApp.PurgeOldEvents(ctx); err != nil {
-					log.Error("failed to purge events: %s", err)
-				}
-				wg.Done()
-			}()
-
-			wg.Wait()
+	runner := jobs.NewRunner(log)
+	notificationsJob.Run = schedulerApp.ProcessNotifications
+	runner.Add(notificationsJob)
+	purgeJob.Run = schedulerApp.PurgeOldEvents
+	runner.Add(purgeJob)
+
+	if config.Schedule.StatusAddr != "" {
+		statusServer := &http.Server{
+			Addr:              config.Schedule.StatusAddr,
+			Handler:           runner.Handler(),
+			ReadHeaderTimeout: statusReadTimeout,
 		}
+		go func() {
+			if err := statusServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to serve job statuses: " + err.Error())
+			}
+		}()
+		defer statusServer.Close()
 	}
 
+	go (&reloader{log: log, config: config, runner: runner, app: schedulerApp, archive: storage}).run(ctx)
+
+	log.Info("Scheduler is started")
+	runner.Run(ctx)
 	log.Info("Scheduler is stopped")
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	app "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/app/scheduler"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+)
+
+var errWrongRetentionRule = errors.New("either ownerEmail or calendarID must be set")
+
+func newPurgePolicy(
+	schedule config.ScheduleConf,
+	purge config.PurgeConf,
+	archive app.EventArchive,
+) (app.PurgePolicy, error) {
+	retention, err := time.ParseDuration(schedule.RetentionPeriod)
+	if err != nil {
+		return app.PurgePolicy{}, fmt.Errorf("retentionPeriod: %w", err)
+	}
+
+	policy := app.PurgePolicy{
+		Retention: retention,
+		BatchSize: purge.BatchSize,
+		DryRun:    purge.DryRun,
+	}
+
+	switch purge.Archive {
+	case config.ArchiveModeNone:
+	case config.ArchiveModeTable:
+		policy.Archiver = app.NewTableArchiver(archive)
+	case config.ArchiveModeFile:
+		if purge.ArchiveDir == "" {
+			return app.PurgePolicy{}, errors.New("archiveDir must be set for the file archive")
+		}
+		policy.Archiver = app.NewFileArchiver(purge.ArchiveDir)
+	default:
+		return app.PurgePolicy{}, fmt.Errorf("unknown archive mode %q", purge.Archive)
+	}
+
+	for i, r := range purge.Retention {
+		if (r.OwnerEmail == "") == (r.CalendarID == "") {
+			return app.PurgePolicy{}, fmt.Errorf("retention[%d]: %w", i, errWrongRetentionRule)
+		}
+		period, err := time.ParseDuration(r.Period)
+		if err != nil {
+			return app.PurgePolicy{}, fmt.Errorf("retention[%d].period: %w", i, err)
+		}
+		policy.Rules = append(policy.Rules, app.RetentionRule{
+			OwnerEmail: r.OwnerEmail,
+			CalendarID: r.CalendarID,
+			Period:     period,
+		})
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	app "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/app/scheduler"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/jobs"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+// reloader re-reads the configuration on SIGHUP and applies the parts which do not need a reconnect.
+type reloader struct {
+	log     *logger.Logger
+	config  config.SchedulerConfig
+	runner  *jobs.Runner
+	app     *app.App
+	archive app.EventArchive
+}
+
+func (r *reloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := r.reload(); err != nil {
+				r.log.Error("Configuration is not reloaded: " + err.Error())
+			}
+		}
+	}
+}
+
+func (r *reloader) reload() error {
+	next, err := config.NewSchedulerConfig(configFile, flags)
+	if err != nil {
+		return err
+	}
+
+	updated := r.config
+	for _, key := range updated.Reload(next) {
+		r.log.Warn("Configuration change needs a restart, it is ignored", "Key", key)
+	}
+
+	scanInterval, err := time.ParseDuration(updated.Schedule.Interval)
+	if err != nil {
+		return fmt.Errorf("failed to parse interval value: %w", err)
+	}
+	notificationsJob, err := newJob("notifications", updated.Schedule.Notifications, scanInterval)
+	if err != nil {
+		return fmt.Errorf("failed to schedule notifications: %w", err)
+	}
+	purgeJob, err := newJob("purge", updated.Schedule.Purge, scanInterval)
+	if err != nil {
+		return fmt.Errorf("failed to schedule purge: %w", err)
+	}
+	purgePolicy, err := newPurgePolicy(updated.Schedule, updated.Purge, r.archive)
+	if err != nil {
+		return fmt.Errorf("failed to parse purge settings: %w", err)
+	}
+	if err := r.log.SetLevel(updated.Logger.Level); err != nil {
+		return err
+	}
+
+	r.runner.Reschedule(notificationsJob.Name, notificationsJob.Schedule, notificationsJob.Jitter)
+	r.runner.Reschedule(purgeJob.Name, purgeJob.Schedule, purgeJob.Jitter)
+	r.app.SetPurgePolicy(purgePolicy)
+	r.config = updated
+
+	r.log.Info("Configuration is reloaded")
+	return nil
+}
@@ -17,10 +17,14 @@ import (
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
-var configFile string
+var (
+	configFile string
+	flags      *config.Flags
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "/etc/calendar/sender_config.toml", "Path to configuration file")
+	flags = config.NewFlags(flag.CommandLine, config.SenderConfig{})
 }
 
 func main() {
@@ -31,23 +35,42 @@ func main() {
 		return
 	}
 
-	config := config.NewSenderConfig(configFile)
-	log := logger.New(config.Logger.Level, config.Logger.Output)
+	config, err := config.NewSenderConfig(configFile, flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:\n"+err.Error())
+		os.Exit(1)
+	}
+	log, err := logger.NewFromConfig(config.Logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create logger: "+err.Error())
+		os.Exit(1)
+	}
 	defer log.Close()
 
-	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	storage := storage.NewStorage(config.Storage)
-	if err := storage.Connect(ctx); err != nil {
+	storage, err := storage.NewStorage(config.Storage)
+	if err == nil {
+		err = storage.Connect(ctx)
+	}
+	if err != nil {
 		log.Error("failed to connect to storage: " + err.Error())
 		log.Close()
 		os.Exit(1) //nolint:gocritic
 	}
 	defer storage.Close(ctx)
 
+	notifiers, err := newNotifiers(log, config.Notifier)
+	if err != nil {
+		log.Error(err.Error())
+		storage.Close(ctx)
+		log.Close()
+		os.Exit(1)
+	}
 	senderApp := app.New(log, storage)
+	senderApp.SetNotifiers(notifiers)
+	go (&reloader{log: log, config: config, app: senderApp}).run(ctx)
 
 	queueConn := queue.NewConnection(config.Queue.QueueServerConf)
 	if err := queueConn.Connect(); err != nil {
@@ -58,13 +81,15 @@ func main() {
 	}
 	defer queueConn.Close()
 
+	// the notification being sent when the sender is stopped is finished
+	notifyCtx := context.WithoutCancel(ctx)
 	handler := queue.NewHandler(func(data []byte) error {
 		var notification contracts.Notification
 		if err := json.Unmarshal(data, &notification); err != nil {
 			log.Error(fmt.Sprintf("failed to parse notification: %s", err))
 			return err
 		}
-		if err := senderApp.Notify(ctx, notification); err != nil {
+		if err := senderApp.Notify(notifyCtx, notification); err != nil {
 			log.Error(fmt.Sprintf("failed to send notification: %s", err))
 			return err
 		}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	app "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/app/sender"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+// reloader re-reads the configuration on SIGHUP and applies the parts which do not need a reconnect.
+type reloader struct {
+	log    *logger.Logger
+	config config.SenderConfig
+	app    *app.App
+}
+
+func (r *reloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := r.reload(); err != nil {
+				r.log.Error("Configuration is not reloaded: " + err.Error())
+			}
+		}
+	}
+}
+
+func (r *reloader) reload() error {
+	next, err := config.NewSenderConfig(configFile, flags)
+	if err != nil {
+		return err
+	}
+
+	updated := r.config
+	for _, key := range updated.Reload(next) {
+		r.log.Warn("Configuration change needs a restart, it is ignored", "Key", key)
+	}
+
+	notifiers, err := newNotifiers(r.log, updated.Notifier)
+	if err != nil {
+		return err
+	}
+	if err := r.log.SetLevel(updated.Logger.Level); err != nil {
+		return err
+	}
+
+	r.app.SetNotifiers(notifiers)
+	r.config = updated
+
+	r.log.Info("Configuration is reloaded")
+	return nil
+}
+
+func newNotifiers(log *logger.Logger, c config.NotifierConf) (map[model.Channel]app.Notifier, error) {
+	var webhookTimeout time.Duration
+	if c.WebhookTimeout != "" {
+		var err error
+		if webhookTimeout, err = time.ParseDuration(c.WebhookTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse webhook timeout: %w", err)
+		}
+	}
+	return app.NewNotifiers(log, webhookTimeout), nil
+}
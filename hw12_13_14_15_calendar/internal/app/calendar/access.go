@@ -0,0 +1,293 @@
+package calendarapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	uuid "github.com/satori/go.uuid"
+)
+
+const busyTitle = "Busy"
+
+var (
+	errWrongRole     = errors.New("unknown role")
+	errOwnerMismatch = errors.New("must match the caller")
+)
+
+func (a *App) CreateCalendar(ctx context.Context, name, ownerEmail string) (model.Calendar, error) {
+	if name == "" {
+		return model.Calendar{}, customerrors.ValidationError{Field: "Name", Err: errCannotBeEmpty}
+	}
+	if caller, ok := identity.UserFromContext(ctx); ok {
+		if ownerEmail != "" && ownerEmail != caller {
+			return model.Calendar{}, customerrors.ValidationError{Field: "OwnerEmail", Err: errOwnerMismatch}
+		}
+		ownerEmail = caller
+	}
+	if ownerEmail == "" {
+		return model.Calendar{}, customerrors.ValidationError{Field: "OwnerEmail", Err: errCannotBeEmpty}
+	}
+
+	calendar := model.Calendar{ID: uuid.NewV4().String(), Name: name, OwnerEmail: ownerEmail}
+	if err := a.storage.AddCalendar(ctx, calendar); err != nil {
+		return model.Calendar{}, err
+	}
+	return calendar, nil
+}
+
+func (a *App) ListCalendars(ctx context.Context, email string) ([]model.Calendar, error) {
+	if caller, ok := identity.UserFromContext(ctx); ok {
+		if email != "" && email != caller {
+			return nil, permissionDenied("calendars of %v", email)
+		}
+		email = caller
+	}
+	if email == "" {
+		return nil, customerrors.ParamError{Param: "Email", Err: errCannotBeEmpty}
+	}
+
+	return a.storage.ListCalendars(ctx, email)
+}
+
+func (a *App) ShareCalendar(ctx context.Context, grant model.Grant) error {
+	if grant.Email == "" {
+		return customerrors.ValidationError{Field: "Email", Err: errCannotBeEmpty}
+	}
+	if !grant.Role.Valid() {
+		return customerrors.ValidationError{Field: "Role", Err: fmt.Errorf("%w %q", errWrongRole, grant.Role)}
+	}
+	if err := a.authorizeCalendar(ctx, grant.CalendarID, model.RoleOwner); err != nil {
+		return err
+	}
+
+	return a.storage.SetCalendarGrant(ctx, grant)
+}
+
+func (a *App) UnshareCalendar(ctx context.Context, calendarID, email string) error {
+	if err := a.authorizeCalendar(ctx, calendarID, model.RoleOwner); err != nil {
+		return err
+	}
+
+	return a.storage.DeleteCalendarGrant(ctx, calendarID, email)
+}
+
+func (a *App) ListCalendarGrants(ctx context.Context, calendarID string) ([]model.Grant, error) {
+	if err := a.authorizeCalendar(ctx, calendarID, model.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	return a.storage.ListCalendarGrants(ctx, calendarID)
+}
+
+func (a *App) ListCalendarEventsForDate(ctx context.Context, calendarID string, date int64) ([]model.Event, error) {
+	dt := time.Unix(date, 0)
+	return a.ListCalendarEventsForPeriod(ctx, calendarID, dt, dt.AddDate(0, 0, 1))
+}
+
+func (a *App) ListCalendarEventsForWeek(ctx context.Context, calendarID string, date int64) ([]model.Event, error) {
+	dt := time.Unix(date, 0)
+	return a.ListCalendarEventsForPeriod(ctx, calendarID, dt, dt.AddDate(0, 0, 7))
+}
+
+// ListCalendarEventsForPeriod lists the events of the calendar starting in [startDate, endDate).
+func (a *App) ListCalendarEventsForPeriod(
+	ctx context.Context,
+	calendarID string,
+	startDate, endDate time.Time,
+) ([]model.Event, error) {
+	if err := a.authorizeCalendar(ctx, calendarID, model.RoleFreeBusy); err != nil {
+		return nil, err
+	}
+
+	events, err := a.storage.ListCalendarEventsForPeriod(ctx, calendarID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return a.visibleEvents(ctx, events)
+}
+
+// authorizeCalendar checks the caller has the required access to the calendar.
+// Requests without a caller identity come from the trusted clients the server lets in anonymously,
+// access is not checked for them.
+func (a *App) authorizeCalendar(ctx context.Context, calendarID string, required model.Role) error {
+	caller, ok := identity.UserFromContext(ctx)
+	if !ok {
+		_, err := a.storage.GetCalendar(ctx, calendarID)
+		return err
+	}
+
+	role, err := a.calendarRole(ctx, calendarID, caller)
+	if err != nil {
+		return err
+	}
+	if !role.Allows(required) {
+		return permissionDenied("calendar %v", calendarID)
+	}
+	return nil
+}
+
+// authorizeEvent returns the role of the caller for the event if it allows the required access,
+// trusted clients are treated as owners.
+func (a *App) authorizeEvent(ctx context.Context, event model.Event, required model.Role) (model.Role, error) {
+	caller, ok := identity.UserFromContext(ctx)
+	if !ok {
+		return model.RoleOwner, nil
+	}
+
+	role, err := a.eventRole(ctx, event, caller)
+	if err != nil {
+		return "", err
+	}
+	if !role.Allows(required) {
+		return "", permissionDenied("event %v", event.ID)
+	}
+	return role, nil
+}
+
+func (a *App) eventRole(ctx context.Context, event model.Event, email string) (model.Role, error) {
+	if event.OwnerEmail == email {
+		return model.RoleOwner, nil
+	}
+	if event.CalendarID == "" {
+		return "", nil
+	}
+	return a.calendarRole(ctx, event.CalendarID, email)
+}
+
+func (a *App) calendarRole(ctx context.Context, calendarID, email string) (model.Role, error) {
+	calendar, err := a.storage.GetCalendar(ctx, calendarID)
+	if err != nil {
+		return "", err
+	}
+	if calendar.OwnerEmail == email {
+		return model.RoleOwner, nil
+	}
+
+	grant, err := a.storage.GetCalendarGrant(ctx, calendarID, email)
+	var notFoundErr customerrors.NotFound
+	if errors.As(err, &notFoundErr) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return grant.Role, nil
+}
+
+// visibleEvents drops the events the caller has no access to and hides the details of free/busy only ones.
+func (a *App) visibleEvents(ctx context.Context, events []model.Event) ([]model.Event, error) {
+	caller, ok := identity.UserFromContext(ctx)
+	if !ok {
+		return events, nil
+	}
+
+	roles := make(map[string]model.Role)
+	result := make([]model.Event, 0, len(events))
+	for _, ev := range events {
+		role, cached := roles[ev.CalendarID]
+		if !cached || ev.CalendarID == "" {
+			var err error
+			role, err = a.eventRole(ctx, ev, caller)
+			if err != nil {
+				return nil, err
+			}
+			roles[ev.CalendarID] = role
+		}
+
+		switch {
+		case role.Allows(model.RoleViewer):
+			result = append(result, ev)
+		case role.Allows(model.RoleFreeBusy):
+			result = append(result, redact(ev))
+		}
+	}
+
+	return result, nil
+}
+
+// searchReadable pages the matches the caller may read. The storage cannot skip the others,
+// so for the callers other than the owner the page is cut from all the matches.
+func (a *App) searchReadable(
+	ctx context.Context,
+	ownerEmail, query string,
+	startDate, endDate time.Time,
+	limit, offset int,
+) ([]model.EventMatch, error) {
+	caller, ok := identity.UserFromContext(ctx)
+	if !ok || caller == ownerEmail {
+		return a.storage.SearchEvents(ctx, ownerEmail, query, startDate, endDate, limit, offset)
+	}
+
+	matches, err := a.storage.SearchEvents(ctx, ownerEmail, query, startDate, endDate, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	matches = a.readableMatches(ctx, matches, caller)
+	if offset >= len(matches) {
+		return make([]model.EventMatch, 0), nil
+	}
+	matches = matches[offset:]
+	if limit < len(matches) {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (a *App) readableMatches(ctx context.Context, matches []model.EventMatch, caller string) []model.EventMatch {
+	result := make([]model.EventMatch, 0, len(matches))
+	for _, m := range matches {
+		role, err := a.eventRole(ctx, m.Event, caller)
+		if err == nil && role.Allows(model.RoleViewer) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// visibleChanges passes the changes of the events the caller may see and hides the details of free/busy only ones.
+// The access is checked for every change, so the grants changed meanwhile apply at once.
+func (a *App) visibleChanges(ctx context.Context, changes <-chan model.Change, caller string) <-chan model.Change {
+	result := make(chan model.Change)
+	go func() {
+		defer close(result)
+		for change := range changes {
+			role, err := a.eventRole(ctx, change.Event, caller)
+			switch {
+			case err != nil:
+				continue
+			case role.Allows(model.RoleViewer):
+			case role.Allows(model.RoleFreeBusy):
+				change.Event = redact(change.Event)
+			default:
+				continue
+			}
+
+			select {
+			case result <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return result
+}
+
+func redact(ev model.Event) model.Event {
+	return model.Event{
+		ID:         ev.ID,
+		Title:      busyTitle,
+		StartTime:  ev.StartTime,
+		EndTime:    ev.EndTime,
+		OwnerEmail: ev.OwnerEmail,
+		CalendarID: ev.CalendarID,
+	}
+}
+
+func permissionDenied(format string, args ...any) error {
+	return customerrors.PermissionDenied{Message: "Access denied to " + fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,242 @@
+package calendarapp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppCalendarAccess(t *testing.T) {
+	app := New(logger.New("INFO", "stdout"), memorystorage.New())
+	ctx := context.Background()
+	exec := identity.WithUser(ctx, "exec@example.com")
+	assistant := identity.WithUser(ctx, "assistant@example.com")
+	viewer := identity.WithUser(ctx, "viewer@example.com")
+	freeBusy := identity.WithUser(ctx, "free-busy@example.com")
+	stranger := identity.WithUser(ctx, "stranger@example.com")
+
+	calendar, err := app.CreateCalendar(exec, "exec", "")
+	require.NoError(t, err)
+	require.Equal(t, "exec@example.com", calendar.OwnerEmail)
+
+	grants := map[string]model.Role{
+		"assistant@example.com": model.RoleEditor,
+		"viewer@example.com":    model.RoleViewer,
+		"free-busy@example.com": model.RoleFreeBusy,
+	}
+	for email, role := range grants {
+		require.NoError(t, app.ShareCalendar(exec, model.Grant{CalendarID: calendar.ID, Email: email, Role: role}))
+	}
+
+	err = app.ShareCalendar(assistant, model.Grant{CalendarID: calendar.ID, Email: "x@example.com", Role: model.RoleOwner})
+	require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+	dto := contracts.Event{
+		Title:       "board meeting",
+		StartTime:   time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+		EndTime:     time.Date(2024, 11, 25, 11, 0, 0, 0, time.UTC),
+		Description: "quarterly results",
+		CalendarID:  calendar.ID,
+	}
+	_, err = app.CreateEvent(viewer, dto)
+	require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+	event, err := app.CreateEvent(assistant, dto)
+	require.NoError(t, err)
+	require.Equal(t, "exec@example.com", event.OwnerEmail)
+
+	t.Run("get", func(t *testing.T) {
+		t.Parallel()
+
+		ev, err := app.GetEvent(viewer, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "board meeting", ev.Title)
+
+		ev, err = app.GetEvent(freeBusy, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, busyTitle, ev.Title)
+		require.Empty(t, ev.Description)
+		require.Equal(t, event.StartTime, ev.StartTime)
+
+		_, err = app.GetEvent(stranger, event.ID)
+		require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+	})
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		date := time.Date(2024, 11, 25, 0, 0, 0, 0, time.UTC).Unix()
+		events, err := app.ListEventsForDate(freeBusy, "exec@example.com", date)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, busyTitle, events[0].Title)
+
+		events, err = app.ListEventsForDate(stranger, "exec@example.com", date)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		events, err = app.ListCalendarEventsForWeek(viewer, calendar.ID, date)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "board meeting", events[0].Title)
+
+		_, err = app.ListCalendarEventsForDate(stranger, calendar.ID, date)
+		require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+		events, err = app.ListEventsForDate(ctx, "exec@example.com", date)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+	})
+
+	t.Run("calendars", func(t *testing.T) {
+		t.Parallel()
+
+		calendars, err := app.ListCalendars(viewer, "")
+		require.NoError(t, err)
+		require.Equal(t, []model.Calendar{calendar}, calendars)
+
+		_, err = app.ListCalendars(viewer, "exec@example.com")
+		require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+		_, err = app.ListCalendarGrants(viewer, calendar.ID)
+		require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+	})
+}
+
+func TestAppCalendarAccessMutations(t *testing.T) {
+	app := New(logger.New("INFO", "stdout"), memorystorage.New())
+	ctx := context.Background()
+	exec := identity.WithUser(ctx, "exec@example.com")
+	assistant := identity.WithUser(ctx, "assistant@example.com")
+	viewer := identity.WithUser(ctx, "viewer@example.com")
+
+	calendar, err := app.CreateCalendar(exec, "exec", "")
+	require.NoError(t, err)
+	require.NoError(t, app.ShareCalendar(exec, model.Grant{
+		CalendarID: calendar.ID, Email: "assistant@example.com", Role: model.RoleEditor,
+	}))
+	require.NoError(t, app.ShareCalendar(exec, model.Grant{
+		CalendarID: calendar.ID, Email: "viewer@example.com", Role: model.RoleViewer,
+	}))
+
+	personal, err := app.CreateEvent(exec, contracts.Event{
+		Title:      "dentist",
+		StartTime:  time.Date(2024, 11, 26, 10, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2024, 11, 26, 11, 0, 0, 0, time.UTC),
+		OwnerEmail: "exec@example.com",
+	})
+	require.NoError(t, err)
+
+	_, err = app.GetEvent(assistant, personal.ID)
+	require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+	_, err = app.CreateEvent(assistant, contracts.Event{
+		Title:      "forged",
+		StartTime:  time.Date(2024, 11, 27, 10, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2024, 11, 27, 11, 0, 0, 0, time.UTC),
+		OwnerEmail: "exec@example.com",
+	})
+	require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+	// the owner moves the event to the shared calendar, so the assistant can manage it
+	moved, err := app.PatchEvent(exec, contracts.Event{ID: personal.ID, CalendarID: calendar.ID},
+		[]string{contracts.EventFieldCalendarID})
+	require.NoError(t, err)
+	require.Equal(t, calendar.ID, moved.CalendarID)
+
+	_, err = app.PatchEvent(viewer, contracts.Event{ID: personal.ID, Title: "changed"},
+		[]string{contracts.EventFieldTitle})
+	require.ErrorAs(t, err, &customerrors.PermissionDenied{})
+
+	updated, err := app.PatchEvent(assistant, contracts.Event{ID: personal.ID, Title: "orthodontist"},
+		[]string{contracts.EventFieldTitle})
+	require.NoError(t, err)
+	require.Equal(t, "orthodontist", updated.Title)
+
+	require.ErrorAs(t, app.DeleteEvent(viewer, personal.ID), &customerrors.PermissionDenied{})
+	require.NoError(t, app.UnshareCalendar(exec, calendar.ID, "assistant@example.com"))
+	require.ErrorAs(t, app.DeleteEvent(assistant, personal.ID), &customerrors.PermissionDenied{})
+	require.NoError(t, app.DeleteEvent(exec, personal.ID))
+}
+
+func TestAppCalendarAccessSharedFeeds(t *testing.T) {
+	storage := memorystorage.New()
+	app := New(logger.New("INFO", "stdout"), storage)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec := identity.WithUser(ctx, "exec@example.com")
+	viewer := identity.WithUser(ctx, "viewer@example.com")
+	freeBusy := identity.WithUser(ctx, "free-busy@example.com")
+
+	calendar, err := app.CreateCalendar(exec, "exec", "")
+	require.NoError(t, err)
+	require.NoError(t, app.ShareCalendar(exec, model.Grant{
+		CalendarID: calendar.ID, Email: "viewer@example.com", Role: model.RoleViewer,
+	}))
+	require.NoError(t, app.ShareCalendar(exec, model.Grant{
+		CalendarID: calendar.ID, Email: "free-busy@example.com", Role: model.RoleFreeBusy,
+	}))
+
+	viewerChanges, err := app.WatchEvents(viewer, "exec@example.com", "")
+	require.NoError(t, err)
+	freeBusyChanges, err := app.WatchEvents(freeBusy, "exec@example.com", "")
+	require.NoError(t, err)
+
+	// the private events go first, so they would fill the pages if the access was checked after the paging
+	for i := 0; i < 6; i++ {
+		ev := model.Event{
+			ID:         fmt.Sprintf("private%d", i),
+			Title:      fmt.Sprintf("budget meeting %d", i),
+			StartTime:  time.Date(2024, 1, 1+i, 12, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 1+i, 12, 30, 0, 0, time.UTC),
+			OwnerEmail: "exec@example.com",
+		}
+		if i >= 3 {
+			ev.ID = fmt.Sprintf("shared%d", i)
+			ev.CalendarID = calendar.ID
+		}
+		require.NoError(t, storage.AddEvent(ctx, ev))
+	}
+
+	t.Run("search", func(t *testing.T) {
+		matches, next, err := app.SearchEvents(viewer, "exec@example.com", "budget", 0, 0, 2, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"shared3", "shared4"}, []string{matches[0].ID, matches[1].ID})
+		require.NotEmpty(t, next)
+
+		matches, next, err = app.SearchEvents(viewer, "exec@example.com", "budget", 0, 0, 2, next)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, "shared5", matches[0].ID)
+		require.Empty(t, next)
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		for _, id := range []string{"shared3", "shared4", "shared5"} {
+			select {
+			case change := <-viewerChanges:
+				require.Equal(t, id, change.Event.ID)
+				require.Contains(t, change.Event.Title, "budget")
+			case <-time.After(time.Second):
+				require.FailNow(t, "change was not received")
+			}
+
+			select {
+			case change := <-freeBusyChanges:
+				require.Equal(t, id, change.Event.ID)
+				require.Equal(t, busyTitle, change.Event.Title)
+			case <-time.After(time.Second):
+				require.FailNow(t, "change was not received")
+			}
+		}
+	})
+}
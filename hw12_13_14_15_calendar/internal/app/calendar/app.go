@@ -3,11 +3,14 @@ package calendarapp
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/common"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 	uuid "github.com/satori/go.uuid"
 )
@@ -23,8 +26,38 @@ type Storage interface {
 	GetEvent(ctx context.Context, eventID string) (model.Event, error)
 	DeleteEvent(ctx context.Context, eventID string) error
 	ListOwnerEventsForPeriod(ctx context.Context, ownerEmail string, startDate, endDate time.Time) ([]model.Event, error)
+	SearchEvents(
+		ctx context.Context,
+		ownerEmail, query string,
+		startDate, endDate time.Time,
+		limit, offset int,
+	) ([]model.EventMatch, error)
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	WatchChanges(ctx context.Context, ownerEmail string, fromRevision int64) (<-chan model.Change, error)
+	AddCalendar(ctx context.Context, calendar model.Calendar) error
+	GetCalendar(ctx context.Context, calendarID string) (model.Calendar, error)
+	ListCalendars(ctx context.Context, email string) ([]model.Calendar, error)
+	SetCalendarGrant(ctx context.Context, grant model.Grant) error
+	GetCalendarGrant(ctx context.Context, calendarID, email string) (model.Grant, error)
+	ListCalendarGrants(ctx context.Context, calendarID string) ([]model.Grant, error)
+	DeleteCalendarGrant(ctx context.Context, calendarID, email string) error
+	ListCalendarEventsForPeriod(
+		ctx context.Context,
+		calendarID string,
+		startDate, endDate time.Time,
+	) ([]model.Event, error)
+	SetReminderNotified(ctx context.Context, reminderID string) error
+	SnoozeReminder(ctx context.Context, reminderID string, until time.Time) error
 }
 
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+	maxBatchSize          = 100
+)
+
+var maxSearchDate = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
 func New(logger common.Logger, storage Storage) *App {
 	return &App{logger: logger, storage: storage}
 }
@@ -34,15 +67,27 @@ var (
 	errCannotBeEmpty   = errors.New("cannot be empty")
 	errWrongPeriod     = errors.New("must be less than EndTime")
 	errNotSameDay      = errors.New("must be on the same day with EndTime")
+	errUnknownTimeZone = errors.New("unknown time zone")
 	errPeriodIsBusy    = errors.New("another meeting exists for that period")
+	errUnknownField    = errors.New("unknown field")
+	errWrongPageToken  = errors.New("wrong page token")
+	errWrongPageSize   = errors.New("must not be negative")
+	errBatchIsEmpty    = errors.New("batch is empty")
+	errBatchTooLarge   = fmt.Errorf("batch must contain at most %d items", maxBatchSize)
+	errWrongRevision   = errors.New("wrong revision token")
 )
 
 func (a *App) CreateEvent(ctx context.Context, dto contracts.Event) (model.Event, error) {
+	dto, err := a.authorizeTarget(ctx, dto)
+	if err != nil {
+		return model.Event{}, err
+	}
 	event, err := a.validateAttributes(ctx, dto)
 	if err != nil {
 		return model.Event{}, err
 	}
 	event.ID = uuid.NewV4().String()
+	event.Reminders = assignReminderIDs(nil, event.Reminders)
 	err = a.storage.AddEvent(ctx, event)
 	if err != nil {
 		return model.Event{}, err
@@ -51,10 +96,23 @@ func (a *App) CreateEvent(ctx context.Context, dto contracts.Event) (model.Event
 }
 
 func (a *App) UpdateEvent(ctx context.Context, dto contracts.Event) (model.Event, error) {
+	existing, err := a.storage.GetEvent(ctx, dto.ID)
+	if err != nil {
+		return model.Event{}, err
+	}
+	if _, err := a.authorizeEvent(ctx, existing, model.RoleEditor); err != nil {
+		return model.Event{}, err
+	}
+	dto, err = a.authorizeTarget(ctx, dto)
+	if err != nil {
+		return model.Event{}, err
+	}
+
 	event, err := a.validateAttributes(ctx, dto)
 	if err != nil {
 		return model.Event{}, err
 	}
+	event.Reminders = assignReminderIDs(existing.Reminders, event.Reminders)
 	err = a.storage.UpdateEvent(ctx, event)
 	if err != nil {
 		return model.Event{}, err
@@ -62,22 +120,272 @@ func (a *App) UpdateEvent(ctx context.Context, dto contracts.Event) (model.Event
 	return event, nil
 }
 
+func (a *App) PatchEvent(ctx context.Context, dto contracts.Event, fields []string) (model.Event, error) {
+	existing, err := a.storage.GetEvent(ctx, dto.ID)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	merged := toContract(existing)
+	for _, field := range fields {
+		switch field {
+		case contracts.EventFieldTitle:
+			merged.Title = dto.Title
+		case contracts.EventFieldStartTime:
+			merged.StartTime = dto.StartTime
+		case contracts.EventFieldEndTime:
+			merged.EndTime = dto.EndTime
+		case contracts.EventFieldTimeZone:
+			merged.TimeZone = dto.TimeZone
+		case contracts.EventFieldDescription:
+			merged.Description = dto.Description
+		case contracts.EventFieldOwnerEmail:
+			merged.OwnerEmail = dto.OwnerEmail
+		case contracts.EventFieldNotify:
+			merged.NotifyBefore = dto.NotifyBefore
+		case contracts.EventFieldCalendarID:
+			merged.CalendarID = dto.CalendarID
+		case contracts.EventFieldReminders:
+			merged.Reminders = dto.Reminders
+		default:
+			return model.Event{}, customerrors.ParamError{
+				Param: "UpdateMask",
+				Err:   fmt.Errorf("%w %q", errUnknownField, field),
+			}
+		}
+	}
+
+	return a.UpdateEvent(ctx, merged)
+}
+
 func (a *App) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
-	return a.storage.GetEvent(ctx, eventID)
+	event, err := a.storage.GetEvent(ctx, eventID)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	role, err := a.authorizeEvent(ctx, event, model.RoleFreeBusy)
+	if err != nil {
+		return model.Event{}, err
+	}
+	if !role.Allows(model.RoleViewer) {
+		return redact(event), nil
+	}
+	return event, nil
 }
 
 func (a *App) DeleteEvent(ctx context.Context, eventID string) error {
+	event, err := a.storage.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if _, err := a.authorizeEvent(ctx, event, model.RoleEditor); err != nil {
+		return err
+	}
+
 	return a.storage.DeleteEvent(ctx, eventID)
 }
 
 func (a *App) ListEventsForDate(ctx context.Context, ownerEmail string, date int64) ([]model.Event, error) {
 	dt := time.Unix(date, 0)
-	return a.storage.ListOwnerEventsForPeriod(ctx, ownerEmail, dt, dt.AddDate(0, 0, 1))
+	return a.ListEventsForPeriod(ctx, ownerEmail, dt, dt.AddDate(0, 0, 1))
 }
 
 func (a *App) ListEventsForWeek(ctx context.Context, ownerEmail string, date int64) ([]model.Event, error) {
 	dt := time.Unix(date, 0)
-	return a.storage.ListOwnerEventsForPeriod(ctx, ownerEmail, dt, dt.AddDate(0, 0, 7))
+	return a.ListEventsForPeriod(ctx, ownerEmail, dt, dt.AddDate(0, 0, 7))
+}
+
+// ListEventsForPeriod lists the events of the owner starting in [startDate, endDate).
+func (a *App) ListEventsForPeriod(
+	ctx context.Context,
+	ownerEmail string,
+	startDate, endDate time.Time,
+) ([]model.Event, error) {
+	events, err := a.storage.ListOwnerEventsForPeriod(ctx, ownerEmail, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return a.visibleEvents(ctx, events)
+}
+
+// authorizeTarget checks the caller may place the event where it is going to be stored.
+// Events of a calendar always belong to the calendar owner.
+func (a *App) authorizeTarget(ctx context.Context, dto contracts.Event) (contracts.Event, error) {
+	if dto.CalendarID != "" {
+		calendar, err := a.storage.GetCalendar(ctx, dto.CalendarID)
+		if err != nil {
+			return contracts.Event{}, err
+		}
+		dto.OwnerEmail = calendar.OwnerEmail
+	}
+
+	target := model.Event{ID: dto.ID, OwnerEmail: dto.OwnerEmail, CalendarID: dto.CalendarID}
+	if _, err := a.authorizeEvent(ctx, target, model.RoleEditor); err != nil {
+		return contracts.Event{}, err
+	}
+	return dto, nil
+}
+
+func (a *App) BatchCreateEvents(
+	ctx context.Context,
+	dtos []contracts.Event,
+	atomic bool,
+) ([]contracts.EventResult, error) {
+	if err := validateBatchSize(len(dtos)); err != nil {
+		return nil, err
+	}
+
+	// events are validated and stored one by one, so conflicts within the batch are caught as well
+	return a.runBatch(ctx, len(dtos), atomic, func(ctx context.Context, i int) contracts.EventResult {
+		event, err := a.CreateEvent(ctx, dtos[i])
+		return contracts.EventResult{ID: event.ID, Event: event, Err: err}
+	})
+}
+
+func (a *App) BatchGetEvents(
+	ctx context.Context,
+	eventIDs []string,
+	atomic bool,
+) ([]contracts.EventResult, error) {
+	if err := validateBatchSize(len(eventIDs)); err != nil {
+		return nil, err
+	}
+
+	return a.runBatch(ctx, len(eventIDs), atomic, func(ctx context.Context, i int) contracts.EventResult {
+		event, err := a.GetEvent(ctx, eventIDs[i])
+		return contracts.EventResult{ID: eventIDs[i], Event: event, Err: err}
+	})
+}
+
+func (a *App) BatchDeleteEvents(
+	ctx context.Context,
+	eventIDs []string,
+	atomic bool,
+) ([]contracts.EventResult, error) {
+	if err := validateBatchSize(len(eventIDs)); err != nil {
+		return nil, err
+	}
+
+	return a.runBatch(ctx, len(eventIDs), atomic, func(ctx context.Context, i int) contracts.EventResult {
+		err := a.DeleteEvent(ctx, eventIDs[i])
+		return contracts.EventResult{ID: eventIDs[i], Err: err}
+	})
+}
+
+// runBatch either fails on the first item error rolling back the whole batch (atomic)
+// or processes every item reporting errors per item.
+func (a *App) runBatch(
+	ctx context.Context,
+	size int,
+	atomic bool,
+	process func(ctx context.Context, i int) contracts.EventResult,
+) ([]contracts.EventResult, error) {
+	results := make([]contracts.EventResult, 0, size)
+	if !atomic {
+		for i := 0; i < size; i++ {
+			results = append(results, process(ctx, i))
+		}
+		return results, nil
+	}
+
+	err := a.storage.InTransaction(ctx, func(ctx context.Context) error {
+		for i := 0; i < size; i++ {
+			res := process(ctx, i)
+			if res.Err != nil {
+				return fmt.Errorf("item %d: %w", i, res.Err)
+			}
+			results = append(results, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func validateBatchSize(size int) error {
+	if size == 0 {
+		return customerrors.ValidationError{Field: "Batch", Err: errBatchIsEmpty}
+	}
+	if size > maxBatchSize {
+		return customerrors.ValidationError{Field: "Batch", Err: errBatchTooLarge}
+	}
+	return nil
+}
+
+func (a *App) SearchEvents(
+	ctx context.Context,
+	ownerEmail, query string,
+	from, to int64,
+	pageSize int,
+	pageToken string,
+) ([]model.EventMatch, string, error) {
+	if query == "" {
+		return nil, "", customerrors.ValidationError{Field: "Query", Err: errCannotBeEmpty}
+	}
+
+	switch {
+	case pageSize < 0:
+		return nil, "", customerrors.ParamError{Param: "PageSize", Err: errWrongPageSize}
+	case pageSize == 0:
+		pageSize = defaultSearchPageSize
+	case pageSize > maxSearchPageSize:
+		pageSize = maxSearchPageSize
+	}
+
+	offset := 0
+	if pageToken != "" {
+		var err error
+		offset, err = strconv.Atoi(pageToken)
+		if err != nil || offset < 0 {
+			return nil, "", customerrors.ParamError{Param: "PageToken", Err: errWrongPageToken}
+		}
+	}
+
+	startDate := time.Unix(from, 0)
+	endDate := maxSearchDate
+	if to != 0 {
+		endDate = time.Unix(to, 0)
+	}
+
+	// one extra record tells whether the next page exists
+	matches, err := a.searchReadable(ctx, ownerEmail, query, startDate, endDate, pageSize+1, offset)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextPageToken := ""
+	if len(matches) > pageSize {
+		matches = matches[:pageSize]
+		nextPageToken = strconv.Itoa(offset + pageSize)
+	}
+
+	return matches, nextPageToken, nil
+}
+
+// WatchEvents streams the changes of the owner events, the callers other than the owner get the changes
+// of the events in the calendars shared with them only.
+func (a *App) WatchEvents(ctx context.Context, ownerEmail, revisionToken string) (<-chan model.Change, error) {
+	var fromRevision int64
+	if revisionToken != "" {
+		var err error
+		fromRevision, err = strconv.ParseInt(revisionToken, 10, 64)
+		if err != nil || fromRevision < 0 {
+			return nil, customerrors.ParamError{Param: "RevisionToken", Err: errWrongRevision}
+		}
+	}
+
+	changes, err := a.storage.WatchChanges(ctx, ownerEmail, fromRevision)
+	if err != nil {
+		return nil, err
+	}
+	if caller, ok := identity.UserFromContext(ctx); ok && caller != ownerEmail {
+		return a.visibleChanges(ctx, changes, caller), nil
+	}
+	return changes, nil
 }
 
 func (a *App) validateAttributes(ctx context.Context, dto contracts.Event) (model.Event, error) {
@@ -85,8 +393,16 @@ func (a *App) validateAttributes(ctx context.Context, dto contracts.Event) (mode
 		return model.Event{}, customerrors.ValidationError{Field: "Title", Err: errCannotBeEmpty}
 	}
 
-	startTime := time.Unix(dto.StartTime, 0)
-	endTime := time.Unix(dto.EndTime, 0)
+	// the day of the event is the one in its time zone, events without a zone keep the server one
+	location := time.Local
+	if dto.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(dto.TimeZone); err != nil {
+			return model.Event{}, customerrors.ValidationError{Field: "TimeZone", Err: errUnknownTimeZone}
+		}
+	}
+	startTime := dto.StartTime.In(location)
+	endTime := dto.EndTime.In(location)
 
 	if startTime.After(endTime) {
 		return model.Event{}, customerrors.ValidationError{Field: "StartTime", Err: errWrongPeriod}
@@ -110,6 +426,11 @@ func (a *App) validateAttributes(ctx context.Context, dto contracts.Event) (mode
 		notifyTime = startTime.Add(-duration)
 	}
 
+	reminders, err := buildReminders(ctx, dto, startTime)
+	if err != nil {
+		return model.Event{}, err
+	}
+
 	existingEvents, err := a.storage.ListOwnerEventsForPeriod(ctx, dto.OwnerEmail, startTime, endTime)
 	if err != nil {
 		return model.Event{}, err
@@ -126,9 +447,27 @@ func (a *App) validateAttributes(ctx context.Context, dto contracts.Event) (mode
 		Title:        dto.Title,
 		StartTime:    startTime,
 		EndTime:      endTime,
+		TimeZone:     dto.TimeZone,
 		Description:  dto.Description,
 		OwnerEmail:   dto.OwnerEmail,
 		NotifyBefore: dto.NotifyBefore,
 		NotifyTime:   notifyTime,
+		CalendarID:   dto.CalendarID,
+		Reminders:    reminders,
 	}, nil
 }
+
+func toContract(ev model.Event) contracts.Event {
+	return contracts.Event{
+		ID:           ev.ID,
+		Title:        ev.Title,
+		StartTime:    ev.StartTime,
+		EndTime:      ev.EndTime,
+		TimeZone:     ev.TimeZone,
+		Description:  ev.Description,
+		NotifyBefore: ev.NotifyBefore,
+		OwnerEmail:   ev.OwnerEmail,
+		CalendarID:   ev.CalendarID,
+		Reminders:    toContractReminders(ev),
+	}
+}
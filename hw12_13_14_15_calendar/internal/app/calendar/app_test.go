@@ -19,32 +19,39 @@ func TestAppCreateEventSuccess(t *testing.T) {
 	tests := []contracts.Event{
 		{
 			Title:        "meeting 1",
-			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user@example.com",
 			NotifyBefore: "",
 		},
 		{
 			Title:        "meeting 2",
-			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user@example.com",
 			NotifyBefore: "",
 		},
 		{
 			Title:        "meeting 3",
-			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user2@example.com",
 			NotifyBefore: "",
 		},
 		{
 			Title:        "meeting 4",
-			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user2@example.com",
 			NotifyBefore: "",
 		},
+		{
+			Title:      "meeting 5",
+			StartTime:  time.Date(2024, 11, 25, 23, 30, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 11, 26, 0, 30, 0, 0, time.UTC),
+			TimeZone:   "Europe/Moscow",
+			OwnerEmail: "user2@example.com",
+		},
 	}
 
 	logger := logger.New("INFO", "stdout")
@@ -71,8 +78,8 @@ func TestAppCreateEventSimpleValidations(t *testing.T) {
 		{
 			add: contracts.Event{
 				Title:        "",
-				StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
-				EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC).Unix(),
+				StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
 				OwnerEmail:   "user@example.com",
 				NotifyBefore: "",
 			},
@@ -81,8 +88,8 @@ func TestAppCreateEventSimpleValidations(t *testing.T) {
 		{
 			add: contracts.Event{
 				Title:        "Title 4",
-				StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
-				EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC).Unix(),
+				StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
 				OwnerEmail:   "",
 				NotifyBefore: "",
 			},
@@ -91,13 +98,33 @@ func TestAppCreateEventSimpleValidations(t *testing.T) {
 		{
 			add: contracts.Event{
 				Title:        "Title 5",
-				StartTime:    time.Date(2024, 11, 25, 11, 0, 0, 0, time.UTC).Unix(),
-				EndTime:      time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
+				StartTime:    time.Date(2024, 11, 25, 11, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
 				OwnerEmail:   "user@example.com",
 				NotifyBefore: "",
 			},
 			err: customerrors.ValidationError{Field: "StartTime", Err: errors.New("must be less than EndTime")},
 		},
+		{
+			add: contracts.Event{
+				Title:      "Title 6",
+				StartTime:  time.Date(2024, 11, 26, 4, 30, 0, 0, time.UTC),
+				EndTime:    time.Date(2024, 11, 26, 5, 30, 0, 0, time.UTC),
+				TimeZone:   "America/New_York",
+				OwnerEmail: "user@example.com",
+			},
+			err: customerrors.ValidationError{Field: "StartTime", Err: errors.New("must be on the same day with EndTime")},
+		},
+		{
+			add: contracts.Event{
+				Title:      "Title 7",
+				StartTime:  time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+				EndTime:    time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
+				TimeZone:   "Mars/Olympus_Mons",
+				OwnerEmail: "user@example.com",
+			},
+			err: customerrors.ValidationError{Field: "TimeZone", Err: errors.New("unknown time zone")},
+		},
 	}
 
 	logger := logger.New("DEBUG", "stdout")
@@ -134,8 +161,8 @@ func TestAppCreateEventTimeBusy(t *testing.T) {
 		{
 			ID:           "xxx2",
 			Title:        "meeting 2",
-			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user@example.com",
 			Description:  "new description2",
 			NotifyBefore: "",
@@ -324,8 +351,8 @@ func TestAppUpdateEventSuccess(t *testing.T) {
 		{
 			ID:           "xxx",
 			Title:        "meeting 1",
-			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user@example.com",
 			Description:  "new description",
 			NotifyBefore: "",
@@ -333,8 +360,8 @@ func TestAppUpdateEventSuccess(t *testing.T) {
 		{
 			ID:           "xxx2",
 			Title:        "metting 2",
-			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user2@example.com",
 			Description:  "new description2",
 			NotifyBefore: "",
@@ -369,16 +396,16 @@ func TestAppUpdateEventNotFound(t *testing.T) {
 		{
 			ID:           "id1",
 			Title:        "meeting 1",
-			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user@example.com",
 			NotifyBefore: "",
 		},
 		{
 			ID:           "id2",
 			Title:        "meeting 2",
-			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
-			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC).Unix(),
+			StartTime:    time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC),
 			OwnerEmail:   "user@example.com",
 			NotifyBefore: "",
 		},
@@ -399,6 +426,103 @@ func TestAppUpdateEventNotFound(t *testing.T) {
 	}
 }
 
+func TestAppPatchEventSuccess(t *testing.T) {
+	data := model.Event{
+		ID:           "xxx",
+		Title:        "meeting 1",
+		StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		Description:  "description 1",
+		OwnerEmail:   "user@example.com",
+		NotifyBefore: "1h",
+	}
+
+	logger := logger.New("DEBUG", "stdout")
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger, storage)
+
+	err := storage.AddEvent(ctx, data)
+	require.NoError(t, err)
+
+	ev, err := app.PatchEvent(ctx, contracts.Event{ID: "xxx", Title: "new title"}, []string{contracts.EventFieldTitle})
+	require.NoError(t, err)
+	require.Equal(t, "new title", ev.Title)
+	require.Equal(t, data.Description, ev.Description)
+	require.Equal(t, data.OwnerEmail, ev.OwnerEmail)
+	require.Equal(t, data.NotifyBefore, ev.NotifyBefore)
+	require.True(t, data.StartTime.Equal(ev.StartTime))
+	require.True(t, data.EndTime.Equal(ev.EndTime))
+	require.True(t, data.StartTime.Add(-time.Hour).Equal(ev.NotifyTime))
+
+	stored, err := app.GetEvent(ctx, "xxx")
+	require.NoError(t, err)
+	require.Equal(t, "new title", stored.Title)
+	require.Equal(t, data.Description, stored.Description)
+}
+
+func TestAppPatchEventValidations(t *testing.T) {
+	data := model.Event{
+		ID:         "xxx",
+		Title:      "meeting 1",
+		StartTime:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		OwnerEmail: "user@example.com",
+	}
+
+	tests := []struct {
+		dto    contracts.Event
+		fields []string
+		err    error
+	}{
+		{
+			dto:    contracts.Event{ID: "xxx"},
+			fields: []string{contracts.EventFieldTitle},
+			err:    customerrors.ValidationError{Field: "Title", Err: errors.New("cannot be empty")},
+		},
+		{
+			dto:    contracts.Event{ID: "xxx", EndTime: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+			fields: []string{contracts.EventFieldEndTime},
+			err:    customerrors.ValidationError{Field: "StartTime", Err: errors.New("must be less than EndTime")},
+		},
+		{
+			dto:    contracts.Event{ID: "xxx", Title: "new title"},
+			fields: []string{"id"},
+			err:    customerrors.ParamError{Param: "UpdateMask", Err: errors.New("unknown field \"id\"")},
+		},
+	}
+
+	logger := logger.New("DEBUG", "stdout")
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger, storage)
+
+	err := storage.AddEvent(ctx, data)
+	require.NoError(t, err)
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+			_, err := app.PatchEvent(ctx, tt.dto, tt.fields)
+			require.Error(t, err)
+			require.ErrorContains(t, err, tt.err.Error())
+		})
+	}
+}
+
+func TestAppPatchEventNotFound(t *testing.T) {
+	logger := logger.New("DEBUG", "stdout")
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger, storage)
+
+	_, err := app.PatchEvent(ctx, contracts.Event{ID: "xxx", Title: "title"}, []string{contracts.EventFieldTitle})
+	require.Error(t, err)
+	var notFoundErr customerrors.NotFound
+	require.ErrorAs(t, err, &notFoundErr)
+}
+
 func TestAppFindEventsForDate(t *testing.T) {
 	data := []model.Event{
 		{
@@ -534,3 +658,198 @@ func TestAppFindEventsForWeek(t *testing.T) {
 		})
 	}
 }
+
+func TestAppSearchEventsPaging(t *testing.T) {
+	logger := logger.New("DEBUG", "stdout")
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger, storage)
+
+	for i := 0; i < 5; i++ {
+		err := storage.AddEvent(ctx, model.Event{
+			ID:         fmt.Sprintf("id%d", i),
+			Title:      fmt.Sprintf("budget meeting %d", i),
+			StartTime:  time.Date(2024, 1, 1+i, 12, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 1+i, 12, 30, 0, 0, time.UTC),
+			OwnerEmail: "user@example.com",
+		})
+		require.NoError(t, err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	seen := make([]string, 0)
+	pageToken := ""
+	for {
+		matches, next, err := app.SearchEvents(ctx, "user@example.com", "budget", from, 0, 2, pageToken)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(matches), 2)
+		for _, m := range matches {
+			seen = append(seen, m.ID)
+		}
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+	require.Equal(t, []string{"id0", "id1", "id2", "id3", "id4"}, seen)
+}
+
+func TestAppSearchEventsValidations(t *testing.T) {
+	tests := []struct {
+		query     string
+		pageSize  int
+		pageToken string
+		err       string
+	}{
+		{query: "", err: "Query: cannot be empty"},
+		{query: "budget", pageSize: -1, err: "PageSize: must not be negative"},
+		{query: "budget", pageToken: "abc", err: "PageToken: wrong page token"},
+		{query: "budget", pageToken: "-5", err: "PageToken: wrong page token"},
+	}
+
+	logger := logger.New("DEBUG", "stdout")
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger, storage)
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+			_, _, err := app.SearchEvents(ctx, "user@example.com", tt.query, 0, 0, tt.pageSize, tt.pageToken)
+			require.Error(t, err)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestAppBatchCreateEvents(t *testing.T) {
+	batch := []contracts.Event{
+		{
+			Title:      "meeting 1",
+			StartTime:  time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
+			OwnerEmail: "user@example.com",
+		},
+		{
+			Title:      "meeting 2",
+			StartTime:  time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
+			OwnerEmail: "user@example.com",
+		},
+		{
+			Title:      "meeting 3",
+			StartTime:  time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 11, 25, 12, 30, 0, 0, time.UTC),
+			OwnerEmail: "user@example.com",
+		},
+	}
+	date := time.Date(2024, 11, 25, 0, 0, 0, 0, time.UTC).Unix()
+
+	t.Run("per item", func(t *testing.T) {
+		logger := logger.New("DEBUG", "stdout")
+		ctx := context.Background()
+		storage := memorystorage.New()
+		app := New(logger, storage)
+
+		results, err := app.BatchCreateEvents(ctx, batch, false)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.NoError(t, results[0].Err)
+		require.NotEmpty(t, results[0].Event.ID)
+		require.ErrorContains(t, results[1].Err, "another meeting exists for that period")
+		require.NoError(t, results[2].Err)
+
+		events, err := app.ListEventsForDate(ctx, "user@example.com", date)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		logger := logger.New("DEBUG", "stdout")
+		ctx := context.Background()
+		storage := memorystorage.New()
+		app := New(logger, storage)
+
+		_, err := app.BatchCreateEvents(ctx, batch, true)
+		require.Error(t, err)
+		require.ErrorContains(t, err, "item 1")
+		var valErr customerrors.ValidationError
+		require.ErrorAs(t, err, &valErr)
+
+		events, err := app.ListEventsForDate(ctx, "user@example.com", date)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		results, err := app.BatchCreateEvents(ctx, []contracts.Event{batch[0], batch[2]}, true)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		events, err = app.ListEventsForDate(ctx, "user@example.com", date)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		logger := logger.New("DEBUG", "stdout")
+		ctx := context.Background()
+		storage := memorystorage.New()
+		app := New(logger, storage)
+
+		_, err := app.BatchCreateEvents(ctx, nil, false)
+		require.ErrorContains(t, err, "Batch: batch is empty")
+
+		_, err = app.BatchCreateEvents(ctx, make([]contracts.Event, maxBatchSize+1), false)
+		require.ErrorContains(t, err, "Batch: batch must contain at most")
+	})
+}
+
+func TestAppBatchGetAndDeleteEvents(t *testing.T) {
+	data := []model.Event{
+		{
+			ID:         "xxx",
+			Title:      "meeting 1",
+			StartTime:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail: "user@example.com",
+		},
+		{
+			ID:         "xxx2",
+			Title:      "meeting 2",
+			StartTime:  time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 2, 12, 30, 0, 0, time.UTC),
+			OwnerEmail: "user@example.com",
+		},
+	}
+
+	logger := logger.New("DEBUG", "stdout")
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger, storage)
+	for _, ev := range data {
+		err := storage.AddEvent(ctx, ev)
+		require.NoError(t, err)
+	}
+
+	results, err := app.BatchGetEvents(ctx, []string{"xxx", "missing", "xxx2"}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, "meeting 1", results[0].Event.Title)
+	var notFoundErr customerrors.NotFound
+	require.ErrorAs(t, results[1].Err, &notFoundErr)
+	require.Equal(t, "meeting 2", results[2].Event.Title)
+
+	_, err = app.BatchGetEvents(ctx, []string{"xxx", "missing"}, true)
+	require.ErrorAs(t, err, &notFoundErr)
+
+	_, err = app.BatchDeleteEvents(ctx, []string{"xxx", "missing"}, true)
+	require.ErrorAs(t, err, &notFoundErr)
+	_, err = app.GetEvent(ctx, "xxx")
+	require.NoError(t, err, "atomic delete should be rolled back")
+
+	results, err = app.BatchDeleteEvents(ctx, []string{"xxx", "missing"}, false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.ErrorAs(t, results[1].Err, &notFoundErr)
+	_, err = app.GetEvent(ctx, "xxx")
+	require.ErrorAs(t, err, &notFoundErr)
+}
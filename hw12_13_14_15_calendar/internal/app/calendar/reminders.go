@@ -0,0 +1,161 @@
+package calendarapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/webhook"
+	uuid "github.com/satori/go.uuid"
+)
+
+const maxSnoozeDuration = 24 * time.Hour
+
+var (
+	errWrongChannel  = errors.New("unknown channel")
+	errWrongTarget   = errors.New("must be an absolute http(s) URL")
+	errWrongDuration = fmt.Errorf("must be positive and at most %v", maxSnoozeDuration)
+)
+
+// SnoozeReminder postpones the delivery of the reminder, it is sent again after the duration even if already notified.
+func (a *App) SnoozeReminder(ctx context.Context, eventID, reminderID, duration string) (model.Reminder, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return model.Reminder{}, customerrors.ParamError{Param: "Duration", Err: errWrongDateFormat}
+	}
+	if d <= 0 || d > maxSnoozeDuration {
+		return model.Reminder{}, customerrors.ParamError{Param: "Duration", Err: errWrongDuration}
+	}
+
+	reminder, err := a.eventReminder(ctx, eventID, reminderID)
+	if err != nil {
+		return model.Reminder{}, err
+	}
+
+	reminder.SnoozedUntil = time.Now().Add(d)
+	reminder.Notified = false
+	if err := a.storage.SnoozeReminder(ctx, reminderID, reminder.SnoozedUntil); err != nil {
+		return model.Reminder{}, err
+	}
+	return reminder, nil
+}
+
+// AckReminder closes the reminder, it is not delivered anymore.
+func (a *App) AckReminder(ctx context.Context, eventID, reminderID string) (model.Reminder, error) {
+	reminder, err := a.eventReminder(ctx, eventID, reminderID)
+	if err != nil {
+		return model.Reminder{}, err
+	}
+
+	reminder.Notified = true
+	if err := a.storage.SetReminderNotified(ctx, reminderID); err != nil {
+		return model.Reminder{}, err
+	}
+	return reminder, nil
+}
+
+func (a *App) eventReminder(ctx context.Context, eventID, reminderID string) (model.Reminder, error) {
+	event, err := a.storage.GetEvent(ctx, eventID)
+	if err != nil {
+		return model.Reminder{}, err
+	}
+	if _, err := a.authorizeEvent(ctx, event, model.RoleEditor); err != nil {
+		return model.Reminder{}, err
+	}
+
+	i := slices.IndexFunc(event.Reminders, func(r model.Reminder) bool { return r.ID == reminderID })
+	if i < 0 {
+		return model.Reminder{}, customerrors.NotFound{
+			Message: fmt.Sprintf("Reminder with id = \"%v\" not found in event \"%v\"", reminderID, eventID),
+		}
+	}
+	return event.Reminders[i], nil
+}
+
+// buildReminders validates the requested reminders, NotifyBefore is a shorthand for one more email reminder.
+func buildReminders(ctx context.Context, dto contracts.Event, startTime time.Time) ([]model.Reminder, error) {
+	requested := dto.Reminders
+	if dto.NotifyBefore != "" {
+		requested = append(slices.Clone(requested), contracts.Reminder{Before: dto.NotifyBefore})
+	}
+
+	reminders := make([]model.Reminder, 0, len(requested))
+	for i, r := range requested {
+		field := fmt.Sprintf("Reminders[%d]", i)
+		duration, err := time.ParseDuration(r.Before)
+		if err != nil {
+			return nil, customerrors.ValidationError{Field: field + ".Before", Err: errWrongDateFormat}
+		}
+
+		channel := r.Channel
+		if channel == "" {
+			channel = model.ChannelEmail
+		}
+		if !channel.Valid() {
+			return nil, customerrors.ValidationError{
+				Field: field + ".Channel",
+				Err:   fmt.Errorf("%w %q", errWrongChannel, channel),
+			}
+		}
+		if channel == model.ChannelWebhook {
+			if err := checkWebhookURL(ctx, r.Target); err != nil {
+				return nil, customerrors.ValidationError{Field: field + ".Target", Err: err}
+			}
+		}
+
+		reminder := model.Reminder{
+			Before:     r.Before,
+			Channel:    channel,
+			Target:     r.Target,
+			NotifyTime: startTime.Add(-duration),
+		}
+		if !slices.ContainsFunc(reminders, reminder.SameDelivery) {
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	return reminders, nil
+}
+
+// assignReminderIDs keeps the identity and the delivery state of the reminders which have not changed.
+func assignReminderIDs(existing, reminders []model.Reminder) []model.Reminder {
+	for i := range reminders {
+		j := slices.IndexFunc(existing, reminders[i].SameDelivery)
+		if j < 0 {
+			reminders[i].ID = uuid.NewV4().String()
+			continue
+		}
+		reminders[i].ID = existing[j].ID
+		reminders[i].Notified = existing[j].Notified
+		reminders[i].SnoozedUntil = existing[j].SnoozedUntil
+		reminders[i].ClaimedUntil = existing[j].ClaimedUntil
+	}
+	return reminders
+}
+
+// checkWebhookURL rejects the targets which are not http(s) URLs or point to the internal network.
+func checkWebhookURL(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errWrongTarget
+	}
+	return webhook.CheckHost(ctx, u.Hostname())
+}
+
+// toContractReminders leaves out the reminder created from NotifyBefore, it is restored from the shorthand.
+func toContractReminders(ev model.Event) []contracts.Reminder {
+	result := make([]contracts.Reminder, 0, len(ev.Reminders))
+	for _, r := range ev.Reminders {
+		if ev.NotifyBefore != "" && r.Before == ev.NotifyBefore && r.Channel == model.ChannelEmail && r.Target == "" {
+			continue
+		}
+		result = append(result, contracts.Reminder{Before: r.Before, Channel: r.Channel, Target: r.Target})
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package calendarapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppEventReminders(t *testing.T) {
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger.New("INFO", "stdout"), storage)
+	start := time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC)
+
+	event, err := app.CreateEvent(ctx, contracts.Event{
+		Title:        "meeting",
+		StartTime:    start,
+		EndTime:      start.Add(time.Hour),
+		OwnerEmail:   "user@example.com",
+		NotifyBefore: "24h",
+		Reminders: []contracts.Reminder{
+			{Before: "10m", Channel: model.ChannelWebhook, Target: "https://example.com/hook"},
+			{Before: "24h"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, event.Reminders, 2, "the shorthand duplicates an explicit reminder")
+	webhook, email := event.Reminders[0], event.Reminders[1]
+	require.NotEmpty(t, webhook.ID)
+	require.True(t, start.Add(-10*time.Minute).Equal(webhook.NotifyTime))
+	require.Equal(t, model.ChannelEmail, email.Channel)
+	require.True(t, start.Add(-24*time.Hour).Equal(email.NotifyTime))
+
+	require.NoError(t, storage.SetReminderNotified(ctx, email.ID))
+
+	patched, err := app.PatchEvent(ctx, contracts.Event{ID: event.ID, Title: "renamed"},
+		[]string{contracts.EventFieldTitle})
+	require.NoError(t, err)
+	require.Equal(t, []string{webhook.ID, email.ID}, reminderIDs(patched.Reminders))
+	require.True(t, patched.Reminders[1].Notified, "delivery state survives unrelated changes")
+
+	patched, err = app.PatchEvent(ctx, contracts.Event{ID: event.ID, NotifyBefore: "1h"},
+		[]string{contracts.EventFieldNotify})
+	require.NoError(t, err)
+	require.Len(t, patched.Reminders, 2)
+	require.Equal(t, webhook.ID, patched.Reminders[0].ID)
+	require.NotEqual(t, email.ID, patched.Reminders[1].ID)
+	require.Equal(t, "1h", patched.Reminders[1].Before)
+	require.False(t, patched.Reminders[1].Notified)
+
+	patched, err = app.PatchEvent(ctx, contracts.Event{ID: event.ID}, []string{contracts.EventFieldReminders})
+	require.NoError(t, err)
+	require.Len(t, patched.Reminders, 1, "the shorthand reminder is kept")
+}
+
+func TestAppSnoozeAndAckReminder(t *testing.T) {
+	ctx := context.Background()
+	storage := memorystorage.New()
+	app := New(logger.New("INFO", "stdout"), storage)
+	start := time.Now().Add(time.Hour)
+
+	event, err := app.CreateEvent(ctx, contracts.Event{
+		Title:        "meeting",
+		StartTime:    start,
+		EndTime:      start.Add(time.Hour),
+		OwnerEmail:   "user@example.com",
+		NotifyBefore: "1h",
+	})
+	require.NoError(t, err)
+	reminderID := event.Reminders[0].ID
+
+	for _, duration := range []string{"", "abc", "0s", "-5m", "25h"} {
+		_, err := app.SnoozeReminder(ctx, event.ID, reminderID, duration)
+		require.ErrorAs(t, err, &customerrors.ParamError{}, duration)
+	}
+	_, err = app.SnoozeReminder(ctx, event.ID, "unknown", "5m")
+	require.ErrorAs(t, err, &customerrors.NotFound{})
+	_, err = app.AckReminder(ctx, "unknown", reminderID)
+	require.ErrorAs(t, err, &customerrors.NotFound{})
+
+	require.NoError(t, storage.SetReminderNotified(ctx, reminderID))
+	snoozed, err := app.SnoozeReminder(ctx, event.ID, reminderID, "15m")
+	require.NoError(t, err)
+	require.False(t, snoozed.Notified)
+	require.WithinDuration(t, time.Now().Add(15*time.Minute), snoozed.SnoozedUntil, time.Second)
+
+	due, err := storage.ListRemindersToBeNotified(ctx, time.Now().Add(14*time.Minute), time.Now().Add(16*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	patched, err := app.PatchEvent(ctx, contracts.Event{ID: event.ID, Title: "renamed"},
+		[]string{contracts.EventFieldTitle})
+	require.NoError(t, err)
+	require.True(t, snoozed.SnoozedUntil.Equal(patched.Reminders[0].SnoozedUntil), "snooze survives unrelated changes")
+
+	acked, err := app.AckReminder(ctx, event.ID, reminderID)
+	require.NoError(t, err)
+	require.True(t, acked.Notified)
+
+	due, err = storage.ListRemindersToBeNotified(ctx, time.Now().Add(14*time.Minute), time.Now().Add(16*time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, due)
+}
+
+func TestAppEventRemindersValidations(t *testing.T) {
+	tests := []struct {
+		reminder contracts.Reminder
+		field    string
+	}{
+		{reminder: contracts.Reminder{Before: "soon"}, field: "Reminders[0].Before"},
+		{reminder: contracts.Reminder{Before: "1h", Channel: "pigeon"}, field: "Reminders[0].Channel"},
+		{reminder: contracts.Reminder{Before: "1h", Channel: model.ChannelWebhook}, field: "Reminders[0].Target"},
+		{
+			reminder: contracts.Reminder{Before: "1h", Channel: model.ChannelWebhook, Target: "ftp://example.com"},
+			field:    "Reminders[0].Target",
+		},
+		{
+			reminder: contracts.Reminder{Before: "1h", Channel: model.ChannelWebhook, Target: "http://127.0.0.1:8080/hook"},
+			field:    "Reminders[0].Target",
+		},
+		{
+			reminder: contracts.Reminder{Before: "1h", Channel: model.ChannelWebhook, Target: "http://localhost/hook"},
+			field:    "Reminders[0].Target",
+		},
+		{
+			reminder: contracts.Reminder{Before: "1h", Channel: model.ChannelWebhook, Target: "http://10.1.2.3/hook"},
+			field:    "Reminders[0].Target",
+		},
+		{
+			reminder: contracts.Reminder{
+				Before: "1h", Channel: model.ChannelWebhook, Target: "http://169.254.169.254/latest/meta-data",
+			},
+			field: "Reminders[0].Target",
+		},
+	}
+
+	app := New(logger.New("INFO", "stdout"), memorystorage.New())
+	start := time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			_, err := app.CreateEvent(context.Background(), contracts.Event{
+				Title:      "meeting",
+				StartTime:  start,
+				EndTime:    start.Add(time.Hour),
+				OwnerEmail: "user@example.com",
+				Reminders:  []contracts.Reminder{tt.reminder},
+			})
+			var validationErr customerrors.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, tt.field, validationErr.Field)
+		})
+	}
+}
+
+func reminderIDs(reminders []model.Reminder) []string {
+	ids := make([]string, 0, len(reminders))
+	for _, r := range reminders {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
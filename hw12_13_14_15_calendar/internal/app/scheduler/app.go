@@ -4,81 +4,110 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/common"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 )
 
+const (
+	defaultClaimTimeout = time.Minute
+	claimBatchSize      = 100
+)
+
 type App struct {
-	logger          common.Logger
-	storage         Storage
-	publisher       Publisher
-	scanInterval    time.Duration
-	retentionPeriod time.Duration
-	startTime       time.Time
+	logger       common.Logger
+	storage      Storage
+	publisher    Publisher
+	claimTimeout time.Duration
+	since        time.Time
+
+	mu    sync.Mutex
+	purge PurgePolicy
 }
 
 type Storage interface {
-	DeleteEventsOlderThan(ctx context.Context, time time.Time) error
-	ListEventsToBeNotified(ctx context.Context, startTime, endTime time.Time) ([]model.Event, error)
+	ListEventsToPurge(ctx context.Context, filter model.PurgeFilter, limit int) ([]model.Event, error)
+	DeleteEvents(ctx context.Context, eventIDs []string) error
+	DeleteChangesOlderThan(ctx context.Context, time time.Time) error
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	ClaimDueReminders(
+		ctx context.Context,
+		since, now, claimUntil time.Time,
+		limit int,
+	) ([]model.DueReminder, error)
 }
 
 type Publisher interface {
 	Publish(data []byte) error
 }
 
-func New(logger common.Logger, storage Storage, publisher Publisher, scanInterval, retentionPeriod time.Duration) *App {
+// New creates the scheduler, the reminders due since the previous scan interval are dispatched.
+// Reminders are claimed for claimTimeout, the ones not delivered by then are dispatched again.
+func New(
+	logger common.Logger,
+	storage Storage,
+	publisher Publisher,
+	scanInterval, claimTimeout time.Duration,
+	purge PurgePolicy,
+) *App {
+	if claimTimeout <= 0 {
+		claimTimeout = defaultClaimTimeout
+	}
 	return &App{
-		logger:          logger,
-		storage:         storage,
-		publisher:       publisher,
-		scanInterval:    scanInterval,
-		retentionPeriod: retentionPeriod,
-		startTime:       time.Now().Add(-scanInterval),
+		logger:       logger,
+		storage:      storage,
+		publisher:    publisher,
+		claimTimeout: claimTimeout,
+		purge:        purge,
+		since:        time.Now().Add(-scanInterval),
 	}
 }
 
 func (a *App) ProcessNotifications(ctx context.Context) error {
-	a.logger.Debug("Checking events to be notified...")
-
-	events, err := a.storage.ListEventsToBeNotified(ctx, a.shiftBoundary(), time.Now())
-	if err != nil {
-		return fmt.Errorf("failed to retrieve events to be notified: %w", err)
-	}
-
-	for _, ev := range events {
-		dto := contracts.Notification{ID: ev.ID, Title: ev.Title, Time: ev.StartTime.Unix(), OwnerEmail: ev.OwnerEmail}
-		a.logger.Debug(fmt.Sprintf("Publishing notification for event ID=%s", ev.ID))
+	a.logger.Debug("Checking reminders to be notified...")
 
-		data, err := json.Marshal(dto)
+	for {
+		now := time.Now()
+		reminders, err := a.storage.ClaimDueReminders(ctx, a.since, now, now.Add(a.claimTimeout), claimBatchSize)
 		if err != nil {
-			return fmt.Errorf("failed to serialize event: %w", err)
+			return fmt.Errorf("failed to claim reminders to be notified: %w", err)
 		}
-		err = a.publisher.Publish(data)
-		if err != nil {
-			return fmt.Errorf("failed to publish event: %w", err)
+
+		for _, r := range reminders {
+			if err := a.publish(logger.WithOwner(ctx, r.Event.OwnerEmail), r); err != nil {
+				// the claimed reminders are dispatched again when their claims expire
+				return err
+			}
 		}
-	}
 
-	return nil
+		if len(reminders) < claimBatchSize {
+			return nil
+		}
+	}
 }
 
-func (a *App) PurgeOldEvents(ctx context.Context) error {
-	a.logger.Debug("Purging old events...")
-
-	if err := a.storage.DeleteEventsOlderThan(ctx, time.Now().Add(-a.retentionPeriod)); err != nil {
-		return fmt.Errorf("failed to delete old events: %w", err)
+func (a *App) publish(ctx context.Context, r model.DueReminder) error {
+	dto := contracts.Notification{
+		ID:         r.Event.ID,
+		ReminderID: r.ID,
+		Channel:    string(r.Channel),
+		Target:     r.Target,
+		Title:      r.Event.Title,
+		Time:       r.Event.StartTime.Unix(),
+		OwnerEmail: r.Event.OwnerEmail,
 	}
+	a.logger.DebugContext(ctx, fmt.Sprintf("Publishing notification for event ID=%s, reminder ID=%s", r.Event.ID, r.ID))
 
+	data, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("failed to serialize event: %w", err)
+	}
+	if err := a.publisher.Publish(data); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
 	return nil
 }
-
-func (a *App) shiftBoundary() time.Time {
-	defer func() {
-		a.startTime = a.startTime.Add(a.scanInterval)
-	}()
-
-	return a.startTime
-}
@@ -7,25 +7,36 @@ import (
 	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 	"github.com/stretchr/testify/require"
 )
 
 type Mock struct {
-	Data       []model.Event
-	deleteCnt  int
+	Data       []model.DueReminder
 	publishCnt int
 }
 
-func (t *Mock) ListEventsToBeNotified(_ context.Context, _, _ time.Time) ([]model.Event, error) {
+func (t *Mock) ClaimDueReminders(_ context.Context, _, _, _ time.Time, _ int) ([]model.DueReminder, error) {
 	return t.Data, nil
 }
 
-func (t *Mock) DeleteEventsOlderThan(_ context.Context, _ time.Time) error {
-	t.deleteCnt++
+func (t *Mock) ListEventsToPurge(_ context.Context, _ model.PurgeFilter, _ int) ([]model.Event, error) {
+	return nil, nil
+}
+
+func (t *Mock) DeleteEvents(_ context.Context, _ []string) error {
+	return nil
+}
+
+func (t *Mock) DeleteChangesOlderThan(_ context.Context, _ time.Time) error {
 	return nil
 }
 
+func (t *Mock) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 func (t *Mock) Publish(_ []byte) error {
 	t.publishCnt++
 	return nil
@@ -33,24 +44,24 @@ func (t *Mock) Publish(_ []byte) error {
 
 func TestAppNotificationSuccess(t *testing.T) {
 	tests := []struct {
-		Data               []model.Event
+		Data               []model.DueReminder
 		expectedPublishCnt int
 	}{
 		{
-			Data: []model.Event{
-				{ID: "id1"},
-				{ID: "id2"},
+			Data: []model.DueReminder{
+				{Reminder: model.Reminder{ID: "r1"}, Event: model.Event{ID: "id1"}},
+				{Reminder: model.Reminder{ID: "r2"}, Event: model.Event{ID: "id1"}},
 			},
 			expectedPublishCnt: 2,
 		},
 		{
-			Data: []model.Event{
-				{ID: "id1"},
+			Data: []model.DueReminder{
+				{Reminder: model.Reminder{ID: "r1"}, Event: model.Event{ID: "id1"}},
 			},
 			expectedPublishCnt: 1,
 		},
 		{
-			Data:               []model.Event{},
+			Data:               []model.DueReminder{},
 			expectedPublishCnt: 0,
 		},
 	}
@@ -64,7 +75,7 @@ func TestAppNotificationSuccess(t *testing.T) {
 			t.Parallel()
 
 			mock := &Mock{}
-			app := New(logger, mock, mock, 1*time.Second, 1*time.Second)
+			app := New(logger, mock, mock, 1*time.Second, time.Minute, PurgePolicy{Retention: time.Second})
 			mock.Data = tt.Data
 			app.ProcessNotifications(ctx)
 			require.Equal(t, tt.expectedPublishCnt, mock.publishCnt)
@@ -72,43 +83,32 @@ func TestAppNotificationSuccess(t *testing.T) {
 	}
 }
 
-func TestAppPurgeSuccess(t *testing.T) {
-	tests := []struct {
-		Data              []model.Event
-		expectedDeleteCnt int
-	}{
-		{
-			Data: []model.Event{
-				{ID: "id1"},
-				{ID: "id2"},
-			},
-			expectedDeleteCnt: 1,
-		},
-		{
-			Data: []model.Event{
-				{ID: "id1"},
-			},
-			expectedDeleteCnt: 1,
-		},
-		{
-			Data:              []model.Event{},
-			expectedDeleteCnt: 1,
-		},
-	}
-
-	logger := logger.New("INFO", "stdout")
+func TestAppNotificationRetry(t *testing.T) {
 	ctx := context.Background()
+	storage := memorystorage.New()
+	err := storage.AddEvent(ctx, model.Event{
+		ID:         "id1",
+		Title:      "meeting",
+		StartTime:  time.Now().Add(time.Hour),
+		EndTime:    time.Now().Add(2 * time.Hour),
+		OwnerEmail: "user@example.com",
+		Reminders:  []model.Reminder{{ID: "r1", Channel: model.ChannelEmail, NotifyTime: time.Now()}},
+	})
+	require.NoError(t, err)
 
-	for i, tt := range tests {
-		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
-			tt := tt
-			t.Parallel()
+	mock := &Mock{}
+	app := New(logger.New("INFO", "stdout"), storage, mock, time.Minute, 50*time.Millisecond, PurgePolicy{})
 
-			mock := &Mock{}
-			app := New(logger, mock, mock, 1*time.Second, 1*time.Second)
-			mock.Data = tt.Data
-			app.PurgeOldEvents(ctx)
-			require.Equal(t, tt.expectedDeleteCnt, mock.deleteCnt)
-		})
-	}
+	require.NoError(t, app.ProcessNotifications(ctx))
+	require.NoError(t, app.ProcessNotifications(ctx))
+	require.Equal(t, 1, mock.publishCnt, "claimed reminder is not dispatched twice")
+
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, app.ProcessNotifications(ctx))
+	require.Equal(t, 2, mock.publishCnt, "reminder is dispatched again when the claim expires")
+
+	require.NoError(t, storage.SetReminderNotified(ctx, "r1"))
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, app.ProcessNotifications(ctx))
+	require.Equal(t, 2, mock.publishCnt)
 }
@@ -0,0 +1,153 @@
+package schedulerapp
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+type EventArchive interface {
+	ArchiveEvents(ctx context.Context, eventIDs []string) error
+}
+
+// TableArchiver copies the events to the archive table of the storage.
+type TableArchiver struct {
+	archive EventArchive
+}
+
+func NewTableArchiver(archive EventArchive) *TableArchiver {
+	return &TableArchiver{archive: archive}
+}
+
+func (a *TableArchiver) Archive(ctx context.Context, events []model.Event) error {
+	ids := make([]string, 0, len(events))
+	for _, ev := range events {
+		ids = append(ids, ev.ID)
+	}
+	return a.archive.ArchiveEvents(ctx, ids)
+}
+
+// FileArchiver writes every batch of events to its own gzip compressed NDJSON file. The file is staged
+// within the transaction removing the batch and published once it commits, the name depends on the batch only,
+// so the batch purged again after a rollback overwrites its staged file.
+type FileArchiver struct {
+	dir string
+}
+
+//nolint:tagliatelle
+type archivedEvent struct {
+	ID           string             `json:"id"`
+	Title        string             `json:"title"`
+	StartTime    time.Time          `json:"start_time"`
+	EndTime      time.Time          `json:"end_time"`
+	Description  string             `json:"description,omitempty"`
+	NotifyBefore string             `json:"notify_before,omitempty"`
+	OwnerEmail   string             `json:"owner_email"`
+	CalendarID   string             `json:"calendar_id,omitempty"`
+	Reminders    []archivedReminder `json:"reminders"`
+}
+
+//nolint:tagliatelle
+type archivedReminder struct {
+	ID         string    `json:"id"`
+	Before     string    `json:"before"`
+	Channel    string    `json:"channel"`
+	Target     string    `json:"target,omitempty"`
+	NotifyTime time.Time `json:"notify_time"`
+	Notified   bool      `json:"notified"`
+}
+
+// stagedSuffix marks the archive file of the batch whose transaction has not committed yet.
+const stagedSuffix = ".staged"
+
+func NewFileArchiver(dir string) *FileArchiver {
+	return &FileArchiver{dir: dir}
+}
+
+func (a *FileArchiver) Archive(_ context.Context, events []model.Event) (err error) {
+	staged := a.fileName(events) + stagedSuffix
+	file, err := os.OpenFile(staged, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot create archive file: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+		if err != nil {
+			os.Remove(staged)
+		}
+	}()
+
+	zw := gzip.NewWriter(file)
+	encoder := json.NewEncoder(zw)
+	for _, ev := range events {
+		if err := encoder.Encode(toArchivedEvent(ev)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Publish renames the staged file of the removed batch to its final name.
+func (a *FileArchiver) Publish(events []model.Event) error {
+	name := a.fileName(events)
+	if err := os.Rename(name+stagedSuffix, name); err != nil {
+		return fmt.Errorf("cannot publish archive file: %w", err)
+	}
+	return nil
+}
+
+// Discard removes the staged file of the batch which is kept.
+func (a *FileArchiver) Discard(events []model.Event) {
+	os.Remove(a.fileName(events) + stagedSuffix)
+}
+
+// fileName names the file after the start time of the first event and the hash of the event ids.
+func (a *FileArchiver) fileName(events []model.Event) string {
+	hash := sha256.New()
+	var first time.Time
+	for i, ev := range events {
+		if i == 0 {
+			first = ev.StartTime
+		}
+		hash.Write([]byte(ev.ID))
+		hash.Write([]byte{0})
+	}
+	return filepath.Join(a.dir, fmt.Sprintf("events-%s-%x.ndjson.gz",
+		first.UTC().Format("20060102T150405"), hash.Sum(nil)[:8]))
+}
+
+func toArchivedEvent(ev model.Event) archivedEvent {
+	reminders := make([]archivedReminder, 0, len(ev.Reminders))
+	for _, r := range ev.Reminders {
+		reminders = append(reminders, archivedReminder{
+			ID:         r.ID,
+			Before:     r.Before,
+			Channel:    string(r.Channel),
+			Target:     r.Target,
+			NotifyTime: r.NotifyTime,
+			Notified:   r.Notified,
+		})
+	}
+
+	return archivedEvent{
+		ID:           ev.ID,
+		Title:        ev.Title,
+		StartTime:    ev.StartTime,
+		EndTime:      ev.EndTime,
+		Description:  ev.Description,
+		NotifyBefore: ev.NotifyBefore,
+		OwnerEmail:   ev.OwnerEmail,
+		CalendarID:   ev.CalendarID,
+		Reminders:    reminders,
+	}
+}
@@ -0,0 +1,187 @@
+package schedulerapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const defaultPurgeBatchSize = 500
+
+// RetentionRule overrides the retention period for the events of the owner or of the calendar.
+// Calendar rules take precedence over owner rules.
+type RetentionRule struct {
+	OwnerEmail string
+	CalendarID string
+	Period     time.Duration
+}
+
+type PurgePolicy struct {
+	Retention time.Duration
+	Rules     []RetentionRule
+	// Archiver keeps the purged events, they are removed permanently when it is nil.
+	Archiver  Archiver
+	BatchSize int
+	DryRun    bool
+}
+
+type Archiver interface {
+	Archive(ctx context.Context, events []model.Event) error
+}
+
+// StagedArchiver writes the archive outside the storage, like the file one. Archive stages the batch within
+// the transaction removing it, the staged batch is published once the transaction commits and discarded otherwise.
+type StagedArchiver interface {
+	Archiver
+	Publish(events []model.Event) error
+	Discard(events []model.Event)
+}
+
+// SetPurgePolicy replaces the policy, the purge in progress completes with the previous one.
+func (a *App) SetPurgePolicy(policy PurgePolicy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.purge = policy
+}
+
+func (a *App) purgePolicy() PurgePolicy {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.purge
+}
+
+func (a *App) PurgeOldEvents(ctx context.Context) error {
+	a.logger.Debug("Purging old events...")
+
+	policy := a.purgePolicy()
+	now := time.Now()
+	total := 0
+	for _, filter := range policy.filters(now) {
+		count, err := a.purgeEvents(ctx, policy, filter)
+		total += count
+		if err != nil {
+			return fmt.Errorf("failed to purge old events: %w", err)
+		}
+	}
+
+	if policy.DryRun {
+		a.logger.Info("Dry run of purging old events is completed", "Events", total)
+		return nil
+	}
+
+	if err := a.storage.DeleteChangesOlderThan(ctx, now.Add(-policy.Retention)); err != nil {
+		return fmt.Errorf("failed to delete old changes: %w", err)
+	}
+	if total > 0 {
+		a.logger.Info("Old events are purged", "Events", total, "Archived", policy.Archiver != nil)
+	}
+
+	return nil
+}
+
+// purgeEvents removes the matching events batch by batch, each batch in its own transaction.
+func (a *App) purgeEvents(ctx context.Context, policy PurgePolicy, filter model.PurgeFilter) (int, error) {
+	batchSize := policy.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultPurgeBatchSize
+	}
+
+	count := 0
+	for ctx.Err() == nil {
+		batch, err := a.storage.ListEventsToPurge(ctx, filter, batchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(batch) == 0 {
+			return count, nil
+		}
+
+		if policy.DryRun {
+			for _, ev := range batch {
+				a.logger.InfoContext(logger.WithOwner(ctx, ev.OwnerEmail), "Event would be purged",
+					"Event Id", ev.ID,
+					"Owner", ev.OwnerEmail,
+					"Calendar Id", ev.CalendarID,
+					"Start Time", ev.StartTime,
+				)
+			}
+		} else if err := a.removeBatch(ctx, policy.Archiver, batch); err != nil {
+			return count, err
+		}
+
+		count += len(batch)
+		if len(batch) < batchSize {
+			return count, nil
+		}
+		last := batch[len(batch)-1]
+		filter.AfterTime, filter.AfterID = last.StartTime, last.ID
+	}
+
+	return count, ctx.Err()
+}
+
+// removeBatch removes the batch in its own transaction, the staged archive is published after the commit.
+func (a *App) removeBatch(ctx context.Context, archiver Archiver, batch []model.Event) error {
+	err := a.storage.InTransaction(ctx, func(ctx context.Context) error {
+		return a.removeEvents(ctx, archiver, batch)
+	})
+	staged, ok := archiver.(StagedArchiver)
+	if !ok {
+		return err
+	}
+	if err != nil {
+		staged.Discard(batch)
+		return err
+	}
+	if err := staged.Publish(batch); err != nil {
+		return fmt.Errorf("failed to archive events: %w", err)
+	}
+	return nil
+}
+
+func (a *App) removeEvents(ctx context.Context, archiver Archiver, events []model.Event) error {
+	if archiver != nil {
+		if err := archiver.Archive(ctx, events); err != nil {
+			return fmt.Errorf("failed to archive events: %w", err)
+		}
+	}
+
+	ids := make([]string, 0, len(events))
+	for _, ev := range events {
+		ids = append(ids, ev.ID)
+	}
+	return a.storage.DeleteEvents(ctx, ids)
+}
+
+// filters splits the events between the retention rules, the default retention applies to the rest.
+func (p PurgePolicy) filters(now time.Time) []model.PurgeFilter {
+	var owners, calendars []string
+	for _, rule := range p.Rules {
+		if rule.CalendarID != "" {
+			calendars = append(calendars, rule.CalendarID)
+		} else {
+			owners = append(owners, rule.OwnerEmail)
+		}
+	}
+
+	filters := make([]model.PurgeFilter, 0, len(p.Rules)+1)
+	for _, rule := range p.Rules {
+		filter := model.PurgeFilter{Before: now.Add(-rule.Period)}
+		if rule.CalendarID != "" {
+			filter.CalendarID = rule.CalendarID
+		} else {
+			filter.OwnerEmail = rule.OwnerEmail
+			filter.ExcludeCalendars = calendars
+		}
+		filters = append(filters, filter)
+	}
+
+	return append(filters, model.PurgeFilter{
+		Before:           now.Add(-p.Retention),
+		ExcludeOwners:    owners,
+		ExcludeCalendars: calendars,
+	})
+}
@@ -0,0 +1,184 @@
+package schedulerapp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+const day = 24 * time.Hour
+
+func seedEvents(t *testing.T, storage *memorystorage.Storage) {
+	t.Helper()
+
+	events := []model.Event{
+		{ID: "old", OwnerEmail: "user@example.com", StartTime: time.Now().Add(-10 * day)},
+		{ID: "recent", OwnerEmail: "user@example.com", StartTime: time.Now().Add(-day)},
+		{ID: "vip-old", OwnerEmail: "vip@example.com", StartTime: time.Now().Add(-10 * day)},
+		{ID: "vip-ancient", OwnerEmail: "vip@example.com", StartTime: time.Now().Add(-100 * day)},
+		{ID: "team-old", OwnerEmail: "vip@example.com", CalendarID: "team", StartTime: time.Now().Add(-3 * day)},
+	}
+	for _, ev := range events {
+		ev.Title = ev.ID
+		ev.EndTime = ev.StartTime.Add(time.Hour)
+		require.NoError(t, storage.AddEvent(context.Background(), ev))
+	}
+}
+
+func remainingEvents(t *testing.T, storage *memorystorage.Storage) []string {
+	t.Helper()
+
+	ids := make([]string, 0)
+	for _, id := range []string{"old", "recent", "vip-old", "vip-ancient", "team-old"} {
+		_, err := storage.GetEvent(context.Background(), id)
+		if err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		require.ErrorAs(t, err, &customerrors.NotFound{})
+	}
+	return ids
+}
+
+func TestAppPurgeRetention(t *testing.T) {
+	rules := []RetentionRule{
+		{OwnerEmail: "vip@example.com", Period: 30 * day},
+		{CalendarID: "team", Period: 2 * day},
+	}
+
+	tests := []struct {
+		policy    PurgePolicy
+		remaining []string
+	}{
+		{
+			policy:    PurgePolicy{Retention: 5 * day},
+			remaining: []string{"recent", "team-old"},
+		},
+		{
+			policy:    PurgePolicy{Retention: 5 * day, Rules: rules},
+			remaining: []string{"recent", "vip-old"},
+		},
+		{
+			policy:    PurgePolicy{Retention: 5 * day, Rules: rules, BatchSize: 1},
+			remaining: []string{"recent", "vip-old"},
+		},
+		{
+			policy:    PurgePolicy{Retention: 5 * day, Rules: rules, DryRun: true, BatchSize: 1},
+			remaining: []string{"old", "recent", "vip-old", "vip-ancient", "team-old"},
+		},
+	}
+
+	logger := logger.New("INFO", "stdout")
+	ctx := context.Background()
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			storage := memorystorage.New()
+			seedEvents(t, storage)
+			app := New(logger, storage, &Mock{}, time.Second, time.Minute, tt.policy)
+
+			require.NoError(t, app.PurgeOldEvents(ctx))
+			require.Equal(t, tt.remaining, remainingEvents(t, storage))
+		})
+	}
+}
+
+func TestAppSetPurgePolicy(t *testing.T) {
+	ctx := context.Background()
+	storage := memorystorage.New()
+	seedEvents(t, storage)
+	app := New(logger.New("INFO", "stdout"), storage, &Mock{}, time.Second, time.Minute, PurgePolicy{Retention: 50 * day})
+
+	require.NoError(t, app.PurgeOldEvents(ctx))
+	require.Equal(t, []string{"old", "recent", "vip-old", "team-old"}, remainingEvents(t, storage))
+
+	app.SetPurgePolicy(PurgePolicy{Retention: 5 * day})
+	require.NoError(t, app.PurgeOldEvents(ctx))
+	require.Equal(t, []string{"recent", "team-old"}, remainingEvents(t, storage))
+}
+
+func TestAppPurgeArchive(t *testing.T) {
+	dir := t.TempDir()
+	storage := memorystorage.New()
+	seedEvents(t, storage)
+
+	app := New(logger.New("INFO", "stdout"), storage, &Mock{}, time.Second, time.Minute, PurgePolicy{
+		Retention: 5 * day,
+		Archiver:  NewFileArchiver(dir),
+		BatchSize: 2,
+	})
+	require.NoError(t, app.PurgeOldEvents(context.Background()))
+	require.Equal(t, []string{"recent", "team-old"}, remainingEvents(t, storage))
+
+	files, err := filepath.Glob(filepath.Join(dir, "events-*.ndjson.gz"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	archived := make(map[string]archivedEvent)
+	for _, name := range files {
+		file, err := os.Open(name)
+		require.NoError(t, err)
+		zr, err := gzip.NewReader(file)
+		require.NoError(t, err)
+
+		scanner := bufio.NewScanner(zr)
+		for scanner.Scan() {
+			var ev archivedEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+			archived[ev.ID] = ev
+		}
+		require.NoError(t, scanner.Err())
+		file.Close()
+	}
+	require.Len(t, archived, 3)
+	require.Equal(t, "vip@example.com", archived["vip-ancient"].OwnerEmail)
+}
+
+// failingDeletes fails the deletion of the events, the transaction removing the batch rolls back.
+type failingDeletes struct {
+	*memorystorage.Storage
+}
+
+func (failingDeletes) DeleteEvents(context.Context, []string) error {
+	return errors.New("disk is full")
+}
+
+func TestAppPurgeArchiveRollback(t *testing.T) {
+	dir := t.TempDir()
+	storage := memorystorage.New()
+	seedEvents(t, storage)
+	policy := PurgePolicy{Retention: 5 * day, Archiver: NewFileArchiver(dir), BatchSize: 2}
+
+	failing := New(logger.New("INFO", "stdout"), failingDeletes{storage}, &Mock{}, time.Second, time.Minute, policy)
+	require.ErrorContains(t, failing.PurgeOldEvents(context.Background()), "disk is full")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "the archive of the kept batch is discarded")
+
+	app := New(logger.New("INFO", "stdout"), storage, &Mock{}, time.Second, time.Minute, policy)
+	require.NoError(t, app.PurgeOldEvents(context.Background()))
+	require.NoError(t, app.PurgeOldEvents(context.Background()))
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.True(t, strings.HasSuffix(entry.Name(), ".ndjson.gz"), entry.Name())
+	}
+}
@@ -3,38 +3,84 @@ package senderapp
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/common"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/webhook"
 )
 
-var errCannotBeEmpty = errors.New("cannot be empty")
+var (
+	errCannotBeEmpty = errors.New("cannot be empty")
+	errWrongChannel  = errors.New("unknown channel")
+)
 
 type App struct {
 	logger  common.Logger
 	storage storage.Storage
+
+	mu        sync.RWMutex
+	notifiers map[model.Channel]Notifier
 }
 
 func New(logger common.Logger, storage storage.Storage) *App {
-	return &App{logger: logger, storage: storage}
+	return &App{
+		logger:    logger,
+		storage:   storage,
+		notifiers: NewNotifiers(logger, 0),
+	}
 }
 
-func (a *App) Notify(ctx context.Context, dto contracts.Notification) error {
-	params := []any{
-		"Event Id", dto.ID,
-		"Time", dto.Time,
-		"Title", dto.Title,
-		"Email", dto.OwnerEmail,
+// NewNotifiers creates the notifier of every channel, the zero webhookTimeout stands for the default one.
+func NewNotifiers(logger common.Logger, webhookTimeout time.Duration) map[model.Channel]Notifier {
+	if webhookTimeout <= 0 {
+		webhookTimeout = defaultWebhookTimeout
+	}
+	return map[model.Channel]Notifier{
+		model.ChannelEmail:   NewLogNotifier(logger),
+		model.ChannelWebhook: NewWebhookNotifier(webhook.NewClient(webhookTimeout)),
 	}
+}
+
+// SetNotifiers replaces the notifiers, the notifications being sent complete with the previous ones.
+func (a *App) SetNotifiers(notifiers map[model.Channel]Notifier) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.notifiers = notifiers
+}
+
+func (a *App) notifier(channel model.Channel) (Notifier, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	notifier, ok := a.notifiers[channel]
+	return notifier, ok
+}
+
+func (a *App) Notify(ctx context.Context, dto contracts.Notification) error {
 	if err := a.validateAttributes(dto); err != nil {
 		return err
 	}
-	a.logger.Info("Notification sent", params...)
+	ctx = logger.WithOwner(ctx, dto.OwnerEmail)
 
-	err := a.storage.SetEventNotified(ctx, dto.ID)
-	return err
+	channel := model.Channel(dto.Channel)
+	if channel == "" {
+		channel = model.ChannelEmail
+	}
+	notifier, ok := a.notifier(channel)
+	if !ok {
+		return customerrors.ValidationError{Field: "Channel", Err: fmt.Errorf("%w %q", errWrongChannel, channel)}
+	}
+	if err := notifier.Send(ctx, dto); err != nil {
+		return err
+	}
+
+	return a.storage.SetReminderNotified(ctx, dto.ReminderID)
 }
 
 func (a *App) validateAttributes(dto contracts.Notification) error {
@@ -46,6 +92,10 @@ func (a *App) validateAttributes(dto contracts.Notification) error {
 		return customerrors.ValidationError{Field: "ID", Err: errCannotBeEmpty}
 	}
 
+	if dto.ReminderID == "" {
+		return customerrors.ValidationError{Field: "ReminderID", Err: errCannotBeEmpty}
+	}
+
 	if dto.OwnerEmail == "" {
 		return customerrors.ValidationError{Field: "Email", Err: errCannotBeEmpty}
 	}
@@ -2,8 +2,11 @@ package senderapp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/webhook"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,12 +26,15 @@ func TestAppNotifySuccess(t *testing.T) {
 	tests := []contracts.Notification{
 		{
 			ID:         "id1",
+			ReminderID: "r1",
 			Title:      "meeting 1",
 			Time:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
 			OwnerEmail: "user@example.com",
 		},
 		{
 			ID:         "id2",
+			ReminderID: "r2",
+			Channel:    "email",
 			Title:      "meeting 2",
 			Time:       time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC).Unix(),
 			OwnerEmail: "user@example.com",
@@ -37,7 +44,8 @@ func TestAppNotifySuccess(t *testing.T) {
 	logger := logger.New("INFO", "stdout")
 	ctx := context.Background()
 	storageConfig := config.StorageConf{Mode: StorageMode}
-	storage := storage.NewStorage(storageConfig)
+	storage, err := storage.NewStorage(storageConfig)
+	require.NoError(t, err)
 	app := New(logger, storage)
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
@@ -52,6 +60,7 @@ func TestAppNotifySuccess(t *testing.T) {
 					StartTime:  time.Unix(tt.Time, 0),
 					EndTime:    time.Unix(tt.Time, 0),
 					OwnerEmail: tt.OwnerEmail,
+					Reminders:  []model.Reminder{{ID: tt.ReminderID, Channel: model.ChannelEmail}},
 				})
 
 			err := app.Notify(ctx, tt)
@@ -77,6 +86,7 @@ func TestAppNotifyError(t *testing.T) {
 		{
 			notification: contracts.Notification{
 				ID:         "ID2",
+				ReminderID: "R2",
 				Title:      "",
 				Time:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
 				OwnerEmail: "user@example.com",
@@ -86,18 +96,40 @@ func TestAppNotifyError(t *testing.T) {
 		{
 			notification: contracts.Notification{
 				ID:         "ID3",
+				ReminderID: "R3",
 				Title:      "Title 3",
 				Time:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
 				OwnerEmail: "",
 			},
 			err: customerrors.ValidationError{Field: "Email", Err: errors.New("cannot be empty")},
 		},
+		{
+			notification: contracts.Notification{
+				ID:         "ID4",
+				Title:      "Title 4",
+				Time:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
+				OwnerEmail: "user@example.com",
+			},
+			err: customerrors.ValidationError{Field: "ReminderID", Err: errors.New("cannot be empty")},
+		},
+		{
+			notification: contracts.Notification{
+				ID:         "ID5",
+				ReminderID: "R5",
+				Channel:    "pigeon",
+				Title:      "Title 5",
+				Time:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
+				OwnerEmail: "user@example.com",
+			},
+			err: customerrors.ValidationError{Field: "Channel", Err: errors.New("unknown channel \"pigeon\"")},
+		},
 	}
 
 	logger := logger.New("INFO", "stdout")
 	ctx := context.Background()
 	storageConfig := config.StorageConf{Mode: StorageMode}
-	storage := storage.NewStorage(storageConfig)
+	storage, err := storage.NewStorage(storageConfig)
+	require.NoError(t, err)
 	app := New(logger, storage)
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
@@ -112,3 +144,84 @@ func TestAppNotifyError(t *testing.T) {
 		})
 	}
 }
+
+func TestAppNotifyWebhook(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- payload
+		if payload["reminder_id"] == "failing" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	storage, err := storage.NewStorage(config.StorageConf{Mode: StorageMode})
+	require.NoError(t, err)
+	app := New(logger.New("INFO", "stdout"), storage)
+	// the test server listens on the loopback, which the default client refuses
+	app.SetNotifiers(map[model.Channel]Notifier{model.ChannelWebhook: NewWebhookNotifier(server.Client())})
+	start := time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC)
+	err = storage.AddEvent(ctx, model.Event{
+		ID:         "id1",
+		Title:      "meeting",
+		StartTime:  start,
+		EndTime:    start.Add(time.Hour),
+		OwnerEmail: "user@example.com",
+		Reminders: []model.Reminder{
+			{ID: "hook", Channel: model.ChannelWebhook, Target: server.URL, NotifyTime: start.Add(-time.Minute)},
+			{ID: "failing", Channel: model.ChannelWebhook, Target: server.URL, NotifyTime: start.Add(-time.Hour)},
+		},
+	})
+	require.NoError(t, err)
+
+	dto := contracts.Notification{
+		ID:         "id1",
+		ReminderID: "hook",
+		Channel:    string(model.ChannelWebhook),
+		Target:     server.URL,
+		Title:      "meeting",
+		Time:       start.Unix(),
+		OwnerEmail: "user@example.com",
+	}
+	require.NoError(t, app.Notify(ctx, dto))
+	require.Equal(t, "hook", (<-received)["reminder_id"])
+
+	dto.ReminderID = "failing"
+	require.ErrorContains(t, app.Notify(ctx, dto), "status 502")
+	<-received
+
+	ev, err := storage.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+	require.True(t, ev.Reminders[0].Notified)
+	require.False(t, ev.Reminders[1].Notified)
+}
+
+func TestAppNotifyWebhookForbiddenAddress(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	storage, err := storage.NewStorage(config.StorageConf{Mode: StorageMode})
+	require.NoError(t, err)
+	app := New(logger.New("INFO", "stdout"), storage)
+
+	err = app.Notify(context.Background(), contracts.Notification{
+		ID:         "id1",
+		ReminderID: "hook",
+		Channel:    string(model.ChannelWebhook),
+		Target:     server.URL,
+		Title:      "meeting",
+		Time:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC).Unix(),
+		OwnerEmail: "user@example.com",
+	})
+	require.ErrorIs(t, err, webhook.ErrForbiddenAddress)
+	require.False(t, called)
+}
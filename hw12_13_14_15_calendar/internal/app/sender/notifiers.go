@@ -0,0 +1,90 @@
+package senderapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/common"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+type Notifier interface {
+	Send(ctx context.Context, dto contracts.Notification) error
+}
+
+// LogNotifier stands in for the mail delivery.
+type LogNotifier struct {
+	logger common.Logger
+}
+
+func NewLogNotifier(logger common.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, dto contracts.Notification) error {
+	email := dto.OwnerEmail
+	if dto.Target != "" {
+		email = dto.Target
+	}
+	n.logger.InfoContext(ctx, "Notification sent",
+		"Event Id", dto.ID,
+		"Reminder Id", dto.ReminderID,
+		"Time", dto.Time,
+		"Title", dto.Title,
+		"Email", email,
+	)
+	return nil
+}
+
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+//nolint:tagliatelle
+type webhookPayload struct {
+	EventID    string `json:"event_id"`
+	ReminderID string `json:"reminder_id"`
+	Title      string `json:"title"`
+	Time       int64  `json:"time"`
+	OwnerEmail string `json:"owner_email"`
+}
+
+func NewWebhookNotifier(client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{client: client}
+}
+
+func (n *WebhookNotifier) Send(ctx context.Context, dto contracts.Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		EventID:    dto.ID,
+		ReminderID: dto.ReminderID,
+		Title:      dto.Title,
+		Time:       dto.Time,
+		OwnerEmail: dto.OwnerEmail,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dto.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("cannot create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
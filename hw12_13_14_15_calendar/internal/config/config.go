@@ -1,20 +1,23 @@
 package config
 
-import (
-	"log"
-
-	"github.com/BurntSushi/toml"
-)
-
 const (
 	StorageModePostgres = "postgres"
 	StorageModeMemory   = "memory"
+	StorageModeSQLite   = "sqlite"
+)
+
+const (
+	ArchiveModeNone  = ""
+	ArchiveModeTable = "table"
+	ArchiveModeFile  = "file"
 )
 
 type CalendarConfig struct {
-	Logger   LoggerConf
-	Endpoint EndpointConf
-	Storage  StorageConf
+	Logger    LoggerConf
+	Endpoint  EndpointConf
+	Storage   StorageConf
+	Cache     CacheConf
+	RateLimit RateLimitConf
 }
 
 type SchedulerConfig struct {
@@ -22,32 +25,110 @@ type SchedulerConfig struct {
 	Storage  StorageConf
 	Queue    QueueProducerConf
 	Schedule ScheduleConf
+	Purge    PurgeConf
 }
 
 type SenderConfig struct {
-	Logger  LoggerConf
-	Queue   QueueConsumerConf
-	Storage StorageConf
+	Logger   LoggerConf
+	Queue    QueueConsumerConf
+	Storage  StorageConf
+	Notifier NotifierConf
+}
+
+// ClientConfig configures the command line client.
+type ClientConfig struct {
+	Server ServerConf
+	Output string // table, json
+}
+
+// ServerConf tells the client where the calendar is and who calls it.
+type ServerConf struct {
+	Address string
+	Token   string // sent as the bearer token to the proxy authenticating the callers
+	User    string // sent as the caller email, the calendar takes it only with trustUserHeader
+	Timeout string
+	TLS     TLSConf
 }
 
 type LoggerConf struct {
 	Level  string
 	Output string
+	Format string // json, text, logfmt
+	// MaxSize in megabytes and RotateEvery rotate the Output file,
+	// MaxBackups and MaxAge limit the rotated files kept
+	MaxSize     int
+	RotateEvery string
+	MaxBackups  int
+	MaxAge      string
 }
 
 type EndpointConf struct {
 	Host     string
 	HTTPPort int
 	GRPCPort int
+	TLS      TLSConf
+	// ShutdownTimeout bounds the draining of the requests in flight, the ones still running are cut
+	ShutdownTimeout string
+	// the callers are identified by the verified client certificates, TrustUserHeader takes the identity
+	// from x-user-email for the proxy authenticating the callers, AllowAnonymous lets the callers without
+	// identity in with the full access
+	TrustUserHeader bool
+	AllowAnonymous  bool
+}
+
+// TLSConf secures the connection, the certificate, the key and the CA are read again when their files change.
+// The servers present CertFile and verify the client certificates against CAFile if ClientAuth is set.
+// The clients verify the server against CAFile, the system roots without it, and present CertFile if set.
+type TLSConf struct {
+	Enabled    bool
+	CertFile   string
+	KeyFile    string
+	CAFile     string
+	ClientAuth bool
+	ServerName string // overrides the host name the server certificate is verified for
 }
 
 type StorageConf struct {
-	Mode     string
-	Host     string
-	Port     int
-	DBName   string
-	User     string
-	Password string
+	Mode        string
+	Host        string
+	Port        int
+	DBName      string
+	User        string
+	Password    string
+	Path        string // database file of the sqlite mode, snapshot file of the memory mode
+	AutoMigrate bool
+	// SnapshotInterval and WAL persist the memory mode with Path set
+	SnapshotInterval string
+	WAL              bool
+}
+
+// CacheConf caches the event lookups, the zero capacity disables the cache. TTL is required
+// with the postgres and the sqlite storages, the scheduler and the sender write to them too.
+type CacheConf struct {
+	Capacity  int
+	TTL       string
+	StatsAddr string
+}
+
+// NotifierConf configures the delivery of the notifications.
+type NotifierConf struct {
+	WebhookTimeout string
+}
+
+// RateLimitConf limits the calls of every client, told apart by the identity or the address.
+// Rate is like "10/s" or "600/m", the empty one does not limit. Burst defaults to the calls of the rate.
+// Methods override the rate for the gRPC methods, like "/calendar.Events/CreateEvent".
+type RateLimitConf struct {
+	Rate       string
+	Burst      int
+	MaxClients int
+	Methods    []MethodRateLimitConf
+}
+
+type MethodRateLimitConf struct {
+	Method string
+	Rate   string
+	Burst  int
 }
 
 type QueueServerConf struct {
@@ -55,6 +136,7 @@ type QueueServerConf struct {
 	Port     int
 	User     string
 	Password string
+	TLS      TLSConf // connects with amqps, usually on the port 5671
 }
 
 type QueueConsumerConf struct {
@@ -73,28 +155,111 @@ type QueueProducerConf struct {
 type ScheduleConf struct {
 	RetentionPeriod string
 	Interval        string
+	ClaimTimeout    string
+	StatusAddr      string
+	Notifications   JobConf
+	Purge           JobConf
+}
+
+// JobConf schedules the job either by the cron expression or with the interval, ScheduleConf.Interval is the default.
+type JobConf struct {
+	Cron     string
+	Interval string
+	Jitter   string
 }
 
-func NewCalendarConfig(filePath string) (c CalendarConfig) {
-	_, err := toml.DecodeFile(filePath, &c)
-	if err != nil {
-		log.Fatalf("Failed to load config file: %v", err)
+type PurgeConf struct {
+	Archive    string
+	ArchiveDir string
+	BatchSize  int
+	DryRun     bool
+	Retention  []RetentionConf
+}
+
+type RetentionConf struct {
+	OwnerEmail string
+	CalendarID string
+	Period     string
+}
+
+// NewCalendarConfig layers the defaults, the file at filePath, the environment and the flags.
+// The error lists every invalid value.
+func NewCalendarConfig(filePath string, flags *Flags) (CalendarConfig, error) {
+	c := CalendarConfig{
+		Logger:    defaultLogger(),
+		Endpoint:  EndpointConf{HTTPPort: 8080, GRPCPort: 15000, ShutdownTimeout: "10s"},
+		Storage:   defaultStorage(),
+		Cache:     CacheConf{TTL: "30s"},
+		RateLimit: RateLimitConf{MaxClients: 10000},
+	}
+	var p problems
+	if err := load(&c, filePath, flags, &p); err != nil {
+		return CalendarConfig{}, err
 	}
-	return c
+	c.validate(&p)
+	return c, p.err()
 }
 
-func NewSchedulerConfig(filePath string) (c SchedulerConfig) {
-	_, err := toml.DecodeFile(filePath, &c)
-	if err != nil {
-		log.Fatalf("Failed to load config file: %v", err)
+// NewSchedulerConfig layers the defaults, the file at filePath, the environment and the flags.
+// The error lists every invalid value.
+func NewSchedulerConfig(filePath string, flags *Flags) (SchedulerConfig, error) {
+	c := SchedulerConfig{
+		Logger:  defaultLogger(),
+		Storage: defaultStorage(),
+		Queue:   QueueProducerConf{QueueServerConf: defaultQueueServer()},
+		Schedule: ScheduleConf{
+			Interval:        "1m",
+			RetentionPeriod: "8760h",
+		},
+	}
+	var p problems
+	if err := load(&c, filePath, flags, &p); err != nil {
+		return SchedulerConfig{}, err
 	}
-	return c
+	c.validate(&p)
+	return c, p.err()
 }
 
-func NewSenderConfig(filePath string) (c SenderConfig) {
-	_, err := toml.DecodeFile(filePath, &c)
-	if err != nil {
-		log.Fatalf("Failed to load config file: %v", err)
+// NewSenderConfig layers the defaults, the file at filePath, the environment and the flags.
+// The error lists every invalid value.
+func NewSenderConfig(filePath string, flags *Flags) (SenderConfig, error) {
+	c := SenderConfig{
+		Logger:   defaultLogger(),
+		Queue:    QueueConsumerConf{QueueServerConf: defaultQueueServer()},
+		Storage:  defaultStorage(),
+		Notifier: NotifierConf{WebhookTimeout: "10s"},
 	}
-	return c
+	var p problems
+	if err := load(&c, filePath, flags, &p); err != nil {
+		return SenderConfig{}, err
+	}
+	c.validate(&p)
+	return c, p.err()
+}
+
+// NewClientConfig layers the defaults, the file at filePath, the environment and the flags.
+// The error lists every invalid value.
+func NewClientConfig(filePath string, flags *Flags) (ClientConfig, error) {
+	c := ClientConfig{
+		Server: ServerConf{Address: "localhost:15000", Timeout: "10s"},
+		Output: "table",
+	}
+	var p problems
+	if err := load(&c, filePath, flags, &p); err != nil {
+		return ClientConfig{}, err
+	}
+	c.validate(&p)
+	return c, p.err()
+}
+
+func defaultLogger() LoggerConf {
+	return LoggerConf{Level: "INFO", Output: "stdout", Format: "json"}
+}
+
+func defaultStorage() StorageConf {
+	return StorageConf{Mode: StorageModeMemory, Port: 5432}
+}
+
+func defaultQueueServer() QueueServerConf {
+	return QueueServerConf{Port: 5672}
 }
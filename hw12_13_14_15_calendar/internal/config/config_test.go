@@ -1,7 +1,9 @@
 package config
 
 import (
+	"flag"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -31,10 +33,241 @@ func TestConfigCases(t *testing.T) {
 			if _, err := os.Stat(tc.configFile); err != nil {
 				require.NoError(t, err)
 			}
-			c := NewCalendarConfig(tc.configFile)
+			c, err := NewCalendarConfig(tc.configFile, nil)
+			require.NoError(t, err)
 
 			require.Equal(t, tc.level, c.Logger.Level)
 			require.Equal(t, tc.output, c.Logger.Output)
 		})
 	}
 }
+
+func TestConfigShipped(t *testing.T) {
+	_, err := NewCalendarConfig("../../configs/calendar_config.toml", nil)
+	require.NoError(t, err)
+	_, err = NewSchedulerConfig("../../configs/scheduler_config.toml", nil)
+	require.NoError(t, err)
+	_, err = NewSenderConfig("../../configs/sender_config.toml", nil)
+	require.NoError(t, err)
+	_, err = NewClientConfig("../../configs/client_config.toml", nil)
+	require.NoError(t, err)
+}
+
+func TestConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.toml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+[storage]
+mode = "postgres"
+host = "db"
+dbname = "calendar"
+user = "file-user"
+password = "file-password"
+`), 0o600))
+	secret := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(secret, []byte("secret-password\n"), 0o600))
+
+	t.Setenv("CALENDAR_STORAGE_USER", "env-user")
+	t.Setenv("CALENDAR_STORAGE_PASSWORD_FILE", secret)
+	t.Setenv("CALENDAR_STORAGE_HOST", "env-host")
+	t.Setenv("CALENDAR_STORAGE_AUTO_MIGRATE", "true")
+
+	fs := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	flags := NewFlags(fs, CalendarConfig{})
+	require.NoError(t, fs.Parse([]string{"-storage.host", "flag-host", "-endpoint.http-port", "9090"}))
+
+	c, err := NewCalendarConfig(file, flags)
+	require.NoError(t, err)
+	require.Equal(t, "INFO", c.Logger.Level, "defaults")
+	require.Equal(t, 15000, c.Endpoint.GRPCPort, "defaults")
+	require.Equal(t, "calendar", c.Storage.DBName, "file")
+	require.Equal(t, "env-user", c.Storage.User, "environment")
+	require.Equal(t, "secret-password", c.Storage.Password, "environment file")
+	require.True(t, c.Storage.AutoMigrate, "environment")
+	require.Equal(t, "flag-host", c.Storage.Host, "flags")
+	require.Equal(t, 9090, c.Endpoint.HTTPPort, "flags")
+}
+
+func TestClientConfig(t *testing.T) {
+	t.Setenv("CALENDAR_SERVER_TOKEN", "secret-token")
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	flags := NewFlags(fs, ClientConfig{})
+	require.NoError(t, fs.Parse([]string{"-output", "json", "-server.address", "calendar:443"}))
+
+	c, err := NewClientConfig("", flags)
+	require.NoError(t, err)
+	require.Equal(t, "secret-token", c.Server.Token)
+	require.Equal(t, "calendar:443", c.Server.Address)
+	require.Equal(t, "json", c.Output)
+	require.Equal(t, "10s", c.Server.Timeout)
+
+	t.Setenv("CALENDAR_OUTPUT", "xml")
+	_, err = NewClientConfig("", nil)
+	require.ErrorContains(t, err, `output: "xml" is not one of table, json`)
+}
+
+func TestConfigEmbeddedKeys(t *testing.T) {
+	t.Setenv("CALENDAR_QUEUE_HOST", "queue")
+	t.Setenv("CALENDAR_QUEUE_QUEUE", "notifications")
+
+	c, err := NewSenderConfig("", nil)
+	require.NoError(t, err)
+	require.Equal(t, "queue", c.Queue.Host)
+	require.Equal(t, 5672, c.Queue.Port)
+	require.Equal(t, "notifications", c.Queue.Queue)
+}
+
+func TestConfigLoadProblems(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+[storage]
+hots = "db"
+`), 0o600))
+	t.Setenv("CALENDAR_QUEUE_PORT", "amqp")
+	t.Setenv("CALENDAR_STORAGE_PASSWORD", "password")
+	t.Setenv("CALENDAR_STORAGE_PASSWORD_FILE", "/run/secrets/password")
+
+	_, err := NewSenderConfig(file, nil)
+	require.Error(t, err)
+	for _, problem := range []string{
+		"storage.hots: unknown key",
+		`CALENDAR_QUEUE_PORT: "amqp" is not a number`,
+		"CALENDAR_STORAGE_PASSWORD: both CALENDAR_STORAGE_PASSWORD and CALENDAR_STORAGE_PASSWORD_FILE are set",
+		"queue.queue: is required",
+	} {
+		require.ErrorContains(t, err, problem)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+[logger]
+level = "LOUD"
+format = "xml"
+maxSize = 10
+
+[storage]
+mode = "postgres"
+
+[schedule]
+interval = "often"
+
+[purge]
+archive = "file"
+
+[[purge.retention]]
+period = "1y"
+`), 0o600))
+
+	_, err := NewSchedulerConfig(file, nil)
+	require.Error(t, err)
+	for _, problem := range []string{
+		`logger.level: "LOUD" is not a log level`,
+		`logger.format: "xml" is not one of json, text, logfmt`,
+		"logger.output: must be a file to be rotated",
+		"storage.host: is required",
+		"storage.db-name: is required",
+		"queue.host: is required",
+		"queue.exchange: is required",
+		`schedule.interval: "often" is not a duration`,
+		"purge.archive-dir: is required",
+		"purge.retention[0]: either ownerEmail or calendarID is required",
+		`purge.retention[0].period: "1y" is not a duration`,
+	} {
+		require.ErrorContains(t, err, problem)
+	}
+	require.NoError(t, os.WriteFile(file, []byte(`
+[rateLimit]
+rate = "fast"
+
+[[rateLimit.methods]]
+rate = "10/d"
+
+[endpoint.tls]
+enabled = true
+clientAuth = true
+
+[storage]
+mode = "sqlite"
+path = "calendar.db"
+
+[cache]
+capacity = 100
+ttl = "0s"
+
+[endpoint]
+shutdownTimeout = ""
+`), 0o600))
+	_, err = NewCalendarConfig(file, nil)
+	require.Error(t, err)
+	for _, problem := range []string{
+		`rate-limit.rate: "fast" is not a rate`,
+		"rate-limit.methods[0].method: is required",
+		`rate-limit.methods[0].rate: "10/d" is not a rate`,
+		"endpoint.tls.cert-file: is required",
+		"endpoint.tls.key-file: is required",
+		"endpoint.tls.ca-file: is required",
+		"cache.ttl: must be positive for the database shared with the scheduler and the sender",
+		"endpoint.shutdown-timeout: is required",
+	} {
+		require.ErrorContains(t, err, problem)
+	}
+}
+
+func TestConfigNames(t *testing.T) {
+	tests := []struct {
+		key  []string
+		env  string
+		flag string
+	}{
+		{key: []string{"Storage", "DBName"}, env: "CALENDAR_STORAGE_DB_NAME", flag: "storage.db-name"},
+		{key: []string{"Endpoint", "HTTPPort"}, env: "CALENDAR_ENDPOINT_HTTP_PORT", flag: "endpoint.http-port"},
+		{key: []string{"Cache", "TTL"}, env: "CALENDAR_CACHE_TTL", flag: "cache.ttl"},
+		{key: []string{"Endpoint", "TLS", "CAFile"}, env: "CALENDAR_ENDPOINT_TLS_CA_FILE", flag: "endpoint.tls.ca-file"},
+		{
+			key:  []string{"Schedule", "Notifications", "Interval"},
+			env:  "CALENDAR_SCHEDULE_NOTIFICATIONS_INTERVAL",
+			flag: "schedule.notifications.interval",
+		},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.env, envName(tc.key))
+		require.Equal(t, tc.flag, flagName(tc.key))
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	c, err := NewSchedulerConfig("../../configs/scheduler_config.toml", nil)
+	require.NoError(t, err)
+
+	next := c
+	next.Logger.Level = "WARN"
+	next.Schedule.Interval = "30s"
+	next.Schedule.RetentionPeriod = "24h"
+	next.Schedule.StatusAddr = ":9999"
+	next.Purge.Retention = []RetentionConf{{OwnerEmail: "user@example.com", Period: "1h"}}
+	next.Storage.Host = "other-db"
+	next.Queue.Port = 5673
+
+	rejected := c.Reload(next)
+	require.ElementsMatch(t, []string{"storage.host", "queue.port", "schedule.status-addr"}, rejected)
+	require.Equal(t, "WARN", c.Logger.Level)
+	require.Equal(t, "30s", c.Schedule.Interval)
+	require.Equal(t, "24h", c.Schedule.RetentionPeriod)
+	require.Equal(t, next.Purge.Retention, c.Purge.Retention)
+	require.Equal(t, "cal_database", c.Storage.Host)
+	require.Equal(t, 5672, c.Queue.Port)
+	require.Empty(t, c.Schedule.StatusAddr)
+
+	sender, err := NewSenderConfig("../../configs/sender_config.toml", nil)
+	require.NoError(t, err)
+	nextSender := sender
+	nextSender.Notifier.WebhookTimeout = "3s"
+	nextSender.Logger.Output = "/var/log/sender.log"
+
+	require.Equal(t, []string{"logger.output"}, sender.Reload(nextSender))
+	require.Equal(t, "3s", sender.Notifier.WebhookTimeout)
+	require.Equal(t, "stdout", sender.Logger.Output)
+}
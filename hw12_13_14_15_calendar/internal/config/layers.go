@@ -0,0 +1,174 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvPrefix starts the names of the environment variables overriding the configuration,
+// e.g. CALENDAR_STORAGE_PASSWORD overrides the password of the [storage] section.
+// The value of NAME_FILE is read from the file it points to, which suits the mounted secrets.
+const EnvPrefix = "CALENDAR"
+
+// Flags overrides the configuration with the command line flags named after the keys,
+// e.g. -storage.password or -endpoint.http-port.
+type Flags struct {
+	fs *flag.FlagSet
+}
+
+// NewFlags defines a flag on fs for every key of the configuration c.
+func NewFlags(fs *flag.FlagSet, c any) *Flags {
+	walk(reflect.ValueOf(c), nil, func(key []string, _ reflect.Value) {
+		fs.String(flagName(key), "", "overrides "+flagName(key)+", also "+envName(key))
+	})
+	return &Flags{fs: fs}
+}
+
+// values returns the flags set on the command line.
+func (f *Flags) values() map[string]string {
+	values := make(map[string]string)
+	if f == nil {
+		return values
+	}
+	f.fs.Visit(func(fl *flag.Flag) {
+		values[fl.Name] = fl.Value.String()
+	})
+	return values
+}
+
+// load applies the file, the environment and the flags to c holding the defaults.
+// The file is skipped if filePath is empty. The values which cannot be applied are collected to p.
+func load(c any, filePath string, flags *Flags, p *problems) error {
+	if filePath != "" {
+		md, err := toml.DecodeFile(filePath, c)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		for _, key := range md.Undecoded() {
+			p.add(key.String(), "unknown key")
+		}
+	}
+
+	flagValues := flags.values()
+	walk(reflect.ValueOf(c).Elem(), nil, func(key []string, v reflect.Value) {
+		value, ok, err := lookupEnv(envName(key))
+		if err != nil {
+			*p = append(*p, err)
+		}
+		if ok {
+			if err := setValue(v, value); err != nil {
+				p.add(envName(key), err.Error())
+			}
+		}
+
+		if value, ok := flagValues[flagName(key)]; ok {
+			if err := setValue(v, value); err != nil {
+				p.add("-"+flagName(key), err.Error())
+			}
+		}
+	})
+	return nil
+}
+
+// walk visits the keys of the scalar fields, the embedded structs share the key of their parent.
+// The lists, like the purge retention, are configured in the file only.
+func walk(v reflect.Value, key []string, visit func(key []string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldKey := key
+		if !field.Anonymous {
+			fieldKey = append(append([]string{}, key...), field.Name)
+		}
+		switch field.Type.Kind() { //nolint:exhaustive
+		case reflect.Struct:
+			walk(v.Field(i), fieldKey, visit)
+		case reflect.String, reflect.Int, reflect.Bool:
+			visit(fieldKey, v.Field(i))
+		}
+	}
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		v.SetBool(b)
+	default:
+		v.SetString(value)
+	}
+	return nil
+}
+
+// lookupEnv reads the variable or the file named by the variable with the _FILE suffix.
+func lookupEnv(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	path, fromFile := os.LookupEnv(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("%v: both %v and %v_FILE are set", name, name, name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%v_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// envName turns the key Storage.DBName into CALENDAR_STORAGE_DB_NAME.
+func envName(key []string) string {
+	parts := []string{EnvPrefix}
+	for _, name := range key {
+		parts = append(parts, strings.ToUpper(strings.Join(words(name), "_")))
+	}
+	return strings.Join(parts, "_")
+}
+
+// flagName turns the key Storage.DBName into storage.db-name.
+func flagName(key []string) string {
+	parts := make([]string, 0, len(key))
+	for _, name := range key {
+		parts = append(parts, strings.Join(words(name), "-"))
+	}
+	return strings.Join(parts, ".")
+}
+
+// words splits the field name into the lower case words, the acronyms are kept whole: HTTPPort is http, port.
+func words(name string) []string {
+	runes := []rune(name)
+	result := make([]string, 0)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		if !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			result = append(result, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(result, strings.ToLower(string(runes[start:])))
+}
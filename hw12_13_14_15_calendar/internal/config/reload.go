@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Reload applies the values of next which are safe to change at runtime:
+// the log level, the job schedules, the retention and the purge settings.
+// It returns the other changed keys, they need a restart and keep their current values.
+func (c *SchedulerConfig) Reload(next SchedulerConfig) []string {
+	rejected := reload(c, &next,
+		"logger.level",
+		"schedule.interval",
+		"schedule.retention-period",
+		"schedule.notifications",
+		"schedule.purge",
+		"purge",
+	)
+	c.Purge.Retention = next.Purge.Retention
+	return rejected
+}
+
+// Reload applies the log level and the notifier settings of next.
+// It returns the other changed keys, they need a restart and keep their current values.
+func (c *SenderConfig) Reload(next SenderConfig) []string {
+	return reload(c, &next, "logger.level", "notifier")
+}
+
+// reload copies the keys under the reloadable prefixes from next to current and lists the other changed keys.
+func reload(current, next any, reloadable ...string) []string {
+	values := make(map[string]reflect.Value)
+	walk(reflect.ValueOf(next).Elem(), nil, func(key []string, v reflect.Value) {
+		values[flagName(key)] = v
+	})
+
+	rejected := make([]string, 0)
+	walk(reflect.ValueOf(current).Elem(), nil, func(key []string, v reflect.Value) {
+		name := flagName(key)
+		value := values[name]
+		if v.Equal(value) {
+			return
+		}
+		for _, prefix := range reloadable {
+			if name == prefix || strings.HasPrefix(name, prefix+".") {
+				v.Set(value)
+				return
+			}
+		}
+		rejected = append(rejected, name)
+	})
+	return rejected
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/ratelimit"
+)
+
+// problems collects every invalid value, so all of them are reported at once.
+type problems []error
+
+func (p *problems) add(key, format string, args ...any) {
+	*p = append(*p, fmt.Errorf("%v: %v", key, fmt.Sprintf(format, args...)))
+}
+
+func (p *problems) required(key, value string) {
+	if value == "" {
+		p.add(key, "is required")
+	}
+}
+
+func (p *problems) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		p.add(key, "%d is not a port", value)
+	}
+}
+
+// duration checks the optional duration.
+func (p *problems) duration(key, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.ParseDuration(value); err != nil {
+		p.add(key, "%q is not a duration", value)
+	}
+}
+
+// rate checks the optional rate, see ratelimit.ParseRate.
+func (p *problems) rate(key, value string) {
+	if _, err := ratelimit.ParseRate(value, 0); err != nil {
+		p.add(key, "%q is not a rate like 10/s, 600/m or 1000/h", value)
+	}
+}
+
+func (p *problems) err() error {
+	return errors.Join(*p...)
+}
+
+func (c LoggerConf) validate(p *problems) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		p.add("logger.level", "%q is not a log level", c.Level)
+	}
+	switch c.Format {
+	case "", "json", "text", "logfmt":
+	default:
+		p.add("logger.format", "%q is not one of json, text, logfmt", c.Format)
+	}
+
+	p.duration("logger.rotate-every", c.RotateEvery)
+	p.duration("logger.max-age", c.MaxAge)
+	if c.MaxSize < 0 {
+		p.add("logger.max-size", "must not be negative")
+	}
+	if c.MaxBackups < 0 {
+		p.add("logger.max-backups", "must not be negative")
+	}
+	rotated := c.MaxSize > 0 || c.RotateEvery != "" || c.MaxBackups > 0 || c.MaxAge != ""
+	if rotated && (c.Output == "" || c.Output == "stdout") {
+		p.add("logger.output", "must be a file to be rotated")
+	}
+}
+
+func (c StorageConf) validate(p *problems) {
+	switch c.Mode {
+	case StorageModeMemory:
+		p.duration("storage.snapshot-interval", c.SnapshotInterval)
+		if c.Path == "" && (c.SnapshotInterval != "" || c.WAL) {
+			p.add("storage.path", "is required to persist the memory mode")
+		}
+	case StorageModePostgres:
+		p.required("storage.host", c.Host)
+		p.port("storage.port", c.Port)
+		p.required("storage.db-name", c.DBName)
+		p.required("storage.user", c.User)
+	case StorageModeSQLite:
+		p.required("storage.path", c.Path)
+	default:
+		p.add("storage.mode", "%q is not one of memory, postgres, sqlite", c.Mode)
+	}
+}
+
+func (c QueueServerConf) validate(p *problems) {
+	p.required("queue.host", c.Host)
+	p.port("queue.port", c.Port)
+	c.TLS.validateClient(p, "queue.tls")
+}
+
+func (c TLSConf) validateServer(p *problems, key string) {
+	if !c.Enabled {
+		return
+	}
+	p.required(key+".cert-file", c.CertFile)
+	p.required(key+".key-file", c.KeyFile)
+	if c.ClientAuth {
+		p.required(key+".ca-file", c.CAFile)
+	}
+}
+
+func (c TLSConf) validateClient(p *problems, key string) {
+	if !c.Enabled {
+		return
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		p.add(key, "certFile and keyFile are set together")
+	}
+	if c.ClientAuth {
+		p.add(key+".client-auth", "is for the servers only")
+	}
+}
+
+func (c CalendarConfig) validate(p *problems) {
+	c.Logger.validate(p)
+	p.port("endpoint.http-port", c.Endpoint.HTTPPort)
+	p.port("endpoint.grpc-port", c.Endpoint.GRPCPort)
+	c.Endpoint.TLS.validateServer(p, "endpoint.tls")
+	p.required("endpoint.shutdown-timeout", c.Endpoint.ShutdownTimeout)
+	p.duration("endpoint.shutdown-timeout", c.Endpoint.ShutdownTimeout)
+	c.Storage.validate(p)
+	if c.Cache.Capacity < 0 {
+		p.add("cache.capacity", "must not be negative")
+	}
+	p.duration("cache.ttl", c.Cache.TTL)
+	if c.Cache.Capacity > 0 && c.Storage.Mode != StorageModeMemory {
+		// the scheduler and the sender write to the same database, their writes are seen once the entries expire
+		if ttl, err := time.ParseDuration(c.Cache.TTL); c.Cache.TTL == "" || (err == nil && ttl <= 0) {
+			p.add("cache.ttl", "must be positive for the database shared with the scheduler and the sender")
+		}
+	}
+	c.RateLimit.validate(p)
+}
+
+func (c RateLimitConf) validate(p *problems) {
+	p.rate("rate-limit.rate", c.Rate)
+	if c.Burst < 0 {
+		p.add("rate-limit.burst", "must not be negative")
+	}
+	if c.MaxClients < 0 {
+		p.add("rate-limit.max-clients", "must not be negative")
+	}
+	for i, m := range c.Methods {
+		key := fmt.Sprintf("rate-limit.methods[%d]", i)
+		p.required(key+".method", m.Method)
+		p.rate(key+".rate", m.Rate)
+		if m.Burst < 0 {
+			p.add(key+".burst", "must not be negative")
+		}
+	}
+}
+
+func (c SchedulerConfig) validate(p *problems) {
+	c.Logger.validate(p)
+	c.Storage.validate(p)
+	c.Queue.validate(p)
+	p.required("queue.exchange", c.Queue.Exchange)
+
+	p.required("schedule.interval", c.Schedule.Interval)
+	p.duration("schedule.interval", c.Schedule.Interval)
+	p.required("schedule.retention-period", c.Schedule.RetentionPeriod)
+	p.duration("schedule.retention-period", c.Schedule.RetentionPeriod)
+	p.duration("schedule.claim-timeout", c.Schedule.ClaimTimeout)
+	for name, job := range map[string]JobConf{"notifications": c.Schedule.Notifications, "purge": c.Schedule.Purge} {
+		p.duration("schedule."+name+".interval", job.Interval)
+		p.duration("schedule."+name+".jitter", job.Jitter)
+	}
+
+	switch c.Purge.Archive {
+	case ArchiveModeNone, ArchiveModeTable:
+	case ArchiveModeFile:
+		p.required("purge.archive-dir", c.Purge.ArchiveDir)
+	default:
+		p.add("purge.archive", "%q is not one of table, file", c.Purge.Archive)
+	}
+	if c.Purge.BatchSize < 0 {
+		p.add("purge.batch-size", "must not be negative")
+	}
+	for i, r := range c.Purge.Retention {
+		key := fmt.Sprintf("purge.retention[%d]", i)
+		if r.OwnerEmail == "" && r.CalendarID == "" {
+			p.add(key, "either ownerEmail or calendarID is required")
+		}
+		p.required(key+".period", r.Period)
+		p.duration(key+".period", r.Period)
+	}
+}
+
+func (c ClientConfig) validate(p *problems) {
+	p.required("server.address", c.Server.Address)
+	p.duration("server.timeout", c.Server.Timeout)
+	c.Server.TLS.validateClient(p, "server.tls")
+	switch c.Output {
+	case "table", "json":
+	default:
+		p.add("output", "%q is not one of table, json", c.Output)
+	}
+}
+
+func (c SenderConfig) validate(p *problems) {
+	c.Logger.validate(p)
+	c.Storage.validate(p)
+	c.Queue.validate(p)
+	p.required("queue.queue", c.Queue.Queue)
+	p.duration("notifier.webhook-timeout", c.Notifier.WebhookTimeout)
+}
@@ -1,11 +1,44 @@
 package contracts
 
+import (
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const (
+	EventFieldTitle       = "title"
+	EventFieldStartTime   = "start_time"
+	EventFieldEndTime     = "end_time"
+	EventFieldTimeZone    = "time_zone"
+	EventFieldDescription = "description"
+	EventFieldOwnerEmail  = "owner_email"
+	EventFieldNotify      = "notify"
+	EventFieldCalendarID  = "calendar_id"
+	EventFieldReminders   = "reminders"
+)
+
 type Event struct {
 	ID           string
 	Title        string
-	StartTime    int64
-	EndTime      int64
+	StartTime    time.Time
+	EndTime      time.Time
+	TimeZone     string
 	Description  string
 	NotifyBefore string
 	OwnerEmail   string
+	CalendarID   string
+	Reminders    []Reminder
+}
+
+type Reminder struct {
+	Before  string
+	Channel model.Channel
+	Target  string
+}
+
+type EventResult struct {
+	ID    string
+	Event model.Event
+	Err   error
 }
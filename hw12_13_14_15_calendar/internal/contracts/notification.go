@@ -2,6 +2,9 @@ package contracts
 
 type Notification struct {
 	ID         string
+	ReminderID string
+	Channel    string
+	Target     string
 	Title      string
 	Time       int64
 	OwnerEmail string
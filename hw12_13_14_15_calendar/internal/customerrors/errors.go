@@ -16,6 +16,18 @@ type (
 	NotFound struct {
 		Message string
 	}
+
+	AlreadyExists struct {
+		Message string
+	}
+
+	OutOfRange struct {
+		Message string
+	}
+
+	PermissionDenied struct {
+		Message string
+	}
 )
 
 func (v ParamError) Error() string {
@@ -29,3 +41,15 @@ func (v ValidationError) Error() string {
 func (e NotFound) Error() string {
 	return e.Message
 }
+
+func (e AlreadyExists) Error() string {
+	return e.Message
+}
+
+func (e OutOfRange) Error() string {
+	return e.Message
+}
+
+func (e PermissionDenied) Error() string {
+	return e.Message
+}
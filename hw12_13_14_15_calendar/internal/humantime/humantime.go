@@ -0,0 +1,168 @@
+// Package humantime parses the times typed by a person, like "tomorrow 10:00" or "+2h".
+package humantime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var dateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var clockLayouts = []string{"15:04", "15:04:05", "3pm", "3:04pm"}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// Parse reads the time relative to now and in its location. It accepts:
+//   - RFC 3339 and the dates like 2024-11-25, 2024-11-25 10:00;
+//   - now, today, tomorrow, yesterday and the weekdays, optionally followed by the time of the day:
+//     "tomorrow 10:00", "friday 3pm", "next monday 9:30"; a day alone means its midnight;
+//   - the time of the day alone, which is today: "10:00";
+//   - the offsets from now: "+2h", "-30m", "in 3d", where d and w are the days and the weeks.
+func Parse(s string, now time.Time) (time.Time, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	s = strings.ToLower(s)
+	switch {
+	case s == "":
+		return time.Time{}, errors.New("empty time")
+	case s == "now":
+		return now, nil
+	case strings.HasPrefix(s, "+"), strings.HasPrefix(s, "-"), strings.HasPrefix(s, "in "):
+		return parseOffset(s, now)
+	}
+
+	fields := strings.Fields(s)
+	day, rest, ok := parseDay(fields, now)
+	if !ok {
+		day, rest = midnight(now), fields
+	}
+	if len(rest) == 0 {
+		return day, nil
+	}
+	clock, ok := parseClock(rest)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%q is not a time like tomorrow 10:00 or 2024-11-25 10:00", s)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0,
+		now.Location()), nil
+}
+
+// ParseDuration extends time.ParseDuration with the days and the weeks: "1d", "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := parseDays(s); ok {
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration like 30m, 1h30m or 2d", s)
+	}
+	return d, nil
+}
+
+// parseOffset keeps the wall clock across the daylight saving changes for the days and the weeks.
+func parseOffset(s string, now time.Time) (time.Time, error) {
+	sign := 1
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	default:
+		s = strings.TrimPrefix(s, "in ")
+	}
+	s = strings.ReplaceAll(s, " ", "")
+
+	if days, ok := parseDays(s); ok {
+		return now.AddDate(0, 0, sign*days), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an offset like +2h, -30m or in 3d", s)
+	}
+	return now.Add(time.Duration(sign) * d), nil
+}
+
+// parseDays reads the whole days and weeks, like 3d or 2w.
+func parseDays(s string) (int, bool) {
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if count, err := strconv.Atoi(n); err == nil {
+				return count * days, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseDay reads the day at the start of fields, it returns its midnight and the fields left.
+func parseDay(fields []string, now time.Time) (time.Time, []string, bool) {
+	today := midnight(now)
+	switch fields[0] {
+	case "today":
+		return today, fields[1:], true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), fields[1:], true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), fields[1:], true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", fields[0], now.Location()); err == nil {
+		return t, fields[1:], true
+	}
+
+	// a weekday is today or the next one, "next" skips today
+	next := fields[0] == "next" && len(fields) > 1
+	if next {
+		fields = fields[1:]
+	}
+	weekday, ok := weekdays[fields[0]]
+	if !ok {
+		return time.Time{}, nil, false
+	}
+	days := (int(weekday) - int(now.Weekday()) + 7) % 7
+	if next && days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, days), fields[1:], true
+}
+
+func parseClock(fields []string) (time.Time, bool) {
+	if len(fields) != 1 {
+		return time.Time{}, false
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, fields[0]); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
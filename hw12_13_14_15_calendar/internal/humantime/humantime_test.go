@@ -0,0 +1,76 @@
+package humantime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	// Wednesday
+	now := time.Date(2024, 11, 27, 14, 30, 15, 0, moscow)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: "2024-11-25T10:00:00Z", expected: time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC)},
+		{input: "2024-11-25", expected: time.Date(2024, 11, 25, 0, 0, 0, 0, moscow)},
+		{input: "2024-11-25 10:00", expected: time.Date(2024, 11, 25, 10, 0, 0, 0, moscow)},
+		{input: "2024-11-25 3pm", expected: time.Date(2024, 11, 25, 15, 0, 0, 0, moscow)},
+		{input: "now", expected: now},
+		{input: "today", expected: time.Date(2024, 11, 27, 0, 0, 0, 0, moscow)},
+		{input: "Tomorrow 10:00", expected: time.Date(2024, 11, 28, 10, 0, 0, 0, moscow)},
+		{input: " tomorrow   9:30pm ", expected: time.Date(2024, 11, 28, 21, 30, 0, 0, moscow)},
+		{input: "yesterday 23:59:59", expected: time.Date(2024, 11, 26, 23, 59, 59, 0, moscow)},
+		{input: "10:00", expected: time.Date(2024, 11, 27, 10, 0, 0, 0, moscow)},
+		{input: "friday 3pm", expected: time.Date(2024, 11, 29, 15, 0, 0, 0, moscow)},
+		{input: "wed 9:00", expected: time.Date(2024, 11, 27, 9, 0, 0, 0, moscow)},
+		{input: "next wednesday", expected: time.Date(2024, 12, 4, 0, 0, 0, 0, moscow)},
+		{input: "monday", expected: time.Date(2024, 12, 2, 0, 0, 0, 0, moscow)},
+		{input: "+2h", expected: now.Add(2 * time.Hour)},
+		{input: "-30m", expected: now.Add(-30 * time.Minute)},
+		{input: "in 3d", expected: time.Date(2024, 11, 30, 14, 30, 15, 0, moscow)},
+		{input: "+1w", expected: time.Date(2024, 12, 4, 14, 30, 15, 0, moscow)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := Parse(tc.input, now)
+			require.NoError(t, err)
+			require.True(t, tc.expected.Equal(actual), "expected %v, got %v", tc.expected, actual)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Date(2024, 11, 27, 14, 30, 0, 0, time.UTC)
+	for _, input := range []string{"", "soon", "tomorrow noon", "tomorrow 10:00 sharp", "25:00", "+2 fortnights", "next"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input, now)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{input: "30m", expected: 30 * time.Minute},
+		{input: "1h30m", expected: 90 * time.Minute},
+		{input: "2d", expected: 48 * time.Hour},
+		{input: "1w", expected: 7 * 24 * time.Hour},
+	}
+	for _, tc := range tests {
+		actual, err := ParseDuration(tc.input)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, actual)
+	}
+
+	_, err := ParseDuration("a while")
+	require.ErrorContains(t, err, "is not a duration")
+}
@@ -0,0 +1,336 @@
+// Package ical reads and writes the events in the iCalendar format of RFC 5545.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	utcLayout      = "20060102T150405Z"
+	localLayout    = "20060102T150405"
+	dateLayout     = "20060102"
+	maxLineOctets  = 75
+	productID      = "-//otus_hw//calendar//EN"
+	organizerEmail = "mailto:"
+)
+
+// Event carries the properties of VEVENT the calendar knows, the others are skipped on decoding.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Organizer   string // email
+	Start       time.Time
+	End         time.Time
+	// Alarms are the times before the start the reminders go off at
+	Alarms []time.Duration
+}
+
+// Encode writes the events as VCALENDAR, the times in UTC. stamp is the time the calendar is created at.
+func Encode(w io.Writer, events []Event, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", productID)
+	line("CALSCALE", "GREGORIAN")
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp.UTC().Format(utcLayout))
+		line("DTSTART", e.Start.UTC().Format(utcLayout))
+		line("DTEND", e.End.UTC().Format(utcLayout))
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Organizer != "" {
+			line("ORGANIZER", organizerEmail+e.Organizer)
+		}
+		for _, before := range e.Alarms {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", escape(e.Summary))
+			line("TRIGGER", formatDuration(-before))
+			line("END", "VALARM")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded splits the content line over 75 octets, the continuation lines start with a space.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space counts
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads the VEVENT components of the calendars in r. The floating times, which have neither
+// UTC nor TZID, and the all-day dates are taken in loc.
+func Decode(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var event *Event
+	var start, end, duration *property
+	inAlarm := false
+	for i, l := range lines {
+		p, err := parseLine(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			event, start, end, duration = &Event{}, nil, nil, nil
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VALARM"):
+			inAlarm = true
+		case p.name == "END" && strings.EqualFold(p.value, "VALARM"):
+			inAlarm = false
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && event != nil:
+			if err := setTimes(event, start, end, duration, loc); err != nil {
+				return nil, fmt.Errorf("event %d: %w", len(events)+1, err)
+			}
+			events = append(events, *event)
+			event = nil
+		case event == nil:
+			// the properties of the calendar and of the other components are skipped
+		case inAlarm:
+			if p.name == "TRIGGER" && p.params["RELATED"] != "END" && p.params["VALUE"] != "DATE-TIME" {
+				if trigger, err := parseDuration(p.value); err == nil && trigger <= 0 {
+					event.Alarms = append(event.Alarms, -trigger)
+				}
+			}
+		default:
+			switch p.name {
+			case "UID":
+				event.UID = p.value
+			case "SUMMARY":
+				event.Summary = unescape(p.value)
+			case "DESCRIPTION":
+				event.Description = unescape(p.value)
+			case "ORGANIZER":
+				event.Organizer = parseOrganizer(p.value)
+			case "DTSTART":
+				start = &p
+			case "DTEND":
+				end = &p
+			case "DURATION":
+				duration = &p
+			}
+		}
+	}
+	return events, nil
+}
+
+// unfold joins the continuation lines, the ones starting with a space or a tab, to the previous line.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case l == "":
+			// the blank lines are not allowed, some exporters write them anyway
+		case (l[0] == ' ' || l[0] == '\t') && len(lines) > 0:
+			lines[len(lines)-1] += l[1:]
+		default:
+			lines = append(lines, l)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits NAME;PARAM=VALUE;...:VALUE, the colons of the quoted parameter values do not end the name.
+func parseLine(l string) (property, error) {
+	quoted := false
+	colon := -1
+	for i, c := range l {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("%q is not a content line", l)
+	}
+
+	parts := strings.Split(l[:colon], ";")
+	p := property{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: l[colon+1:]}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return p, nil
+}
+
+// parseOrganizer returns the email of the mailto: address.
+func parseOrganizer(value string) string {
+	if len(value) < len(organizerEmail) || !strings.EqualFold(value[:len(organizerEmail)], organizerEmail) {
+		return ""
+	}
+	return value[len(organizerEmail):]
+}
+
+func setTimes(e *Event, start, end, duration *property, loc *time.Location) error {
+	if start == nil {
+		return errors.New("DTSTART is missing")
+	}
+	var err error
+	if e.Start, err = parseTime(*start, loc); err != nil {
+		return fmt.Errorf("DTSTART: %w", err)
+	}
+
+	switch {
+	case end != nil:
+		if e.End, err = parseTime(*end, loc); err != nil {
+			return fmt.Errorf("DTEND: %w", err)
+		}
+	case duration != nil:
+		d, err := parseDuration(duration.value)
+		if err != nil {
+			return fmt.Errorf("DURATION: %w", err)
+		}
+		e.End = e.Start.Add(d)
+	case start.params["VALUE"] == "DATE":
+		// the all-day event lasts the day
+		e.End = e.Start.AddDate(0, 0, 1)
+	default:
+		e.End = e.Start
+	}
+	return nil
+}
+
+func parseTime(p property, loc *time.Location) (time.Time, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(dateLayout) {
+		return time.ParseInLocation(dateLayout, p.value, loc)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse(utcLayout, p.value)
+	}
+	if tzid := p.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	return time.ParseInLocation(localLayout, p.value, loc)
+}
+
+// parseDuration reads the durations like -PT15M, P1D or P1W.
+func parseDuration(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("%q is not a duration", s)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var d time.Duration
+	number := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(number)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("%q is not a duration", s)
+			}
+			d += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("%q is not a duration", s)
+	}
+	return sign * d, nil
+}
+
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		if d == 0 {
+			return b.String()
+		}
+	}
+
+	b.WriteByte('T')
+	h, m, s := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+	if h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	if s > 0 || (h == 0 && m == 0) {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
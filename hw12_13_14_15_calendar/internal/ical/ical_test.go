@@ -0,0 +1,133 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	events := []Event{
+		{
+			UID:         "9b2f6c1e-1",
+			Summary:     "Планёрка; weekly, team",
+			Description: "Agenda:\n1. Status\n2. " + strings.Repeat("Очень длинное описание ", 10),
+			Organizer:   "lead@example.com",
+			Start:       time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC),
+			End:         time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC),
+			Alarms:      []time.Duration{15 * time.Minute, 24 * time.Hour},
+		},
+		{
+			UID:     "9b2f6c1e-2",
+			Summary: "Lunch",
+			Start:   time.Date(2024, 11, 26, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+			End:     time.Date(2024, 11, 26, 13, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, events, time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)))
+	for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(l), maxLineOctets, "the lines are folded")
+	}
+	require.Contains(t, buf.String(), "TRIGGER:-PT15M\r\n")
+	require.Contains(t, buf.String(), "TRIGGER:-P1D\r\n")
+	require.Contains(t, buf.String(), "DTSTART:20241126T090000Z\r\n")
+
+	decoded, err := Decode(&buf, time.UTC)
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	for i := range events {
+		require.Equal(t, events[i].UID, decoded[i].UID)
+		require.Equal(t, events[i].Summary, decoded[i].Summary)
+		require.Equal(t, events[i].Description, decoded[i].Description)
+		require.Equal(t, events[i].Organizer, decoded[i].Organizer)
+		require.True(t, events[i].Start.Equal(decoded[i].Start))
+		require.True(t, events[i].End.Equal(decoded[i].End))
+		require.Equal(t, events[i].Alarms, decoded[i].Alarms)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Google Inc//Google Calendar 70.9054//EN",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Europe/Berlin:20241125T100000",
+		"DURATION:PT1H30M",
+		"UID:zoned@google.com",
+		"ORGANIZER;CN=\"Lead: Team\":MAILTO:lead@example.com",
+		"SUMMARY:Retro",
+		"DESCRIPTION:first line\\nsecond line\\, continued that is folded a",
+		" cross the lines",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:This is an event reminder",
+		"TRIGGER:-P0DT0H10M0S",
+		"END:VALARM",
+		"BEGIN:VALARM",
+		"TRIGGER;VALUE=DATE-TIME:20241125T080000Z",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20241126",
+		"UID:allday",
+		"SUMMARY:Holiday",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20241127T090000",
+		"DTEND:20241127T100000",
+		"UID:floating",
+		"SUMMARY:Floating",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Decode(strings.NewReader(input), moscow)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	require.Equal(t, "zoned@google.com", events[0].UID)
+	require.Equal(t, "lead@example.com", events[0].Organizer)
+	require.Equal(t, "first line\nsecond line, continued that is folded across the lines", events[0].Description)
+	require.True(t, time.Date(2024, 11, 25, 10, 0, 0, 0, berlin).Equal(events[0].Start))
+	require.True(t, time.Date(2024, 11, 25, 11, 30, 0, 0, berlin).Equal(events[0].End))
+	require.Equal(t, []time.Duration{10 * time.Minute}, events[0].Alarms)
+
+	require.True(t, time.Date(2024, 11, 26, 0, 0, 0, 0, moscow).Equal(events[1].Start))
+	require.True(t, time.Date(2024, 11, 27, 0, 0, 0, 0, moscow).Equal(events[1].End))
+
+	require.True(t, time.Date(2024, 11, 27, 9, 0, 0, 0, moscow).Equal(events[2].Start))
+	require.True(t, time.Date(2024, 11, 27, 10, 0, 0, 0, moscow).Equal(events[2].End))
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "no colon", input: "BEGIN:VEVENT\nSUMMARY\nEND:VEVENT", err: "line 2"},
+		{name: "no start", input: "BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT", err: "DTSTART is missing"},
+		{name: "bad start", input: "BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT", err: "DTSTART"},
+		{name: "bad duration", input: "BEGIN:VEVENT\nDTSTART:20241127T090000Z\nDURATION:1h\nEND:VEVENT", err: "DURATION"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tc.input), time.UTC)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
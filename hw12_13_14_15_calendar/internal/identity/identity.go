@@ -0,0 +1,33 @@
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+// MetadataKey carries the email of the caller. It is trusted only from the gateway of the same process
+// and, if configured so, from the proxy authenticating the callers.
+const MetadataKey = "x-user-email"
+
+type userKey struct{}
+
+func WithUser(ctx context.Context, email string) context.Context {
+	return context.WithValue(ctx, userKey{}, email)
+}
+
+func UserFromContext(ctx context.Context) (string, bool) {
+	email, ok := ctx.Value(userKey{}).(string)
+	return email, ok && email != ""
+}
+
+// FromTLS identifies the caller by the verified client certificate, by its first email address or its common name.
+func FromTLS(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	leaf := state.VerifiedChains[0][0]
+	if len(leaf.EmailAddresses) > 0 {
+		return leaf.EmailAddresses[0], true
+	}
+	return leaf.Subject.CommonName, leaf.Subject.CommonName != ""
+}
@@ -0,0 +1,219 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/common"
+)
+
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays every run by a random duration up to its value.
+	Jitter time.Duration
+	Run    func(ctx context.Context) error
+}
+
+type Status struct {
+	Name         string    `json:"name"`
+	Running      bool      `json:"running"`
+	Runs         int       `json:"runs"`
+	Failures     int       `json:"failures"`
+	Skipped      int       `json:"skipped"`
+	LastStart    time.Time `json:"lastStart"`
+	LastDuration string    `json:"lastDuration,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+	NextRun      time.Time `json:"nextRun"`
+}
+
+// Runner runs every job on its own schedule, a run is skipped while the previous one is still in progress.
+type Runner struct {
+	logger   common.Logger
+	jobs     []Job
+	mu       sync.Mutex
+	statuses map[string]*Status
+	// rescheduled wakes the job waiting for its next run
+	rescheduled map[string]chan struct{}
+}
+
+func NewRunner(logger common.Logger) *Runner {
+	return &Runner{
+		logger:      logger,
+		statuses:    make(map[string]*Status),
+		rescheduled: make(map[string]chan struct{}),
+	}
+}
+
+func (r *Runner) Add(job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs = append(r.jobs, job)
+	r.statuses[job.Name] = &Status{Name: job.Name}
+	r.rescheduled[job.Name] = make(chan struct{}, 1)
+}
+
+// Reschedule replaces the schedule of the job, the next run is planned again by the new schedule.
+// The run in progress is not affected.
+func (r *Runner) Reschedule(name string, schedule Schedule, jitter time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.jobs {
+		if r.jobs[i].Name != name {
+			continue
+		}
+		r.jobs[i].Schedule = schedule
+		r.jobs[i].Jitter = jitter
+		select {
+		case r.rescheduled[name] <- struct{}{}:
+		default:
+		}
+		return true
+	}
+	return false
+}
+
+func (r *Runner) job(name string) Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, job := range r.jobs {
+		if job.Name == name {
+			return job
+		}
+	}
+	return Job{}
+}
+
+// Run blocks until the context is done and the started runs are finished.
+func (r *Runner) Run(ctx context.Context) {
+	r.mu.Lock()
+	jobs := slices.Clone(r.jobs)
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.schedule(ctx, job.Name)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) schedule(ctx context.Context, name string) {
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	r.mu.Lock()
+	rescheduled := r.rescheduled[name]
+	r.mu.Unlock()
+
+	for {
+		job := r.job(name)
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			r.logger.Warn("Job has no more runs scheduled", "Job", job.Name)
+			return
+		}
+		if job.Jitter > 0 {
+			next = next.Add(rand.N(job.Jitter)) //nolint:gosec
+		}
+		r.update(job.Name, func(s *Status) { s.NextRun = next })
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-rescheduled:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		if !r.start(job.Name) {
+			r.logger.Warn("Job run is skipped, the previous run is still in progress", "Job", job.Name)
+			continue
+		}
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			r.run(ctx, job)
+		}()
+	}
+}
+
+func (r *Runner) start(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.statuses[name]
+	if status.Running {
+		status.Skipped++
+		return false
+	}
+	status.Running = true
+	status.LastStart = time.Now()
+	return true
+}
+
+func (r *Runner) run(ctx context.Context, job Job) {
+	started := time.Now()
+	err := job.Run(ctx)
+	duration := time.Since(started)
+
+	r.update(job.Name, func(s *Status) {
+		s.Running = false
+		s.Runs++
+		s.LastDuration = duration.String()
+		s.LastError = ""
+		if err != nil {
+			s.Failures++
+			s.LastError = err.Error()
+		}
+	})
+
+	if err != nil {
+		r.logger.Error("Job run failed", "Job", job.Name, "Duration", duration.String(), "Error", err.Error())
+		return
+	}
+	r.logger.Info("Job run succeeded", "Job", job.Name, "Duration", duration.String())
+}
+
+func (r *Runner) update(name string, fn func(s *Status)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(r.statuses[name])
+}
+
+func (r *Runner) Statuses() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]Status, 0, len(r.statuses))
+	for _, s := range r.statuses {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Handler exposes the job statuses as JSON.
+func (r *Runner) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(r.Statuses()); err != nil {
+			r.logger.Error("failed to encode job statuses: " + err.Error())
+		}
+	})
+}
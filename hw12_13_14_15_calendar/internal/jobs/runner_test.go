@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerSkipsOverlappingRuns(t *testing.T) {
+	var active, maxActive atomic.Int32
+	release := make(chan struct{})
+
+	runner := NewRunner(logger.New("INFO", "stdout"))
+	runner.Add(Job{
+		Name:     "slow",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			n := active.Add(1)
+			defer active.Add(-1)
+			if n > maxActive.Load() {
+				maxActive.Store(n)
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	})
+	runner.Add(Job{
+		Name:     "failing",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(_ context.Context) error {
+			return errors.New("failed")
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		statuses := runner.Statuses()
+		return statuses[0].Failures >= 2 && statuses[1].Skipped >= 2
+	}, time.Second, time.Millisecond)
+	close(release)
+	cancel()
+	<-done
+
+	require.Equal(t, int32(1), maxActive.Load())
+
+	statuses := runner.Statuses()
+	require.Equal(t, "failing", statuses[0].Name)
+	require.Equal(t, "failed", statuses[0].LastError)
+	require.Equal(t, statuses[0].Runs, statuses[0].Failures)
+	require.Equal(t, "slow", statuses[1].Name)
+	require.False(t, statuses[1].Running, "runs are finished when Run returns")
+	require.GreaterOrEqual(t, statuses[1].Runs, 1)
+
+	recorder := httptest.NewRecorder()
+	runner.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	var exposed []Status
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &exposed))
+	require.Len(t, exposed, 2)
+	require.Equal(t, statuses[1].Skipped, exposed[1].Skipped)
+}
+
+func TestRunnerJitter(t *testing.T) {
+	runner := NewRunner(logger.New("INFO", "stdout"))
+	runner.Add(Job{
+		Name:     "jittered",
+		Schedule: Every(time.Hour),
+		Jitter:   time.Minute,
+		Run:      func(_ context.Context) error { return nil },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return !runner.Statuses()[0].NextRun.IsZero() }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	next := time.Until(runner.Statuses()[0].NextRun)
+	require.Greater(t, next, 59*time.Minute)
+	require.LessOrEqual(t, next, 61*time.Minute)
+	require.Zero(t, runner.Statuses()[0].Runs)
+}
+
+func TestRunnerReschedule(t *testing.T) {
+	var runs atomic.Int32
+	runner := NewRunner(logger.New("INFO", "stdout"))
+	runner.Add(Job{
+		Name:     "rescheduled",
+		Schedule: Every(time.Hour),
+		Run: func(_ context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	})
+	require.False(t, runner.Reschedule("unknown", Every(time.Millisecond), 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return !runner.Statuses()[0].NextRun.IsZero() }, time.Second, time.Millisecond)
+	require.True(t, runner.Reschedule("rescheduled", Every(5*time.Millisecond), 0))
+	require.Eventually(t, func() bool { return runs.Load() >= 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	require.Less(t, time.Until(runner.Statuses()[0].NextRun), time.Minute)
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	after := time.Date(2024, 11, 25, 10, 17, 30, 0, time.UTC) // Monday
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{expr: "* * * * *", expected: time.Date(2024, 11, 25, 10, 18, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", expected: time.Date(2024, 11, 25, 10, 30, 0, 0, time.UTC)},
+		{expr: "0 * * * *", expected: time.Date(2024, 11, 25, 11, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", expected: time.Date(2024, 11, 25, 11, 0, 0, 0, time.UTC)},
+		{expr: "5,10 9-11 * * *", expected: time.Date(2024, 11, 25, 11, 5, 0, 0, time.UTC)},
+		{expr: "30 2 * * *", expected: time.Date(2024, 11, 26, 2, 30, 0, 0, time.UTC)},
+		{expr: "0 0 * * 0", expected: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", expected: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 1 *", expected: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 12 1 * 3", expected: time.Date(2024, 11, 27, 12, 0, 0, 0, time.UTC)},
+		{expr: "@every 90s", expected: after.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			schedule, err := Parse(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, schedule.Next(after))
+		})
+	}
+}
+
+func TestCronParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"@every -1s",
+		"@every soon",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			expr := expr
+			t.Parallel()
+
+			_, err := Parse(expr)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+const (
+	FieldRequestID = "Request Id"
+	FieldTraceID   = "Trace Id"
+	FieldOwner     = "Owner"
+)
+
+type fieldsKey struct{}
+
+// WithRequestID attaches the request id to the lines logged with the returned context.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithFields(ctx, FieldRequestID, requestID)
+}
+
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return WithFields(ctx, FieldTraceID, traceID)
+}
+
+func WithOwner(ctx context.Context, email string) context.Context {
+	return WithFields(ctx, FieldOwner, email)
+}
+
+// WithFields attaches the key-value pairs to the lines logged with the returned context,
+// a key set again replaces the previous value.
+func WithFields(ctx context.Context, args ...any) context.Context {
+	fields := slices.Clone(fieldsFrom(ctx))
+	for _, attr := range argsToAttrs(args) {
+		if attr.Value.Kind() == slog.KindString && attr.Value.String() == "" {
+			continue
+		}
+		fields = slices.DeleteFunc(fields, func(a slog.Attr) bool { return a.Key == attr.Key })
+		fields = append(fields, attr)
+	}
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+func fieldsFrom(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return fields
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// contextHandler adds the fields attached to the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if fields := fieldsFrom(ctx); len(fields) > 0 {
+		r = r.Clone()
+		r.AddAttrs(fields...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// TraceIDFromTraceparent extracts the trace id from the W3C traceparent header, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01. It returns "" for a malformed header.
+func TraceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0123456789abcdef") != "" {
+		return ""
+	}
+	if strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
@@ -1,49 +1,122 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
 )
 
 const (
 	OutputStdout = "stdout"
 )
 
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+)
+
+const megabyte = 1 << 20
+
 type Logger struct {
 	logger *slog.Logger
-	file   *os.File
+	level  *slog.LevelVar
+	file   io.Closer
 }
 
+// New creates the JSON logger, the program exits if the level or the output are wrong.
 func New(level string, output string) *Logger {
-	var logger *slog.Logger
-	var l slog.Level
-
-	err := l.UnmarshalText([]byte(level))
+	l, err := NewFromConfig(config.LoggerConf{Level: level, Output: output})
 	if err != nil {
-		log.Fatalf("Failed to parse log level: %v", err)
+		log.Fatal(err)
 	}
+	return l
+}
 
-	options := &slog.HandlerOptions{
-		Level: l,
+// NewFromConfig creates the logger writing in the configured format, the output file is rotated if configured.
+func NewFromConfig(c config.LoggerConf) (*Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(c.Level)); err != nil {
+		return nil, fmt.Errorf("failed to parse log level: %w", err)
 	}
-	var file *os.File
-	switch output {
-	case OutputStdout, "":
-		logger = slog.New(slog.NewJSONHandler(os.Stdout, options))
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(l)
 
+	var w io.Writer
+	var file io.Closer
+	switch c.Output {
+	case OutputStdout, "":
+		w = os.Stdout
 	default:
-		file, err = os.OpenFile(output, os.O_WRONLY|os.O_CREATE, 0o666)
+		rotation, err := rotationFromConfig(c)
+		if err != nil {
+			return nil, err
+		}
+		f, err := openRotatingFile(c.Output, rotation)
 		if err != nil {
-			log.Fatalf("Failed to create/open a log file: %v", err)
+			return nil, fmt.Errorf("failed to create/open a log file: %w", err)
+		}
+		w, file = f, f
+	}
+
+	options := &slog.HandlerOptions{Level: levelVar}
+	var handler slog.Handler
+	switch c.Format {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(w, options)
+	case FormatLogfmt:
+		handler = slog.NewTextHandler(w, options)
+	case FormatText:
+		handler = newTextHandler(w, options)
+	default:
+		if file != nil {
+			file.Close()
 		}
-		logger = slog.New(slog.NewJSONHandler(file, options))
+		return nil, fmt.Errorf("unknown log format %q", c.Format)
 	}
 
 	return &Logger{
-		logger: logger,
+		logger: slog.New(contextHandler{handler}),
+		level:  levelVar,
 		file:   file,
+	}, nil
+}
+
+func rotationFromConfig(c config.LoggerConf) (rotation, error) {
+	r := rotation{maxSize: int64(c.MaxSize) * megabyte, maxBackups: c.MaxBackups}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"rotateEvery", c.RotateEvery, &r.every},
+		{"maxAge", c.MaxAge, &r.maxAge},
+	} {
+		if d.value == "" {
+			continue
+		}
+		var err error
+		if *d.dest, err = time.ParseDuration(d.value); err != nil {
+			return rotation{}, fmt.Errorf("failed to parse %v: %w", d.name, err)
+		}
+	}
+	return r, nil
+}
+
+// SetLevel changes the level of the messages written from now on.
+func (l *Logger) SetLevel(level string) error {
+	var value slog.Level
+	if err := value.UnmarshalText([]byte(level)); err != nil {
+		return err
 	}
+	l.level.Set(value)
+	return nil
 }
 
 func (l *Logger) Info(msg string, args ...any) {
@@ -62,6 +135,23 @@ func (l *Logger) Debug(msg string, args ...any) {
 	l.logger.Debug(msg, args...)
 }
 
+// InfoContext logs the message with the fields attached to ctx, see WithRequestID, WithTraceID and WithOwner.
+func (l *Logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.logger.InfoContext(ctx, msg, args...)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.logger.WarnContext(ctx, msg, args...)
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.logger.ErrorContext(ctx, msg, args...)
+}
+
+func (l *Logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.logger.DebugContext(ctx, msg, args...)
+}
+
 func (l *Logger) Fatal(msg string, args ...any) {
 	l.logger.Error(msg, args...)
 	os.Exit(1)
@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
 	"github.com/stretchr/testify/require"
 )
 
@@ -63,3 +67,103 @@ func TestLoggingCases(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggerSetLevel(t *testing.T) {
+	fOut, err := os.CreateTemp("", "logger*.txt")
+	require.NoError(t, err)
+	defer os.Remove(fOut.Name())
+
+	l := New("info", fOut.Name())
+	defer l.Close()
+
+	l.Debug("hidden debug msg")
+	require.NoError(t, l.SetLevel("debug"))
+	l.Debug("shown debug msg")
+	require.Error(t, l.SetLevel("loud"))
+
+	output, err := os.ReadFile(fOut.Name())
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "hidden debug msg")
+	require.Contains(t, string(output), "shown debug msg")
+}
+
+func TestLoggerFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{
+			format:   FormatJSON,
+			expected: []string{`"level":"INFO"`, `"msg":"Event created"`, `"Event Id":"42"`, `"Owner":"user@example.com"`},
+		},
+		{
+			format:   FormatLogfmt,
+			expected: []string{"level=INFO", `msg="Event created"`, `"Event Id"=42`, "Owner=user@example.com"},
+		},
+		{
+			format:   FormatText,
+			expected: []string{"INFO  Event created", `"Event Id"=42`, "Owner=user@example.com"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "calendar.log")
+			l, err := NewFromConfig(config.LoggerConf{Level: "INFO", Output: output, Format: tc.format})
+			require.NoError(t, err)
+
+			l.InfoContext(WithOwner(context.Background(), "user@example.com"), "Event created", "Event Id", "42")
+			l.Close()
+
+			data, err := os.ReadFile(output)
+			require.NoError(t, err)
+			for _, expected := range tc.expected {
+				require.Contains(t, string(data), expected)
+			}
+		})
+	}
+
+	_, err := NewFromConfig(config.LoggerConf{Level: "INFO", Format: "xml"})
+	require.Error(t, err)
+}
+
+func TestLoggerContextFields(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "calendar.log")
+	l := New("DEBUG", output)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithTraceID(ctx, TraceIDFromTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	ctx = WithOwner(ctx, "")
+	l.DebugContext(WithRequestID(ctx, "req-2"), "with fields")
+	l.Debug("without fields")
+	l.Close()
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.Equal(t, "req-2", first[FieldRequestID], "the request id is replaced")
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", first[FieldTraceID])
+	require.NotContains(t, first, FieldOwner, "empty fields are skipped")
+	require.NotContains(t, lines[1], FieldRequestID)
+
+	require.Empty(t, TraceIDFromTraceparent("00-00000000000000000000000000000000-00f067aa0ba902b7-01"))
+	require.Empty(t, TraceIDFromTraceparent("garbage"))
+}
+
+func TestLoggerAppends(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "calendar.log")
+	for _, msg := range []string{"first run", "second run"} {
+		l := New("INFO", output)
+		l.Info(msg)
+		l.Close()
+	}
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "first run")
+	require.Contains(t, string(data), "second run")
+}
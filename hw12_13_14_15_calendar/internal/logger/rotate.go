@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupLayout suffixes the rotated files, the names sort by the rotation time.
+const backupLayout = "20060102T150405.000000000"
+
+// rotation limits the log file, the zero values disable the limits.
+type rotation struct {
+	// maxSize is the size in bytes the file is rotated at
+	maxSize int64
+	// every rotates the file at the multiples of the duration, e.g. at midnight UTC for 24h
+	every      time.Duration
+	maxBackups int
+	maxAge     time.Duration
+}
+
+// rotatingFile appends to the log file, the rotated files are renamed to path.<time> next to it.
+type rotatingFile struct {
+	rotation
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func openRotatingFile(path string, r rotation) (*rotatingFile, error) {
+	f := &rotatingFile{rotation: r, path: path, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	// the file left by the previous run is rotated by the time it was started
+	f.openedAt = f.now()
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, fs.ErrClosed
+	}
+	if f.due(len(p)) {
+		// the file reopened after a failed rotation is written on, the rotation is retried on the next write
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, fmt.Errorf("failed to rotate log file: %w", err)
+		}
+		// the backups failed to be removed are removed on the next rotation
+		_ = f.prune()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) due(size int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && f.size+int64(size) > f.maxSize {
+		return true
+	}
+	return f.every > 0 && !f.now().Truncate(f.every).Equal(f.openedAt.Truncate(f.every))
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	backup := f.path + "." + f.now().UTC().Format(backupLayout)
+	if err := os.Rename(f.path, backup); err != nil {
+		return errors.Join(err, f.open())
+	}
+	return f.open()
+}
+
+// prune removes the rotated files over maxBackups and older than maxAge.
+func (f *rotatingFile) prune() error {
+	if f.maxBackups <= 0 && f.maxAge <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+	type backup struct {
+		path      string
+		rotatedAt time.Time
+	}
+	backups := make([]backup, 0, len(matches))
+	for _, match := range matches {
+		rotatedAt, err := time.Parse(backupLayout, strings.TrimPrefix(match, f.path+"."))
+		if err == nil {
+			backups = append(backups, backup{path: match, rotatedAt: rotatedAt})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotatedAt.After(backups[j].rotatedAt) })
+
+	var errs []error
+	for i, b := range backups {
+		if (f.maxBackups > 0 && i >= f.maxBackups) || (f.maxAge > 0 && f.now().Sub(b.rotatedAt) > f.maxAge) {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	sort.Strings(matches)
+	return matches
+}
+
+func TestRotatingFileBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	f, err := openRotatingFile(path, rotation{maxSize: 10, maxBackups: 2})
+	require.NoError(t, err)
+	f.now = func() time.Time { return now }
+	defer f.Close()
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		now = now.Add(time.Second)
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line-4\n", string(data))
+
+	rotated := backups(t, path)
+	require.Len(t, rotated, 2, "the oldest backup is removed")
+	data, err = os.ReadFile(rotated[0])
+	require.NoError(t, err)
+	require.Equal(t, "line-2\n", string(data))
+}
+
+func TestRotatingFileByTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	now := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+
+	f, err := openRotatingFile(path, rotation{every: 24 * time.Hour, maxAge: 36 * time.Hour})
+	require.NoError(t, err)
+	f.now = func() time.Time { return now }
+	f.openedAt = now
+	defer f.Close()
+
+	write := func(line string) {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	write("day-1\n")
+	now = now.Add(30 * time.Minute)
+	write("day-1 again\n")
+	require.Empty(t, backups(t, path))
+
+	now = now.Add(time.Hour)
+	write("day-2\n")
+	require.Len(t, backups(t, path), 1)
+
+	now = now.Add(48 * time.Hour)
+	write("day-4\n")
+	rotated := backups(t, path)
+	require.Len(t, rotated, 1, "the backups older than maxAge are removed")
+	data, err := os.ReadFile(rotated[0])
+	require.NoError(t, err)
+	require.Equal(t, "day-2\n", string(data))
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	f, err := openRotatingFile(path, rotation{maxSize: 10})
+	require.NoError(t, err)
+	f.now = func() time.Time { return now }
+	defer f.Close()
+
+	// the backup name is taken by a directory, so the file cannot be renamed to it
+	blocked := path + "." + now.Format(backupLayout)
+	require.NoError(t, os.MkdirAll(filepath.Join(blocked, "taken"), 0o755))
+
+	for _, line := range []string{"line-1\n", "line-2\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line-1\nline-2\n", string(data), "the writes go on to the same file")
+
+	now = now.Add(time.Second)
+	_, err = f.Write([]byte("line-3\n"))
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line-3\n", string(data), "the rotation is retried")
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const textTimeLayout = "2006-01-02 15:04:05.000"
+
+// textHandler writes the lines meant for a person reading the console:
+// the time, the level and the message come first, the attributes follow as key=value.
+type textHandler struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	options *slog.HandlerOptions
+	// prefix is the formatted attributes added with WithAttrs
+	prefix string
+	groups []string
+}
+
+func newTextHandler(w io.Writer, options *slog.HandlerOptions) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, w: w, options: options}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.options.Level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(textTimeLayout))
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(&b, "%-5s %s", r.Level.String(), r.Message)
+	b.WriteString(h.prefix)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.groups, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	for _, a := range attrs {
+		writeAttr(&b, h.groups, a)
+	}
+	clone := *h
+	clone.prefix = b.String()
+	return &clone
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string{}, h.groups...), name)
+	return &clone
+}
+
+func writeAttr(b *strings.Builder, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(append([]string{}, groups...), a.Key)
+		}
+		for _, attr := range a.Value.Group() {
+			writeAttr(b, groups, attr)
+		}
+		return
+	}
+
+	key := strings.Join(append(append([]string{}, groups...), a.Key), ".")
+	b.WriteByte(' ')
+	b.WriteString(quote(key))
+	b.WriteByte('=')
+	switch a.Value.Kind() { //nolint:exhaustive
+	case slog.KindTime:
+		b.WriteString(a.Value.Time().Format(time.RFC3339Nano))
+	default:
+		b.WriteString(quote(a.Value.String()))
+	}
+}
+
+// quote quotes the strings which would not read as a single token.
+func quote(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '=' || r == '"' || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+// Package lrucache is the least recently used cache with expiring entries.
+package lrucache
+
+import (
+	"sync"
+	"time"
+)
+
+type Key string
+
+// Stats counts the cache lookups since the cache was created.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Expired   uint64 `json:"expired"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+type internalData struct {
+	Key       Key
+	Value     any
+	ExpiresAt time.Time
+}
+
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	queue    List
+	items    map[Key]*ListItem
+	stats    Stats
+	now      func() time.Time
+}
+
+// New creates the cache keeping up to capacity entries, the entries expire after ttl unless it is zero.
+func New(capacity int, ttl time.Duration) *Cache {
+	return &Cache{
+		capacity: capacity,
+		ttl:      ttl,
+		queue:    NewList(),
+		items:    make(map[Key]*ListItem, capacity),
+		now:      time.Now,
+	}
+}
+
+func (c *Cache) Set(key Key, value any) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := internalData{Key: key, Value: value}
+	if c.ttl > 0 {
+		data.ExpiresAt = c.now().Add(c.ttl)
+	}
+
+	if item, ok := c.items[key]; ok {
+		c.queue.MoveToFront(item)
+		item.Value = data
+		return true
+	}
+
+	c.storeItem(key, c.queue.PushFront(data))
+	if c.queue.Len() > c.capacity {
+		c.removeItem(c.queue.Back())
+		c.stats.Evictions++
+	}
+
+	return false
+}
+
+func (c *Cache) Get(key Key) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	data, ok := item.Value.(internalData)
+	if !ok || (!data.ExpiresAt.IsZero() && !c.now().Before(data.ExpiresAt)) {
+		c.removeItem(item)
+		c.stats.Expired++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.queue.MoveToFront(item)
+	c.stats.Hits++
+	return data.Value, true
+}
+
+func (c *Cache) Remove(key Key) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item, ok := c.items[key]; ok {
+		c.removeItem(item)
+	}
+}
+
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queue = NewList()
+	c.items = make(map[Key]*ListItem, c.capacity)
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.queue.Len()
+	stats.Capacity = c.capacity
+	return stats
+}
+
+func (c *Cache) storeItem(key Key, item *ListItem) {
+	c.items[key] = item
+}
+
+func (c *Cache) removeItem(item *ListItem) {
+	data, ok := item.Value.(internalData)
+
+	if ok {
+		delete(c.items, data.Key)
+		c.queue.Remove(item)
+	}
+}
@@ -0,0 +1,312 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockKey identifies the advisory lock which keeps replicas from migrating at the same time.
+const lockKey = 7_357_001
+
+// versionTable is the table goose keeps the applied versions in, it is shared with the goose container.
+const versionTable = "goose_db_version"
+
+const annotation = "-- +goose"
+
+var (
+	ErrNoMigrations    = errors.New("no migrations to roll back")
+	errNoUpAnnotation  = errors.New("missing '-- +goose Up' annotation")
+	errUnknownVersion  = errors.New("migration file name must start with the version number")
+	errDuplicateNumber = errors.New("duplicate migration version")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Dialect adapts the version bookkeeping and locking to the database.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS, dialect Dialect) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load reads the *.sql migrations ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	versions := make(map[int64]string, len(names))
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(path.Base(name), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: %w", name, errUnknownVersion)
+		}
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("%s and %s: %w", other, name, errDuplicateNumber)
+		}
+		versions[version] = name
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, Up: up, Down: down})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parse splits the goose annotated file into statements, StatementBegin/End blocks are kept whole.
+func parse(data string) (up, down []string, err error) {
+	var section *[]string
+	var statement strings.Builder
+	inBlock := false
+	// the Up section may be empty, when the migration has nothing to do in the dialect
+	hasUp := false
+
+	flush := func() {
+		if section != nil && hasSQL(statement.String()) {
+			*section = append(*section, strings.TrimSpace(statement.String()))
+		}
+		statement.Reset()
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if command, ok := strings.CutPrefix(trimmed, annotation); ok {
+			switch strings.TrimSpace(command) {
+			case "Up":
+				flush()
+				section = &up
+				hasUp = true
+			case "Down":
+				flush()
+				section = &down
+			case "StatementBegin":
+				flush()
+				inBlock = true
+			case "StatementEnd":
+				flush()
+				inBlock = false
+			}
+			continue
+		}
+		if section == nil {
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+
+	if !hasUp {
+		return nil, nil, errNoUpAnnotation
+	}
+	return up, down, nil
+}
+
+func hasSQL(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return true
+		}
+	}
+	return false
+}
+
+// Up applies the pending migrations, each one in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			err := run(ctx, conn, migration.Up,
+				"INSERT INTO "+versionTable+" (version_id, is_applied) VALUES ($1, true)", migration.Version)
+			if err != nil {
+				return fmt.Errorf("cannot apply %s: %w", migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var rolledBack Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			err := run(ctx, conn, migration.Down,
+				"DELETE FROM "+versionTable+" WHERE version_id = $1", migration.Version)
+			if err != nil {
+				return fmt.Errorf("cannot roll back %s: %w", migration.Name, err)
+			}
+			rolledBack = migration
+			return nil
+		}
+		return ErrNoMigrations
+	})
+	return rolledBack, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	result := make([]Status, 0, len(m.migrations))
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			appliedAt, ok := versions[migration.Version]
+			result = append(result, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// withLock runs fn on a single connection holding the advisory lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	// SQLite databases serve a single node, the database lock serializes the migrating transactions
+	if m.dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+			return fmt.Errorf("cannot acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	}
+
+	if err := m.ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	id, now := "serial", "now()"
+	if m.dialect == SQLite {
+		id, now = "integer", "CURRENT_TIMESTAMP"
+	}
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+versionTable+` (
+		id `+id+` primary key,
+		version_id bigint not null,
+		is_applied boolean not null,
+		tstamp timestamp default `+now+`
+	)`)
+	if err != nil {
+		return fmt.Errorf("cannot create version table: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions replays the version log, goose marks rolled back versions by either a row or its removal.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version_id, is_applied, tstamp FROM "+versionTable+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var applied bool
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &applied, &appliedAt); err != nil {
+			return nil, err
+		}
+		if applied {
+			versions[version] = appliedAt.Time
+		} else {
+			delete(versions, version)
+		}
+	}
+	return versions, rows.Err()
+}
+
+func run(ctx context.Context, conn *sql.Conn, statements []string, record string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return errors.Join(err, tx.Rollback())
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/migrations"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite" // need import sqlite
+)
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+
+	for i, m := range loaded {
+		require.Equal(t, int64(i+1), m.Version, m.Name)
+		require.NotEmpty(t, m.Up, m.Name)
+		require.NotEmpty(t, m.Down, m.Name)
+	}
+
+	changes := loaded[4]
+	require.Len(t, changes.Up, 4)
+	require.True(t, strings.HasPrefix(changes.Up[2], "CREATE FUNCTION log_event_change()"))
+	require.True(t, strings.HasSuffix(changes.Up[2], "$$ LANGUAGE plpgsql;"))
+	require.Equal(t, []string{"drop table events;"}, loaded[0].Down)
+}
+
+func TestSQLiteMigrationsMatch(t *testing.T) {
+	postgres, err := Load(migrations.FS)
+	require.NoError(t, err)
+	sqlite, err := Load(migrations.SQLite())
+	require.NoError(t, err)
+
+	require.Len(t, sqlite, len(postgres))
+	for i := range postgres {
+		require.Equal(t, postgres[i].Version, sqlite[i].Version)
+		require.Equal(t, postgres[i].Name, sqlite[i].Name)
+	}
+}
+
+func TestMigratorSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	migrator, err := New(db, migrations.SQLite(), SQLite)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations))
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		require.True(t, s.Applied, s.Name)
+		require.False(t, s.AppliedAt.IsZero(), s.Name)
+	}
+
+	for range migrator.migrations {
+		_, err := migrator.Down(ctx)
+		require.NoError(t, err)
+	}
+	_, err = migrator.Down(ctx)
+	require.ErrorIs(t, err, ErrNoMigrations)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations), "migrations apply again after the roll back")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		up   []string
+		down []string
+	}{
+		{
+			name: "statements",
+			data: "-- +goose Up\nCREATE TABLE a (id int);\n\n-- comment\nINSERT INTO a\nVALUES (1);\n" +
+				"-- +goose Down\nDROP TABLE a",
+			up:   []string{"CREATE TABLE a (id int);", "-- comment\nINSERT INTO a\nVALUES (1);"},
+			down: []string{"DROP TABLE a"},
+		},
+		{
+			name: "block",
+			data: "-- header\n-- +goose Up\n-- +goose StatementBegin\nBEGIN;\nSELECT 1;\nEND;\n-- +goose StatementEnd\n",
+			up:   []string{"BEGIN;\nSELECT 1;\nEND;"},
+		},
+		{
+			name: "trailing comment",
+			data: "-- +goose Up\nSELECT 1;\n-- done\n-- +goose Down\n",
+			up:   []string{"SELECT 1;"},
+		},
+		{
+			// the migration has nothing to do in one of the dialects
+			name: "empty up",
+			data: "-- +goose Up\n-- nothing to do\n\n-- +goose Down\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			up, down, err := parse(tt.data)
+			require.NoError(t, err)
+			require.Equal(t, tt.up, up)
+			require.Equal(t, tt.down, down)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no version": {"init.sql": {Data: []byte("-- +goose Up\nSELECT 1;")}},
+		"duplicate": {
+			"001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;")},
+			"1_b.sql":   {Data: []byte("-- +goose Up\nSELECT 1;")},
+		},
+		"no annotation": {"001_a.sql": {Data: []byte("SELECT 1;")}},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			fsys := fsys
+			t.Parallel()
+
+			_, err := Load(fsys)
+			require.Error(t, err)
+		})
+	}
+}
@@ -1,23 +1,31 @@
 package queue
 
 import (
+	"crypto/tls"
 	"fmt"
 	"sync"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/tlsconfig"
 	"github.com/streadway/amqp"
 )
 
 type Connection struct {
 	uri      string
+	tls      config.TLSConf
 	amqpConn *amqp.Connection
 	mut      sync.Mutex
 }
 
+// NewConnection connects with amqps if TLS is enabled.
 func NewConnection(conf config.QueueServerConf) *Connection {
-	uri := fmt.Sprintf("amqp://%v:%v@%v:%v/", conf.User, conf.Password, conf.Host, conf.Port)
+	scheme := "amqp"
+	if conf.TLS.Enabled {
+		scheme = "amqps"
+	}
+	uri := fmt.Sprintf("%v://%v:%v@%v:%v/", scheme, conf.User, conf.Password, conf.Host, conf.Port)
 
-	return &Connection{uri: uri}
+	return &Connection{uri: uri, tls: conf.TLS}
 }
 
 func (c *Connection) Connect() error {
@@ -25,7 +33,16 @@ func (c *Connection) Connect() error {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
-	c.amqpConn, err = amqp.Dial(c.uri)
+	if c.tls.Enabled {
+		// the CA is read on every connect, the client certificate when its files change
+		var tlsConfig *tls.Config
+		if tlsConfig, err = tlsconfig.NewClientConfig(c.tls); err != nil {
+			return fmt.Errorf("connection failure: %w", err)
+		}
+		c.amqpConn, err = amqp.DialTLS(c.uri, tlsConfig)
+	} else {
+		c.amqpConn, err = amqp.Dial(c.uri)
+	}
 	if err != nil {
 		return fmt.Errorf("connection failure: %w", err)
 	}
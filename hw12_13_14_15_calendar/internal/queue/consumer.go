@@ -34,6 +34,7 @@ func NewConsumer(
 	}
 }
 
+// Start consumes the messages until ctx is done, the message being handled then is finished first.
 func (c *Consumer) Start(ctx context.Context) error {
 	var err error
 
@@ -81,6 +82,11 @@ func (c *Consumer) Start(ctx context.Context) error {
 			if !ok {
 				return nil
 			}
+			if ctx.Err() != nil {
+				// the consumer is stopping, the message is redelivered to another one
+				msg.Nack(false, true)
+				return nil
+			}
 			c.handler.Handle(msg)
 		}
 	}
@@ -0,0 +1,122 @@
+// Package ratelimit limits the calls of every client with the token buckets.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/lrucache"
+)
+
+const defaultMaxClients = 10000
+
+var errWrongRate = errors.New(`expected the rate like "10/s", "600/m" or "1000/h"`)
+
+// Rate refills the bucket with Limit tokens per second up to Burst, the zero Limit does not limit the calls.
+type Rate struct {
+	Limit float64
+	Burst int
+}
+
+// ParseRate parses the rate like "10/s", "600/m" or "1000/h", the empty one does not limit the calls.
+// The burst defaults to the number of calls of the rate.
+func ParseRate(rate string, burst int) (Rate, error) {
+	if rate == "" {
+		return Rate{}, nil
+	}
+
+	count, unit, ok := strings.Cut(rate, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q", errWrongRate, rate)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Rate{}, fmt.Errorf("%w: %q", errWrongRate, rate)
+	}
+
+	if burst <= 0 {
+		burst = n
+	}
+	return Rate{Limit: float64(n) / per.Seconds(), Burst: burst}, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a bucket per client and method. The buckets of up to maxClients pairs are kept,
+// the least recently used one is dropped first and starts full when the client comes back.
+type Limiter struct {
+	mu      sync.Mutex
+	rate    Rate
+	methods map[string]Rate
+	buckets *lrucache.Cache
+	now     func() time.Time
+}
+
+// New creates the limiter applying rate to every method except the ones listed in methods.
+func New(rate Rate, methods map[string]Rate, maxClients int) *Limiter {
+	if maxClients <= 0 {
+		maxClients = defaultMaxClients
+	}
+	return &Limiter{
+		rate:    rate,
+		methods: methods,
+		buckets: lrucache.New(maxClients, 0),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the client for the method.
+// If there is none, it returns false and the time until the next token.
+func (l *Limiter) Allow(method, client string) (bool, time.Duration) {
+	rate, ok := l.methods[method]
+	if !ok {
+		rate = l.rate
+	}
+	if rate.Limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key := lrucache.Key(method + " " + client)
+	b, ok := l.getBucket(key)
+	if !ok {
+		b = &bucket{tokens: float64(rate.Burst), last: now}
+		l.buckets.Set(key, b)
+	}
+
+	b.tokens = math.Min(float64(rate.Burst), b.tokens+now.Sub(b.last).Seconds()*rate.Limit)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate.Limit * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *Limiter) getBucket(key lrucache.Key) (*bucket, bool) {
+	value, ok := l.buckets.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return value.(*bucket), true
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		burst    int
+		expected Rate
+		err      bool
+	}{
+		{rate: "", expected: Rate{}},
+		{rate: "10/s", expected: Rate{Limit: 10, Burst: 10}},
+		{rate: "60/m", burst: 5, expected: Rate{Limit: 1, Burst: 5}},
+		{rate: "3600/h", expected: Rate{Limit: 1, Burst: 3600}},
+		{rate: "10", err: true},
+		{rate: "0/s", err: true},
+		{rate: "10/d", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rate, func(t *testing.T) {
+			rate, err := ParseRate(tc.rate, tc.burst)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, rate)
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(Rate{Limit: 1, Burst: 2}, map[string]Rate{
+		"/calendar.Events/CreateEvent": {Limit: 0.5, Burst: 1},
+		"/calendar.Events/GetEvent":    {},
+	}, 3)
+	l.now = func() time.Time { return now }
+
+	allowed := func(method, client string) bool {
+		ok, _ := l.Allow(method, client)
+		return ok
+	}
+
+	require.True(t, allowed("/calendar.Events/ListCalendars", "alice"))
+	require.True(t, allowed("/calendar.Events/ListCalendars", "alice"))
+	ok, wait := l.Allow("/calendar.Events/ListCalendars", "alice")
+	require.False(t, ok, "the burst is spent")
+	require.Equal(t, time.Second, wait)
+	require.True(t, allowed("/calendar.Events/ListCalendars", "bob"), "the clients have their own buckets")
+
+	require.True(t, allowed("/calendar.Events/CreateEvent", "alice"))
+	ok, wait = l.Allow("/calendar.Events/CreateEvent", "alice")
+	require.False(t, ok, "the methods have their own limits")
+	require.Equal(t, 2*time.Second, wait)
+
+	for i := 0; i < 10; i++ {
+		require.True(t, allowed("/calendar.Events/GetEvent", "alice"), "the zero rate does not limit")
+	}
+
+	now = now.Add(time.Second)
+	require.True(t, allowed("/calendar.Events/ListCalendars", "alice"), "the bucket is refilled")
+	require.False(t, allowed("/calendar.Events/ListCalendars", "alice"))
+
+	require.True(t, allowed("/calendar.Events/ListCalendars", "carol"))
+	require.Equal(t, 3, l.buckets.Stats().Size, "the buckets are bounded")
+}
@@ -0,0 +1,42 @@
+// Package requestid correlates the log lines of a request across the gateway and the gRPC server.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	Header      = "X-Request-ID"
+	MetadataKey = "x-request-id"
+)
+
+// maxLength bounds the ids accepted from the callers, they end up in every log line of the request.
+const maxLength = 128
+
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Valid tells whether the id sent by the caller can be reused, the others are replaced with a new one.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Ensure returns the id sent by the caller if it is valid and a new one otherwise.
+func Ensure(id string) string {
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	roles = map[pb.CalendarGrant_Role]model.Role{
+		pb.CalendarGrant_FREE_BUSY: model.RoleFreeBusy,
+		pb.CalendarGrant_VIEWER:    model.RoleViewer,
+		pb.CalendarGrant_EDITOR:    model.RoleEditor,
+		pb.CalendarGrant_OWNER:     model.RoleOwner,
+	}
+	pbRoles = map[model.Role]pb.CalendarGrant_Role{
+		model.RoleFreeBusy: pb.CalendarGrant_FREE_BUSY,
+		model.RoleViewer:   pb.CalendarGrant_VIEWER,
+		model.RoleEditor:   pb.CalendarGrant_EDITOR,
+		model.RoleOwner:    pb.CalendarGrant_OWNER,
+	}
+)
+
+func (s *Service) toCalendar(calendar model.Calendar) *pb.Calendar {
+	return &pb.Calendar{
+		Id:         calendar.ID,
+		Name:       calendar.Name,
+		OwnerEmail: calendar.OwnerEmail,
+	}
+}
+
+func (s *Service) CreateCalendar(ctx context.Context, req *pb.NewCalendarRequest) (*pb.Calendar, error) {
+	calendar, err := s.app.CreateCalendar(ctx, req.GetName(), req.GetOwnerEmail())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return s.toCalendar(calendar), nil
+}
+
+func (s *Service) ListCalendars(ctx context.Context, req *pb.ListCalendarsRequest) (*pb.CalendarList, error) {
+	result, err := s.app.ListCalendars(ctx, req.GetEmail())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	calendars := make([]*pb.Calendar, 0, len(result))
+	for _, c := range result {
+		calendars = append(calendars, s.toCalendar(c))
+	}
+
+	return &pb.CalendarList{Calendars: calendars}, nil
+}
+
+func (s *Service) ShareCalendar(ctx context.Context, req *pb.ShareCalendarRequest) (*empty.Empty, error) {
+	if req.GetCalendarId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "calendar is not specified")
+	}
+	role, ok := roles[req.GetRole()]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "role is not specified")
+	}
+
+	err := s.app.ShareCalendar(ctx, model.Grant{CalendarID: req.GetCalendarId(), Email: req.GetEmail(), Role: role})
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+func (s *Service) UnshareCalendar(ctx context.Context, req *pb.UnshareCalendarRequest) (*empty.Empty, error) {
+	if req.GetCalendarId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "calendar is not specified")
+	}
+
+	err := s.app.UnshareCalendar(ctx, req.GetCalendarId(), req.GetEmail())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+func (s *Service) ListCalendarGrants(ctx context.Context, req *pb.CalendarIdRequest) (*pb.CalendarGrantList, error) {
+	if req.GetCalendarId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "calendar is not specified")
+	}
+
+	result, err := s.app.ListCalendarGrants(ctx, req.GetCalendarId())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	grants := make([]*pb.CalendarGrant, 0, len(result))
+	for _, g := range result {
+		grants = append(grants, &pb.CalendarGrant{CalendarId: g.CalendarID, Email: g.Email, Role: pbRoles[g.Role]})
+	}
+
+	return &pb.CalendarGrantList{Grants: grants}, nil
+}
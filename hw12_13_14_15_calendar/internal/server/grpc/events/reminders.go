@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	channels = map[pb.Reminder_Channel]model.Channel{
+		pb.Reminder_CHANNEL_UNSPECIFIED: "",
+		pb.Reminder_EMAIL:               model.ChannelEmail,
+		pb.Reminder_WEBHOOK:             model.ChannelWebhook,
+	}
+	pbChannels = map[model.Channel]pb.Reminder_Channel{
+		model.ChannelEmail:   pb.Reminder_EMAIL,
+		model.ChannelWebhook: pb.Reminder_WEBHOOK,
+	}
+)
+
+func toContractReminders(items []*pb.Reminder) []contracts.Reminder {
+	reminders := make([]contracts.Reminder, 0, len(items))
+	for _, item := range items {
+		channel, ok := channels[item.GetChannel()]
+		if !ok {
+			// unknown values are rejected by the application
+			channel = model.Channel(item.GetChannel().String())
+		}
+		reminders = append(reminders, contracts.Reminder{
+			Before:  item.GetBefore(),
+			Channel: channel,
+			Target:  item.GetTarget(),
+		})
+	}
+	return reminders
+}
+
+func (s *Service) toPbReminders(reminders []model.Reminder) []*pb.Reminder {
+	items := make([]*pb.Reminder, 0, len(reminders))
+	for _, r := range reminders {
+		items = append(items, s.toPbReminder(r))
+	}
+	return items
+}
+
+func (s *Service) toPbReminder(r model.Reminder) *pb.Reminder {
+	item := &pb.Reminder{
+		Id:         r.ID,
+		Before:     r.Before,
+		Channel:    pbChannels[r.Channel],
+		Target:     r.Target,
+		NotifyTime: r.NotifyTime.Unix(),
+		Notified:   r.Notified,
+	}
+	if !r.SnoozedUntil.IsZero() {
+		item.SnoozedUntil = r.SnoozedUntil.Unix()
+	}
+	return item
+}
+
+func (s *Service) SnoozeReminder(ctx context.Context, req *pb.SnoozeReminderRequest) (*pb.Reminder, error) {
+	if err := validateReminderID(req.GetEventId(), req.GetReminderId()); err != nil {
+		return nil, err
+	}
+
+	reminder, err := s.app.SnoozeReminder(ctx, req.GetEventId(), req.GetReminderId(), req.GetDuration())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return s.toPbReminder(reminder), nil
+}
+
+func (s *Service) AckReminder(ctx context.Context, req *pb.ReminderIdRequest) (*pb.Reminder, error) {
+	if err := validateReminderID(req.GetEventId(), req.GetReminderId()); err != nil {
+		return nil, err
+	}
+
+	reminder, err := s.app.AckReminder(ctx, req.GetEventId(), req.GetReminderId())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return s.toPbReminder(reminder), nil
+}
+
+func validateReminderID(eventID, reminderID string) error {
+	if eventID == "" {
+		return status.Error(codes.InvalidArgument, "event is not specified")
+	}
+	if reminderID == "" {
+		return status.Error(codes.InvalidArgument, "reminder is not specified")
+	}
+	return nil
+}
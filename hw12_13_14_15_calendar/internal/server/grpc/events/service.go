@@ -2,9 +2,13 @@ package events
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 	"google.golang.org/grpc/codes"
@@ -14,10 +18,49 @@ import (
 type Application interface {
 	CreateEvent(ctx context.Context, dto contracts.Event) (model.Event, error)
 	UpdateEvent(ctx context.Context, dto contracts.Event) (model.Event, error)
+	PatchEvent(ctx context.Context, dto contracts.Event, fields []string) (model.Event, error)
 	GetEvent(ctx context.Context, eventID string) (model.Event, error)
 	DeleteEvent(ctx context.Context, eventID string) error
 	ListEventsForDate(ctx context.Context, ownerEmail string, date int64) ([]model.Event, error)
 	ListEventsForWeek(ctx context.Context, ownerEmail string, date int64) ([]model.Event, error)
+	ListEventsForPeriod(ctx context.Context, ownerEmail string, startDate, endDate time.Time) ([]model.Event, error)
+	SearchEvents(
+		ctx context.Context,
+		ownerEmail, query string,
+		from, to int64,
+		pageSize int,
+		pageToken string,
+	) ([]model.EventMatch, string, error)
+	BatchCreateEvents(ctx context.Context, dtos []contracts.Event, atomic bool) ([]contracts.EventResult, error)
+	BatchGetEvents(ctx context.Context, eventIDs []string, atomic bool) ([]contracts.EventResult, error)
+	BatchDeleteEvents(ctx context.Context, eventIDs []string, atomic bool) ([]contracts.EventResult, error)
+	WatchEvents(ctx context.Context, ownerEmail, revisionToken string) (<-chan model.Change, error)
+	ListCalendarEventsForDate(ctx context.Context, calendarID string, date int64) ([]model.Event, error)
+	ListCalendarEventsForWeek(ctx context.Context, calendarID string, date int64) ([]model.Event, error)
+	ListCalendarEventsForPeriod(
+		ctx context.Context,
+		calendarID string,
+		startDate, endDate time.Time,
+	) ([]model.Event, error)
+	CreateCalendar(ctx context.Context, name, ownerEmail string) (model.Calendar, error)
+	ListCalendars(ctx context.Context, email string) ([]model.Calendar, error)
+	ShareCalendar(ctx context.Context, grant model.Grant) error
+	UnshareCalendar(ctx context.Context, calendarID, email string) error
+	ListCalendarGrants(ctx context.Context, calendarID string) ([]model.Grant, error)
+	SnoozeReminder(ctx context.Context, eventID, reminderID, duration string) (model.Reminder, error)
+	AckReminder(ctx context.Context, eventID, reminderID string) (model.Reminder, error)
+}
+
+// v1EventFields are the fields a v1 update without the mask replaces, the time zone set through v2 is kept.
+var v1EventFields = []string{
+	contracts.EventFieldTitle,
+	contracts.EventFieldStartTime,
+	contracts.EventFieldEndTime,
+	contracts.EventFieldDescription,
+	contracts.EventFieldOwnerEmail,
+	contracts.EventFieldNotify,
+	contracts.EventFieldCalendarID,
+	contracts.EventFieldReminders,
 }
 
 type Logger interface {
@@ -46,6 +89,54 @@ func (s *Service) toPersistedEvent(ev model.Event) *pb.PersistedEvent {
 		Description: ev.Description,
 		OwnerEmail:  ev.OwnerEmail,
 		Notify:      ev.NotifyBefore,
+		CalendarId:  ev.CalendarID,
+		Reminders:   s.toPbReminders(ev.Reminders),
+	}
+}
+
+func (s *Service) toBatchResponse(results []contracts.EventResult, withEvents bool) *pb.BatchEventResponse {
+	items := make([]*pb.BatchEventResult, 0, len(results))
+	for _, res := range results {
+		item := &pb.BatchEventResult{Id: res.ID}
+		switch {
+		case res.Err != nil:
+			item.Error = &pb.BatchItemError{Code: int32(errorCode(res.Err)), Message: res.Err.Error()}
+		case withEvents:
+			item.Event = s.toPersistedEvent(res.Event)
+		}
+		items = append(items, item)
+	}
+
+	return &pb.BatchEventResponse{Results: items}
+}
+
+var changeTypes = map[model.ChangeType]pb.EventChange_Type{
+	model.ChangeCreated: pb.EventChange_CREATED,
+	model.ChangeUpdated: pb.EventChange_UPDATED,
+	model.ChangeDeleted: pb.EventChange_DELETED,
+}
+
+func errorCode(err error) codes.Code {
+	var notFoundErr customerrors.NotFound
+	var alreadyExistsErr customerrors.AlreadyExists
+	var validationErr customerrors.ValidationError
+	var paramErr customerrors.ParamError
+	var outOfRangeErr customerrors.OutOfRange
+	var permissionErr customerrors.PermissionDenied
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		return codes.NotFound
+	case errors.As(err, &alreadyExistsErr):
+		return codes.AlreadyExists
+	case errors.As(err, &outOfRangeErr):
+		return codes.OutOfRange
+	case errors.As(err, &permissionErr):
+		return codes.PermissionDenied
+	case errors.As(err, &validationErr), errors.As(err, &paramErr):
+		return codes.InvalidArgument
+	default:
+		return status.Code(err)
 	}
 }
 
@@ -57,14 +148,16 @@ func (s *Service) CreateEvent(ctx context.Context, req *pb.NewEventRequest) (*pb
 
 	event, err := s.app.CreateEvent(ctx, contracts.Event{
 		Title:        payload.Title,
-		StartTime:    payload.StartTime,
-		EndTime:      payload.EndTime,
+		StartTime:    time.Unix(payload.StartTime, 0),
+		EndTime:      time.Unix(payload.EndTime, 0),
 		Description:  payload.Description,
 		OwnerEmail:   payload.OwnerEmail,
 		NotifyBefore: payload.Notify,
+		CalendarID:   payload.CalendarId,
+		Reminders:    toContractReminders(payload.Reminders),
 	})
 	if err != nil {
-		return nil, err
+		return nil, status.Error(errorCode(err), err.Error())
 	}
 
 	return &pb.ScalarEventResponse{
@@ -82,17 +175,30 @@ func (s *Service) UpdateEvent(ctx context.Context, req *pb.UpdateEventRequest) (
 		return nil, status.Error(codes.InvalidArgument, "event is not specified")
 	}
 
-	event, err := s.app.UpdateEvent(ctx, contracts.Event{
+	dto := contracts.Event{
 		ID:           id,
 		Title:        payload.Title,
-		StartTime:    payload.StartTime,
-		EndTime:      payload.EndTime,
+		StartTime:    time.Unix(payload.StartTime, 0),
+		EndTime:      time.Unix(payload.EndTime, 0),
 		Description:  payload.Description,
 		OwnerEmail:   payload.OwnerEmail,
 		NotifyBefore: payload.Notify,
-	})
+		CalendarID:   payload.CalendarId,
+		Reminders:    toContractReminders(payload.Reminders),
+	}
+
+	var event model.Event
+	var err error
+	if mask := req.GetUpdateMask(); len(mask.GetPaths()) > 0 {
+		if !mask.IsValid(payload) {
+			return nil, status.Error(codes.InvalidArgument, "update mask contains unknown fields")
+		}
+		event, err = s.app.PatchEvent(ctx, dto, mask.GetPaths())
+	} else {
+		event, err = s.app.PatchEvent(ctx, dto, v1EventFields)
+	}
 	if err != nil {
-		return nil, err
+		return nil, status.Error(errorCode(err), err.Error())
 	}
 
 	return &pb.ScalarEventResponse{
@@ -108,7 +214,7 @@ func (s *Service) GetEvent(ctx context.Context, req *pb.EventIdRequest) (*pb.Sca
 
 	event, err := s.app.GetEvent(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(errorCode(err), err.Error())
 	}
 
 	return &pb.ScalarEventResponse{
@@ -124,7 +230,7 @@ func (s *Service) DeleteEvent(ctx context.Context, req *pb.EventIdRequest) (*emp
 
 	err := s.app.DeleteEvent(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(errorCode(err), err.Error())
 	}
 
 	return &empty.Empty{}, nil
@@ -133,13 +239,20 @@ func (s *Service) DeleteEvent(ctx context.Context, req *pb.EventIdRequest) (*emp
 func (s *Service) GetEventsForDay(ctx context.Context, req *pb.DateRequest) (*pb.VectorEventResponse, error) {
 	owner := req.GetOwner()
 	date := req.GetDate()
-	if owner == "" {
-		return nil, status.Error(codes.InvalidArgument, "owner is not specified")
+	calendarID := req.GetCalendarId()
+	if owner == "" && calendarID == "" {
+		return nil, status.Error(codes.InvalidArgument, "owner or calendar is not specified")
 	}
 
-	result, err := s.app.ListEventsForDate(ctx, owner, date)
+	var result []model.Event
+	var err error
+	if calendarID != "" {
+		result, err = s.app.ListCalendarEventsForDate(ctx, calendarID, date)
+	} else {
+		result, err = s.app.ListEventsForDate(ctx, owner, date)
+	}
 	if err != nil {
-		return nil, err
+		return nil, status.Error(errorCode(err), err.Error())
 	}
 
 	persitedEvents := make([]*pb.PersistedEvent, 0)
@@ -155,13 +268,20 @@ func (s *Service) GetEventsForDay(ctx context.Context, req *pb.DateRequest) (*pb
 func (s *Service) GetEventsForWeek(ctx context.Context, req *pb.DateRequest) (*pb.VectorEventResponse, error) {
 	owner := req.GetOwner()
 	date := req.GetDate()
-	if owner == "" {
-		return nil, status.Error(codes.InvalidArgument, "owner is not specified")
+	calendarID := req.GetCalendarId()
+	if owner == "" && calendarID == "" {
+		return nil, status.Error(codes.InvalidArgument, "owner or calendar is not specified")
 	}
 
-	result, err := s.app.ListEventsForWeek(ctx, owner, date)
+	var result []model.Event
+	var err error
+	if calendarID != "" {
+		result, err = s.app.ListCalendarEventsForWeek(ctx, calendarID, date)
+	} else {
+		result, err = s.app.ListEventsForWeek(ctx, owner, date)
+	}
 	if err != nil {
-		return nil, err
+		return nil, status.Error(errorCode(err), err.Error())
 	}
 
 	persistedEvents := make([]*pb.PersistedEvent, 0)
@@ -173,3 +293,113 @@ func (s *Service) GetEventsForWeek(ctx context.Context, req *pb.DateRequest) (*p
 		Events: persistedEvents,
 	}, nil
 }
+
+func (s *Service) SearchEvents(ctx context.Context, req *pb.SearchRequest) (*pb.SearchEventResponse, error) {
+	owner := req.GetOwner()
+	if owner == "" {
+		return nil, status.Error(codes.InvalidArgument, "owner is not specified")
+	}
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is not specified")
+	}
+
+	result, nextPageToken, err := s.app.SearchEvents(ctx,
+		owner,
+		req.GetQuery(),
+		req.GetFrom(),
+		req.GetTo(),
+		int(req.GetPageSize()),
+		req.GetPageToken(),
+	)
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	matches := make([]*pb.EventMatch, 0, len(result))
+	for _, m := range result {
+		matches = append(matches, &pb.EventMatch{
+			Event:                s.toPersistedEvent(m.Event),
+			Rank:                 m.Rank,
+			TitleHighlight:       m.TitleHighlight,
+			DescriptionHighlight: m.DescriptionHighlight,
+		})
+	}
+
+	return &pb.SearchEventResponse{
+		Matches:       matches,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (s *Service) BatchCreateEvents(
+	ctx context.Context,
+	req *pb.BatchCreateEventsRequest,
+) (*pb.BatchEventResponse, error) {
+	dtos := make([]contracts.Event, 0, len(req.GetEvents()))
+	for _, payload := range req.GetEvents() {
+		dtos = append(dtos, contracts.Event{
+			Title:        payload.Title,
+			StartTime:    time.Unix(payload.StartTime, 0),
+			EndTime:      time.Unix(payload.EndTime, 0),
+			Description:  payload.Description,
+			OwnerEmail:   payload.OwnerEmail,
+			NotifyBefore: payload.Notify,
+			CalendarID:   payload.CalendarId,
+			Reminders:    toContractReminders(payload.Reminders),
+		})
+	}
+
+	results, err := s.app.BatchCreateEvents(ctx, dtos, req.GetAtomic())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return s.toBatchResponse(results, true), nil
+}
+
+func (s *Service) BatchGetEvents(ctx context.Context, req *pb.BatchEventIdsRequest) (*pb.BatchEventResponse, error) {
+	results, err := s.app.BatchGetEvents(ctx, req.GetIds(), req.GetAtomic())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return s.toBatchResponse(results, true), nil
+}
+
+func (s *Service) BatchDeleteEvents(ctx context.Context, req *pb.BatchEventIdsRequest) (*pb.BatchEventResponse, error) {
+	results, err := s.app.BatchDeleteEvents(ctx, req.GetIds(), req.GetAtomic())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return s.toBatchResponse(results, false), nil
+}
+
+func (s *Service) WatchEvents(req *pb.WatchRequest, stream pb.Events_WatchEventsServer) error {
+	owner := req.GetOwner()
+	if owner == "" {
+		return status.Error(codes.InvalidArgument, "owner is not specified")
+	}
+
+	ctx := stream.Context()
+	changes, err := s.app.WatchEvents(ctx, owner, req.GetRevisionToken())
+	if err != nil {
+		return status.Error(errorCode(err), err.Error())
+	}
+
+	for change := range changes {
+		err := stream.Send(&pb.EventChange{
+			RevisionToken: strconv.FormatInt(change.Revision, 10),
+			Type:          changeTypes[change.Type],
+			Event:         s.toPersistedEvent(change.Event),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return status.Error(codes.Unavailable, "change feed interrupted, resume from the last revision token")
+}
@@ -0,0 +1,369 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/contracts"
+	pbv2 "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb/v2"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	v2Channels = map[pbv2.Reminder_Channel]model.Channel{
+		pbv2.Reminder_CHANNEL_UNSPECIFIED: "",
+		pbv2.Reminder_EMAIL:               model.ChannelEmail,
+		pbv2.Reminder_WEBHOOK:             model.ChannelWebhook,
+	}
+	pbv2Channels = map[model.Channel]pbv2.Reminder_Channel{
+		model.ChannelEmail:   pbv2.Reminder_EMAIL,
+		model.ChannelWebhook: pbv2.Reminder_WEBHOOK,
+	}
+	// v2MaskPaths are the application fields named differently in v2.
+	v2MaskPaths = map[string]string{
+		"notify_before": contracts.EventFieldNotify,
+	}
+)
+
+// V2Service serves the events with the RFC 3339 timestamps, the durations and the time zones.
+type V2Service struct {
+	logger Logger
+	app    Application
+	pbv2.UnimplementedEventsServer
+}
+
+func NewV2Service(logger Logger, app Application) *V2Service {
+	return &V2Service{logger: logger, app: app}
+}
+
+func (s *V2Service) CreateEvent(ctx context.Context, req *pbv2.NewEventRequest) (*pbv2.ScalarEventResponse, error) {
+	payload := req.GetEvent()
+	if payload == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is not specified")
+	}
+	if err := validatePeriod(payload); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	event, err := s.app.CreateEvent(ctx, toV2Contract(payload))
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &pbv2.ScalarEventResponse{
+		Event: toV2PersistedEvent(event),
+	}, nil
+}
+
+func (s *V2Service) UpdateEvent(ctx context.Context, req *pbv2.UpdateEventRequest) (*pbv2.ScalarEventResponse, error) {
+	id := req.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is not specified")
+	}
+	payload := req.GetEvent()
+	if payload == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is not specified")
+	}
+
+	dto := toV2Contract(payload)
+	dto.ID = id
+
+	var event model.Event
+	var err error
+	if mask := req.GetUpdateMask(); len(mask.GetPaths()) > 0 {
+		if !mask.IsValid(payload) {
+			return nil, status.Error(codes.InvalidArgument, "update mask contains unknown fields")
+		}
+		fields := make([]string, 0, len(mask.GetPaths()))
+		for _, path := range mask.GetPaths() {
+			if field, ok := v2MaskPaths[path]; ok {
+				path = field
+			}
+			fields = append(fields, path)
+		}
+		event, err = s.app.PatchEvent(ctx, dto, fields)
+	} else {
+		if err := validatePeriod(payload); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		event, err = s.app.UpdateEvent(ctx, dto)
+	}
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &pbv2.ScalarEventResponse{
+		Event: toV2PersistedEvent(event),
+	}, nil
+}
+
+func (s *V2Service) GetEvent(ctx context.Context, req *pbv2.EventIdRequest) (*pbv2.ScalarEventResponse, error) {
+	id := req.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is not specified")
+	}
+
+	event, err := s.app.GetEvent(ctx, id)
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &pbv2.ScalarEventResponse{
+		Event: toV2PersistedEvent(event),
+	}, nil
+}
+
+func (s *V2Service) DeleteEvent(ctx context.Context, req *pbv2.EventIdRequest) (*empty.Empty, error) {
+	id := req.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is not specified")
+	}
+
+	err := s.app.DeleteEvent(ctx, id)
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+func (s *V2Service) GetEventsForDay(ctx context.Context, req *pbv2.DateRequest) (*pbv2.VectorEventResponse, error) {
+	return s.listEvents(ctx, req, 1)
+}
+
+func (s *V2Service) GetEventsForWeek(ctx context.Context, req *pbv2.DateRequest) (*pbv2.VectorEventResponse, error) {
+	return s.listEvents(ctx, req, 7)
+}
+
+// listEvents lists the events of the days starting at the midnight of the requested date in its time zone,
+// the days are counted by the calendar, so they keep their length over the daylight saving changes.
+func (s *V2Service) listEvents(
+	ctx context.Context,
+	req *pbv2.DateRequest,
+	days int,
+) (*pbv2.VectorEventResponse, error) {
+	owner := req.GetOwner()
+	calendarID := req.GetCalendarId()
+	if owner == "" && calendarID == "" {
+		return nil, status.Error(codes.InvalidArgument, "owner or calendar is not specified")
+	}
+	if err := validateTimestamp("date", req.GetDate()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	location := time.UTC
+	if req.GetTimeZone() != "" {
+		var err error
+		if location, err = time.LoadLocation(req.GetTimeZone()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown time zone %q", req.GetTimeZone())
+		}
+	}
+
+	date := req.GetDate().AsTime().In(location)
+	startDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+	endDate := startDate.AddDate(0, 0, days)
+
+	var result []model.Event
+	var err error
+	if calendarID != "" {
+		result, err = s.app.ListCalendarEventsForPeriod(ctx, calendarID, startDate, endDate)
+	} else {
+		result, err = s.app.ListEventsForPeriod(ctx, owner, startDate, endDate)
+	}
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	persistedEvents := make([]*pbv2.PersistedEvent, 0, len(result))
+	for _, e := range result {
+		persistedEvents = append(persistedEvents, toV2PersistedEvent(e))
+	}
+
+	return &pbv2.VectorEventResponse{
+		Events: persistedEvents,
+	}, nil
+}
+
+func (s *V2Service) SearchEvents(ctx context.Context, req *pbv2.SearchRequest) (*pbv2.SearchEventResponse, error) {
+	owner := req.GetOwner()
+	if owner == "" {
+		return nil, status.Error(codes.InvalidArgument, "owner is not specified")
+	}
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is not specified")
+	}
+
+	// the search bounds are whole seconds, the zero to searches till the end of time
+	var from, to int64
+	if req.GetFrom() != nil {
+		from = req.GetFrom().GetSeconds()
+	}
+	if req.GetTo() != nil {
+		to = req.GetTo().GetSeconds()
+	}
+
+	result, nextPageToken, err := s.app.SearchEvents(ctx,
+		owner,
+		req.GetQuery(),
+		from,
+		to,
+		int(req.GetPageSize()),
+		req.GetPageToken(),
+	)
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	matches := make([]*pbv2.EventMatch, 0, len(result))
+	for _, m := range result {
+		matches = append(matches, &pbv2.EventMatch{
+			Event:                toV2PersistedEvent(m.Event),
+			Rank:                 m.Rank,
+			TitleHighlight:       m.TitleHighlight,
+			DescriptionHighlight: m.DescriptionHighlight,
+		})
+	}
+
+	return &pbv2.SearchEventResponse{
+		Matches:       matches,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (s *V2Service) BatchCreateEvents(
+	ctx context.Context,
+	req *pbv2.BatchCreateEventsRequest,
+) (*pbv2.BatchEventResponse, error) {
+	dtos := make([]contracts.Event, 0, len(req.GetEvents()))
+	for i, payload := range req.GetEvents() {
+		if err := validatePeriod(payload); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "events[%d]: %v", i, err)
+		}
+		dtos = append(dtos, toV2Contract(payload))
+	}
+
+	results, err := s.app.BatchCreateEvents(ctx, dtos, req.GetAtomic())
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	items := make([]*pbv2.BatchEventResult, 0, len(results))
+	for _, res := range results {
+		item := &pbv2.BatchEventResult{Id: res.ID}
+		if res.Err != nil {
+			item.Error = &pbv2.BatchItemError{Code: int32(errorCode(res.Err)), Message: res.Err.Error()}
+		} else {
+			item.Event = toV2PersistedEvent(res.Event)
+		}
+		items = append(items, item)
+	}
+
+	return &pbv2.BatchEventResponse{Results: items}, nil
+}
+
+// validatePeriod requires the start and the end, v1 took the missing ones for the Unix epoch.
+func validatePeriod(payload *pbv2.TransientEvent) error {
+	if err := validateTimestamp("start_time", payload.GetStartTime()); err != nil {
+		return err
+	}
+	return validateTimestamp("end_time", payload.GetEndTime())
+}
+
+func validateTimestamp(name string, ts *timestamppb.Timestamp) error {
+	if ts == nil {
+		return fmt.Errorf("%v is not specified", name)
+	}
+	if err := ts.CheckValid(); err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+	return nil
+}
+
+func toV2Contract(payload *pbv2.TransientEvent) contracts.Event {
+	dto := contracts.Event{
+		Title:        payload.GetTitle(),
+		StartTime:    toTime(payload.GetStartTime()),
+		EndTime:      toTime(payload.GetEndTime()),
+		TimeZone:     payload.GetTimeZone(),
+		Description:  payload.GetDescription(),
+		OwnerEmail:   payload.GetOwnerEmail(),
+		NotifyBefore: durationString(payload.GetNotifyBefore()),
+		CalendarID:   payload.GetCalendarId(),
+		Reminders:    make([]contracts.Reminder, 0, len(payload.GetReminders())),
+	}
+	for _, item := range payload.GetReminders() {
+		channel, ok := v2Channels[item.GetChannel()]
+		if !ok {
+			// unknown values are rejected by the application
+			channel = model.Channel(item.GetChannel().String())
+		}
+		dto.Reminders = append(dto.Reminders, contracts.Reminder{
+			Before:  durationString(item.GetBefore()),
+			Channel: channel,
+			Target:  item.GetTarget(),
+		})
+	}
+	return dto
+}
+
+func toV2PersistedEvent(ev model.Event) *pbv2.PersistedEvent {
+	event := &pbv2.PersistedEvent{
+		Id:           ev.ID,
+		Title:        ev.Title,
+		StartTime:    timestamppb.New(ev.StartTime),
+		EndTime:      timestamppb.New(ev.EndTime),
+		TimeZone:     ev.TimeZone,
+		Description:  ev.Description,
+		OwnerEmail:   ev.OwnerEmail,
+		NotifyBefore: toDuration(ev.NotifyBefore),
+		CalendarId:   ev.CalendarID,
+		Reminders:    make([]*pbv2.Reminder, 0, len(ev.Reminders)),
+	}
+	for _, r := range ev.Reminders {
+		item := &pbv2.Reminder{
+			Id:         r.ID,
+			Before:     toDuration(r.Before),
+			Channel:    pbv2Channels[r.Channel],
+			Target:     r.Target,
+			NotifyTime: timestamppb.New(r.NotifyTime),
+			Notified:   r.Notified,
+		}
+		if !r.SnoozedUntil.IsZero() {
+			item.SnoozedUntil = timestamppb.New(r.SnoozedUntil)
+		}
+		event.Reminders = append(event.Reminders, item)
+	}
+	return event
+}
+
+func toTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// durationString keeps the offsets in the form of the v1 notify strings, the application parses them.
+func durationString(d *durationpb.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.AsDuration().String()
+}
+
+// toDuration converts the stored offset, the invalid ones cannot be stored and are left out.
+func toDuration(s string) *durationpb.Duration {
+	if s == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil
+	}
+	return durationpb.New(d)
+}
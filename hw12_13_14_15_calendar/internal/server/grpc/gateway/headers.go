@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/requestid"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/middleware"
+	"google.golang.org/grpc/metadata"
+)
+
+// IncomingHeaderMatcher forwards the log correlation headers to the gRPC metadata along with the default ones.
+// The identity headers the callers send are dropped, IdentityMetadata forwards the identity established
+// for the request.
+func IncomingHeaderMatcher(key string) (string, bool) {
+	name := strings.TrimPrefix(strings.ToLower(key), strings.ToLower(runtime.MetadataHeaderPrefix))
+	if name == identity.MetadataKey || name == middleware.GatewayTokenMetadataKey {
+		return "", false
+	}
+
+	for _, forwarded := range []string{
+		requestid.MetadataKey,
+		middleware.TraceparentMetadataKey,
+	} {
+		if strings.EqualFold(key, forwarded) {
+			return forwarded, true
+		}
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// IdentityMetadata forwards the identity of the HTTP caller along with token, the gRPC server trusts
+// the identity forwarded with its gateway token.
+func IdentityMetadata(token string) func(context.Context, *http.Request) metadata.MD {
+	return func(_ context.Context, r *http.Request) metadata.MD {
+		md := metadata.Pairs(middleware.GatewayTokenMetadataKey, token)
+		if email, ok := identity.UserFromContext(r.Context()); ok {
+			md.Set(identity.MetadataKey, email)
+		}
+		return md
+	}
+}
+
+// OutgoingHeaderMatcher sends Retry-After of the limited calls as is, the other metadata gets the default prefix.
+func OutgoingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, middleware.RetryAfterMetadataKey) {
+		return "Retry-After", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
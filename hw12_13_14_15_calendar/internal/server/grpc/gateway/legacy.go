@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIPrefix is the version prefix of the REST routes.
+const APIPrefix = "/api/v1"
+
+// legacyRoots are the unversioned resources the routes were served at before the prefix.
+var legacyRoots = []string{"/events", "/calendars"}
+
+// LegacyPathMiddleware routes the unversioned paths like /events/{id} to their /api/v1 successors
+// while the clients migrate, the responses are marked deprecated.
+type LegacyPathMiddleware struct {
+	handler http.Handler
+}
+
+func NewLegacyPathMiddleware(handlerToWrap http.Handler) *LegacyPathMiddleware {
+	return &LegacyPathMiddleware{handler: handlerToWrap}
+}
+
+func (m *LegacyPathMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isLegacyPath(r.URL.Path) {
+		r.URL.Path = APIPrefix + r.URL.Path
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = APIPrefix + r.URL.RawPath
+		}
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+r.URL.EscapedPath()+`>; rel="successor-version"`)
+	}
+
+	m.handler.ServeHTTP(w, r)
+}
+
+func isLegacyPath(path string) bool {
+	for _, root := range legacyRoots {
+		rest, ok := strings.CutPrefix(path, root)
+		if ok && (rest == "" || rest[0] == '/' || rest[0] == ':') {
+			return true
+		}
+	}
+	return false
+}
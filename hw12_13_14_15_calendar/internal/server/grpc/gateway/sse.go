@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	EventStreamContentType = "text/event-stream"
+	lastEventIDHeader      = "Last-Event-ID"
+	revisionTokenParam     = "revision_token"
+)
+
+// SSEMarshaler writes streamed messages as Server-Sent Events.
+// Event changes carry their revision token as the event id, so browsers resume with Last-Event-ID.
+type SSEMarshaler struct {
+	runtime.JSONPb
+}
+
+func NewSSEMarshaler() *SSEMarshaler {
+	return &SSEMarshaler{
+		JSONPb: runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		},
+	}
+}
+
+func (m *SSEMarshaler) ContentType(_ any) string {
+	return EventStreamContentType
+}
+
+func (m *SSEMarshaler) Marshal(v any) ([]byte, error) {
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if chunk, ok := v.(map[string]any); ok {
+		if change, ok := chunk["result"].(*pb.EventChange); ok && change.GetRevisionToken() != "" {
+			buf.WriteString("id: " + change.GetRevisionToken() + "\n")
+		}
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+
+	return buf.Bytes(), nil
+}
+
+func (m *SSEMarshaler) Delimiter() []byte {
+	return []byte("\n\n")
+}
+
+type LastEventIDMiddleware struct {
+	handler http.Handler
+}
+
+func NewLastEventIDMiddleware(handlerToWrap http.Handler) *LastEventIDMiddleware {
+	return &LastEventIDMiddleware{handler: handlerToWrap}
+}
+
+func (m *LastEventIDMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if lastEventID := r.Header.Get(lastEventIDHeader); lastEventID != "" {
+		query := r.URL.Query()
+		if query.Get(revisionTokenParam) == "" {
+			query.Set(revisionTokenParam, lastEventID)
+			r.URL.RawQuery = query.Encode()
+		}
+	}
+
+	m.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const TraceparentMetadataKey = "traceparent"
+
+// LogFieldsUnaryInterceptor attaches the trace id and the caller to the lines logged for the call.
+// It goes after the identity interceptor and before the logging one.
+func LogFieldsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withLogFields(ctx), req)
+	}
+}
+
+func LogFieldsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpcmiddleware.WrapServerStream(stream)
+		wrapped.WrappedContext = withLogFields(stream.Context())
+		return handler(srv, wrapped)
+	}
+}
+
+func withLogFields(ctx context.Context) context.Context {
+	if values := metadata.ValueFromIncomingContext(ctx, TraceparentMetadataKey); len(values) > 0 {
+		ctx = logger.WithTraceID(ctx, logger.TraceIDFromTraceparent(values[0]))
+	}
+	if email, ok := identity.UserFromContext(ctx); ok {
+		ctx = logger.WithOwner(ctx, email)
+	}
+	return ctx
+}
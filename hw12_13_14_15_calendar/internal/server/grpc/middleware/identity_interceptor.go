@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GatewayTokenMetadataKey carries the token of the gateway, the identity it forwards is trusted with it.
+const GatewayTokenMetadataKey = "x-gateway-token"
+
+// anonymousPrefix starts the methods of the health and the reflection services, they need no identity.
+const anonymousPrefix = "/grpc."
+
+// IdentityConf tells where the callers are identified from. The verified client certificate identifies
+// the caller by its email address or its common name. The identity in x-user-email is taken along with
+// GatewayToken, which the gateway of the same process sends, and from any caller with TrustUserHeader,
+// for the proxy authenticating the callers. The calls without identity are rejected with Unauthenticated
+// unless AllowAnonymous is set, such callers are trusted with the full access.
+type IdentityConf struct {
+	GatewayToken    string
+	TrustUserHeader bool
+	AllowAnonymous  bool
+}
+
+func IdentityUnaryInterceptor(conf IdentityConf) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := conf.identify(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func IdentityStreamInterceptor(conf IdentityConf) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := conf.identify(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpcmiddleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+type gatewayKey struct{}
+
+// fromGateway tells whether the call is forwarded by the gateway of the same process.
+func fromGateway(ctx context.Context) bool {
+	forwarded, _ := ctx.Value(gatewayKey{}).(bool)
+	return forwarded
+}
+
+func (c IdentityConf) identify(ctx context.Context, method string) (context.Context, error) {
+	if c.GatewayToken != "" &&
+		subtle.ConstantTimeCompare([]byte(firstValue(ctx, GatewayTokenMetadataKey)), []byte(c.GatewayToken)) == 1 {
+		ctx = context.WithValue(ctx, gatewayKey{}, true)
+	}
+	if email, ok := c.caller(ctx); ok {
+		return identity.WithUser(ctx, email), nil
+	}
+	if c.AllowAnonymous || strings.HasPrefix(method, anonymousPrefix) {
+		return ctx, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "the caller is not identified")
+}
+
+func (c IdentityConf) caller(ctx context.Context) (string, bool) {
+	header := firstValue(ctx, identity.MetadataKey)
+	if fromGateway(ctx) {
+		return header, header != ""
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if email, ok := identity.FromTLS(&info.State); ok {
+				return email, true
+			}
+		}
+	}
+
+	if c.TrustUserHeader {
+		return header, header != ""
+	}
+	return "", false
+}
+
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func InterceptorLogger(l Logger) logging.Logger {
-	return logging.LoggerFunc(func(_ context.Context, lvl logging.Level, msg string, fields ...any) {
+	return logging.LoggerFunc(func(ctx context.Context, lvl logging.Level, msg string, fields ...any) {
 		f := make([]any, 0, len(fields)/2)
 
 		for i := 0; i < len(fields); i += 2 {
@@ -20,13 +20,13 @@ func InterceptorLogger(l Logger) logging.Logger {
 
 		switch lvl {
 		case logging.LevelDebug:
-			l.Debug(msg, f...)
+			l.DebugContext(ctx, msg, f...)
 		case logging.LevelInfo:
-			l.Info(msg, f...)
+			l.InfoContext(ctx, msg, f...)
 		case logging.LevelWarn:
-			l.Warn(msg, f...)
+			l.WarnContext(ctx, msg, f...)
 		case logging.LevelError:
-			l.Error(msg, f...)
+			l.ErrorContext(ctx, msg, f...)
 		default:
 			panic(fmt.Sprintf("unknown level %v", lvl))
 		}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/requestid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequestIDUnaryInterceptor(t *testing.T) {
+	interceptor := RequestIDUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/calendar.Events/GetEvent"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestid.MetadataKey, "req-42"))
+	logged := filepath.Join(t.TempDir(), "calendar.log")
+	l := logger.New("INFO", logged)
+	defer l.Close()
+
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, _ any) (any, error) {
+		l.InfoContext(ctx, "handled")
+		return nil, nil
+	})
+	require.NoError(t, err)
+	data, err := os.ReadFile(logged)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Request Id":"req-42"`)
+
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, _ any) (any, error) {
+		l.InfoContext(ctx, "generated")
+		return nil, nil
+	})
+	require.NoError(t, err)
+	data, err = os.ReadFile(logged)
+	require.NoError(t, err)
+	require.Regexp(t, `"msg":"generated","Request Id":"[0-9a-f]{32}"`, string(data))
+}
+
+func TestRecoveryUnaryInterceptor(t *testing.T) {
+	logged := filepath.Join(t.TempDir(), "calendar.log")
+	l := logger.New("INFO", logged)
+	defer l.Close()
+
+	interceptor := RecoveryUnaryInterceptor(l)
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	data, err := os.ReadFile(logged)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"panic":"boom"`)
+	require.Contains(t, string(data), "middleware_test.go")
+}
+
+func TestIdentityUnaryInterceptor(t *testing.T) {
+	certified := func(cert *x509.Certificate) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+		}})
+	}
+	withHeaders := func(ctx context.Context, kv ...string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(kv...))
+	}
+
+	tests := []struct {
+		name   string
+		conf   IdentityConf
+		ctx    context.Context
+		method string
+		caller string
+		code   codes.Code
+	}{
+		{
+			name:   "certificate email",
+			ctx:    certified(&x509.Certificate{EmailAddresses: []string{"cert@example.com"}}),
+			caller: "cert@example.com",
+		},
+		{
+			name:   "certificate common name",
+			ctx:    certified(&x509.Certificate{Subject: pkix.Name{CommonName: "cn@example.com"}}),
+			caller: "cn@example.com",
+		},
+		{
+			name: "certificate over trusted header",
+			conf: IdentityConf{TrustUserHeader: true},
+			ctx: withHeaders(certified(&x509.Certificate{EmailAddresses: []string{"cert@example.com"}}),
+				identity.MetadataKey, "header@example.com"),
+			caller: "cert@example.com",
+		},
+		{
+			name: "untrusted header",
+			ctx:  withHeaders(context.Background(), identity.MetadataKey, "header@example.com"),
+			code: codes.Unauthenticated,
+		},
+		{
+			name:   "trusted header",
+			conf:   IdentityConf{TrustUserHeader: true},
+			ctx:    withHeaders(context.Background(), identity.MetadataKey, "header@example.com"),
+			caller: "header@example.com",
+		},
+		{
+			name: "forwarded by gateway",
+			conf: IdentityConf{GatewayToken: "secret"},
+			ctx: withHeaders(context.Background(),
+				GatewayTokenMetadataKey, "secret", identity.MetadataKey, "gateway@example.com"),
+			caller: "gateway@example.com",
+		},
+		{
+			name: "wrong gateway token",
+			conf: IdentityConf{GatewayToken: "secret"},
+			ctx: withHeaders(context.Background(),
+				GatewayTokenMetadataKey, "guess", identity.MetadataKey, "gateway@example.com"),
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "anonymous",
+			ctx:  context.Background(),
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "anonymous allowed",
+			conf: IdentityConf{AllowAnonymous: true},
+			ctx:  context.Background(),
+		},
+		{
+			name:   "health check",
+			ctx:    context.Background(),
+			method: "/grpc.health.v1.Health/Check",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = "/calendar.Events/GetEvent"
+			}
+			var caller string
+			_, err := IdentityUnaryInterceptor(tc.conf)(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+				func(ctx context.Context, _ any) (any, error) {
+					caller, _ = identity.UserFromContext(ctx)
+					return nil, nil
+				})
+			require.Equal(t, tc.code, status.Code(err))
+			require.Equal(t, tc.caller, caller)
+		})
+	}
+}
+
+type fakeLimiter struct {
+	clients []string
+	allowed bool
+}
+
+func (l *fakeLimiter) Allow(_, client string) (bool, time.Duration) {
+	l.clients = append(l.clients, client)
+	return l.allowed, 1500 * time.Millisecond
+}
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		client string
+	}{
+		{
+			name:   "identity",
+			ctx:    identity.WithUser(context.Background(), "user@example.com"),
+			client: "user:user@example.com",
+		},
+		{
+			name:   "address",
+			ctx:    peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7")}}),
+			client: "ip:10.0.0.7",
+		},
+		{
+			name: "unauthenticated header",
+			ctx: metadata.NewIncomingContext(
+				peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.6")}}),
+				metadata.Pairs(identity.MetadataKey, "victim@example.com"),
+			),
+			client: "ip:10.0.0.6",
+		},
+		{
+			name: "forwarded by gateway",
+			ctx: metadata.NewIncomingContext(
+				context.WithValue(
+					peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}}),
+					gatewayKey{}, true,
+				),
+				metadata.Pairs("x-forwarded-for", "6.6.6.6, 10.0.0.8"),
+			),
+			client: "ip:10.0.0.8",
+		},
+		{
+			name: "forwarded from loopback",
+			ctx: metadata.NewIncomingContext(
+				peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}}),
+				metadata.Pairs("x-forwarded-for", "6.6.6.6"),
+			),
+			client: "ip:127.0.0.1",
+		},
+		{
+			name: "forwarded by caller",
+			ctx: metadata.NewIncomingContext(
+				peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.9")}}),
+				metadata.Pairs("x-forwarded-for", "6.6.6.6"),
+			),
+			client: "ip:10.0.0.9",
+		},
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/calendar.Events/CreateEvent"}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := &fakeLimiter{allowed: true}
+			resp, err := RateLimitUnaryInterceptor(limiter)(tc.ctx, nil, info, handler)
+			require.NoError(t, err)
+			require.Equal(t, "ok", resp)
+
+			limiter.allowed = false
+			_, err = RateLimitUnaryInterceptor(limiter)(tc.ctx, nil, info, handler)
+			require.Equal(t, codes.ResourceExhausted, status.Code(err))
+			require.Contains(t, err.Error(), "retry in 2 seconds")
+			require.Equal(t, []string{tc.client, tc.client}, limiter.clients)
+		})
+	}
+}
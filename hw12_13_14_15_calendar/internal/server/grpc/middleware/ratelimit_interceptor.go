@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterMetadataKey tells the limited caller how many seconds to wait, the gateway sends it as Retry-After.
+const RetryAfterMetadataKey = "retry-after"
+
+const forwardedForMetadataKey = "x-forwarded-for"
+
+type RateLimiter interface {
+	Allow(method, client string) (bool, time.Duration)
+}
+
+// RateLimitUnaryInterceptor rejects the calls over the limit of the method with ResourceExhausted.
+// It goes after the identity interceptor, the callers are told apart by their authenticated identity
+// or their address.
+func RateLimitUnaryInterceptor(limiter RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allow(ctx, limiter, info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func RateLimitStreamInterceptor(limiter RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(stream.Context(), limiter, info.FullMethod, stream.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func allow(ctx context.Context, limiter RateLimiter, method string, setHeader func(metadata.MD) error) error {
+	ok, wait := limiter.Allow(method, client(ctx))
+	if ok {
+		return nil
+	}
+
+	retryAfter := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+	_ = setHeader(metadata.Pairs(RetryAfterMetadataKey, retryAfter))
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %v seconds", retryAfter)
+}
+
+// client identifies the caller by the identity the identity interceptor has authenticated or by the address.
+// The address of the calls forwarded by the gateway is the last one of x-forwarded-for, which the gateway adds.
+func client(ctx context.Context) string {
+	if email, ok := identity.UserFromContext(ctx); ok {
+		return "user:" + email
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if fromGateway(ctx) {
+		if values := metadata.ValueFromIncomingContext(ctx, forwardedForMetadataKey); len(values) > 0 {
+			forwarded := strings.Split(values[len(values)-1], ",")
+			host = strings.TrimSpace(forwarded[len(forwarded)-1])
+		}
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDUnaryInterceptor reuses the request id forwarded by the gateway or the client, or generates one.
+// The id is sent back in the response header and attached to the lines logged for the call.
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := incomingRequestID(ctx)
+		// the header cannot be sent when the call is not served by a transport, e.g. in the tests
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+		return handler(logger.WithRequestID(ctx, id), req)
+	}
+}
+
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := incomingRequestID(stream.Context())
+		_ = stream.SetHeader(metadata.Pairs(requestid.MetadataKey, id))
+
+		wrapped := grpcmiddleware.WrapServerStream(stream)
+		wrapped.WrappedContext = logger.WithRequestID(stream.Context(), id)
+		return handler(srv, wrapped)
+	}
+}
+
+func incomingRequestID(ctx context.Context) string {
+	var id string
+	if values := metadata.ValueFromIncomingContext(ctx, requestid.MetadataKey); len(values) > 0 {
+		id = values[0]
+	}
+	return requestid.Ensure(id)
+}
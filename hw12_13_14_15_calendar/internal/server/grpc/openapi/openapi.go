@@ -0,0 +1,39 @@
+// Package openapi serves the OpenAPI v2 documents of the REST gateway, make generate writes them from the proto.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Path is where the v1 document is served, V2Path the v2 one.
+const (
+	Path   = "/openapi.json"
+	V2Path = "/api/v2/openapi.json"
+)
+
+var (
+	//go:embed EventService.swagger.json
+	v1Spec []byte
+	//go:embed v2/EventService.swagger.json
+	v2Spec []byte
+)
+
+// Documents maps the paths the documents are served at to them.
+func Documents() map[string][]byte {
+	return map[string][]byte{Path: v1Spec, V2Path: v2Spec}
+}
+
+// Handler serves the document, the browser tools on the other origins may read it too.
+func Handler(spec []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write(spec)
+	})
+}
@@ -20,12 +20,24 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Events_CreateEvent_FullMethodName      = "/calendar.Events/CreateEvent"
-	Events_UpdateEvent_FullMethodName      = "/calendar.Events/UpdateEvent"
-	Events_GetEvent_FullMethodName         = "/calendar.Events/GetEvent"
-	Events_DeleteEvent_FullMethodName      = "/calendar.Events/DeleteEvent"
-	Events_GetEventsForDay_FullMethodName  = "/calendar.Events/GetEventsForDay"
-	Events_GetEventsForWeek_FullMethodName = "/calendar.Events/GetEventsForWeek"
+	Events_CreateEvent_FullMethodName        = "/calendar.Events/CreateEvent"
+	Events_UpdateEvent_FullMethodName        = "/calendar.Events/UpdateEvent"
+	Events_GetEvent_FullMethodName           = "/calendar.Events/GetEvent"
+	Events_DeleteEvent_FullMethodName        = "/calendar.Events/DeleteEvent"
+	Events_GetEventsForDay_FullMethodName    = "/calendar.Events/GetEventsForDay"
+	Events_GetEventsForWeek_FullMethodName   = "/calendar.Events/GetEventsForWeek"
+	Events_SearchEvents_FullMethodName       = "/calendar.Events/SearchEvents"
+	Events_BatchCreateEvents_FullMethodName  = "/calendar.Events/BatchCreateEvents"
+	Events_BatchGetEvents_FullMethodName     = "/calendar.Events/BatchGetEvents"
+	Events_BatchDeleteEvents_FullMethodName  = "/calendar.Events/BatchDeleteEvents"
+	Events_WatchEvents_FullMethodName        = "/calendar.Events/WatchEvents"
+	Events_CreateCalendar_FullMethodName     = "/calendar.Events/CreateCalendar"
+	Events_ListCalendars_FullMethodName      = "/calendar.Events/ListCalendars"
+	Events_ShareCalendar_FullMethodName      = "/calendar.Events/ShareCalendar"
+	Events_UnshareCalendar_FullMethodName    = "/calendar.Events/UnshareCalendar"
+	Events_ListCalendarGrants_FullMethodName = "/calendar.Events/ListCalendarGrants"
+	Events_SnoozeReminder_FullMethodName     = "/calendar.Events/SnoozeReminder"
+	Events_AckReminder_FullMethodName        = "/calendar.Events/AckReminder"
 )
 
 // EventsClient is the client API for Events service.
@@ -38,6 +50,18 @@ type EventsClient interface {
 	DeleteEvent(ctx context.Context, in *EventIdRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 	GetEventsForDay(ctx context.Context, in *DateRequest, opts ...grpc.CallOption) (*VectorEventResponse, error)
 	GetEventsForWeek(ctx context.Context, in *DateRequest, opts ...grpc.CallOption) (*VectorEventResponse, error)
+	SearchEvents(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchEventResponse, error)
+	BatchCreateEvents(ctx context.Context, in *BatchCreateEventsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error)
+	BatchGetEvents(ctx context.Context, in *BatchEventIdsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error)
+	BatchDeleteEvents(ctx context.Context, in *BatchEventIdsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error)
+	WatchEvents(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EventChange], error)
+	CreateCalendar(ctx context.Context, in *NewCalendarRequest, opts ...grpc.CallOption) (*Calendar, error)
+	ListCalendars(ctx context.Context, in *ListCalendarsRequest, opts ...grpc.CallOption) (*CalendarList, error)
+	ShareCalendar(ctx context.Context, in *ShareCalendarRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	UnshareCalendar(ctx context.Context, in *UnshareCalendarRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListCalendarGrants(ctx context.Context, in *CalendarIdRequest, opts ...grpc.CallOption) (*CalendarGrantList, error)
+	SnoozeReminder(ctx context.Context, in *SnoozeReminderRequest, opts ...grpc.CallOption) (*Reminder, error)
+	AckReminder(ctx context.Context, in *ReminderIdRequest, opts ...grpc.CallOption) (*Reminder, error)
 }
 
 type eventsClient struct {
@@ -108,6 +132,135 @@ func (c *eventsClient) GetEventsForWeek(ctx context.Context, in *DateRequest, op
 	return out, nil
 }
 
+func (c *eventsClient) SearchEvents(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchEventResponse)
+	err := c.cc.Invoke(ctx, Events_SearchEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) BatchCreateEvents(ctx context.Context, in *BatchCreateEventsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchEventResponse)
+	err := c.cc.Invoke(ctx, Events_BatchCreateEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) BatchGetEvents(ctx context.Context, in *BatchEventIdsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchEventResponse)
+	err := c.cc.Invoke(ctx, Events_BatchGetEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) BatchDeleteEvents(ctx context.Context, in *BatchEventIdsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchEventResponse)
+	err := c.cc.Invoke(ctx, Events_BatchDeleteEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) WatchEvents(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EventChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Events_ServiceDesc.Streams[0], Events_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, EventChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Events_WatchEventsClient = grpc.ServerStreamingClient[EventChange]
+
+func (c *eventsClient) CreateCalendar(ctx context.Context, in *NewCalendarRequest, opts ...grpc.CallOption) (*Calendar, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Calendar)
+	err := c.cc.Invoke(ctx, Events_CreateCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) ListCalendars(ctx context.Context, in *ListCalendarsRequest, opts ...grpc.CallOption) (*CalendarList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalendarList)
+	err := c.cc.Invoke(ctx, Events_ListCalendars_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) ShareCalendar(ctx context.Context, in *ShareCalendarRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, Events_ShareCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) UnshareCalendar(ctx context.Context, in *UnshareCalendarRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, Events_UnshareCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) ListCalendarGrants(ctx context.Context, in *CalendarIdRequest, opts ...grpc.CallOption) (*CalendarGrantList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalendarGrantList)
+	err := c.cc.Invoke(ctx, Events_ListCalendarGrants_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) SnoozeReminder(ctx context.Context, in *SnoozeReminderRequest, opts ...grpc.CallOption) (*Reminder, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reminder)
+	err := c.cc.Invoke(ctx, Events_SnoozeReminder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) AckReminder(ctx context.Context, in *ReminderIdRequest, opts ...grpc.CallOption) (*Reminder, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reminder)
+	err := c.cc.Invoke(ctx, Events_AckReminder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EventsServer is the server API for Events service.
 // All implementations must embed UnimplementedEventsServer
 // for forward compatibility.
@@ -118,6 +271,18 @@ type EventsServer interface {
 	DeleteEvent(context.Context, *EventIdRequest) (*empty.Empty, error)
 	GetEventsForDay(context.Context, *DateRequest) (*VectorEventResponse, error)
 	GetEventsForWeek(context.Context, *DateRequest) (*VectorEventResponse, error)
+	SearchEvents(context.Context, *SearchRequest) (*SearchEventResponse, error)
+	BatchCreateEvents(context.Context, *BatchCreateEventsRequest) (*BatchEventResponse, error)
+	BatchGetEvents(context.Context, *BatchEventIdsRequest) (*BatchEventResponse, error)
+	BatchDeleteEvents(context.Context, *BatchEventIdsRequest) (*BatchEventResponse, error)
+	WatchEvents(*WatchRequest, grpc.ServerStreamingServer[EventChange]) error
+	CreateCalendar(context.Context, *NewCalendarRequest) (*Calendar, error)
+	ListCalendars(context.Context, *ListCalendarsRequest) (*CalendarList, error)
+	ShareCalendar(context.Context, *ShareCalendarRequest) (*empty.Empty, error)
+	UnshareCalendar(context.Context, *UnshareCalendarRequest) (*empty.Empty, error)
+	ListCalendarGrants(context.Context, *CalendarIdRequest) (*CalendarGrantList, error)
+	SnoozeReminder(context.Context, *SnoozeReminderRequest) (*Reminder, error)
+	AckReminder(context.Context, *ReminderIdRequest) (*Reminder, error)
 	mustEmbedUnimplementedEventsServer()
 }
 
@@ -146,6 +311,42 @@ func (UnimplementedEventsServer) GetEventsForDay(context.Context, *DateRequest)
 func (UnimplementedEventsServer) GetEventsForWeek(context.Context, *DateRequest) (*VectorEventResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetEventsForWeek not implemented")
 }
+func (UnimplementedEventsServer) SearchEvents(context.Context, *SearchRequest) (*SearchEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchEvents not implemented")
+}
+func (UnimplementedEventsServer) BatchCreateEvents(context.Context, *BatchCreateEventsRequest) (*BatchEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCreateEvents not implemented")
+}
+func (UnimplementedEventsServer) BatchGetEvents(context.Context, *BatchEventIdsRequest) (*BatchEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetEvents not implemented")
+}
+func (UnimplementedEventsServer) BatchDeleteEvents(context.Context, *BatchEventIdsRequest) (*BatchEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchDeleteEvents not implemented")
+}
+func (UnimplementedEventsServer) WatchEvents(*WatchRequest, grpc.ServerStreamingServer[EventChange]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedEventsServer) CreateCalendar(context.Context, *NewCalendarRequest) (*Calendar, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCalendar not implemented")
+}
+func (UnimplementedEventsServer) ListCalendars(context.Context, *ListCalendarsRequest) (*CalendarList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCalendars not implemented")
+}
+func (UnimplementedEventsServer) ShareCalendar(context.Context, *ShareCalendarRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShareCalendar not implemented")
+}
+func (UnimplementedEventsServer) UnshareCalendar(context.Context, *UnshareCalendarRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnshareCalendar not implemented")
+}
+func (UnimplementedEventsServer) ListCalendarGrants(context.Context, *CalendarIdRequest) (*CalendarGrantList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCalendarGrants not implemented")
+}
+func (UnimplementedEventsServer) SnoozeReminder(context.Context, *SnoozeReminderRequest) (*Reminder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SnoozeReminder not implemented")
+}
+func (UnimplementedEventsServer) AckReminder(context.Context, *ReminderIdRequest) (*Reminder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AckReminder not implemented")
+}
 func (UnimplementedEventsServer) mustEmbedUnimplementedEventsServer() {}
 func (UnimplementedEventsServer) testEmbeddedByValue()                {}
 
@@ -275,6 +476,215 @@ func _Events_GetEventsForWeek_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Events_SearchEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).SearchEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_SearchEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).SearchEvents(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_BatchCreateEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).BatchCreateEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_BatchCreateEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).BatchCreateEvents(ctx, req.(*BatchCreateEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_BatchGetEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchEventIdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).BatchGetEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_BatchGetEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).BatchGetEvents(ctx, req.(*BatchEventIdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_BatchDeleteEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchEventIdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).BatchDeleteEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_BatchDeleteEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).BatchDeleteEvents(ctx, req.(*BatchEventIdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventsServer).WatchEvents(m, &grpc.GenericServerStream[WatchRequest, EventChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Events_WatchEventsServer = grpc.ServerStreamingServer[EventChange]
+
+func _Events_CreateCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NewCalendarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).CreateCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_CreateCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).CreateCalendar(ctx, req.(*NewCalendarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_ListCalendars_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCalendarsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).ListCalendars(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_ListCalendars_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).ListCalendars(ctx, req.(*ListCalendarsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_ShareCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShareCalendarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).ShareCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_ShareCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).ShareCalendar(ctx, req.(*ShareCalendarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_UnshareCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnshareCalendarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).UnshareCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_UnshareCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).UnshareCalendar(ctx, req.(*UnshareCalendarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_ListCalendarGrants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalendarIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).ListCalendarGrants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_ListCalendarGrants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).ListCalendarGrants(ctx, req.(*CalendarIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_SnoozeReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnoozeReminderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).SnoozeReminder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_SnoozeReminder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).SnoozeReminder(ctx, req.(*SnoozeReminderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_AckReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReminderIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).AckReminder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_AckReminder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).AckReminder(ctx, req.(*ReminderIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Events_ServiceDesc is the grpc.ServiceDesc for Events service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -306,7 +716,57 @@ var Events_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetEventsForWeek",
 			Handler:    _Events_GetEventsForWeek_Handler,
 		},
+		{
+			MethodName: "SearchEvents",
+			Handler:    _Events_SearchEvents_Handler,
+		},
+		{
+			MethodName: "BatchCreateEvents",
+			Handler:    _Events_BatchCreateEvents_Handler,
+		},
+		{
+			MethodName: "BatchGetEvents",
+			Handler:    _Events_BatchGetEvents_Handler,
+		},
+		{
+			MethodName: "BatchDeleteEvents",
+			Handler:    _Events_BatchDeleteEvents_Handler,
+		},
+		{
+			MethodName: "CreateCalendar",
+			Handler:    _Events_CreateCalendar_Handler,
+		},
+		{
+			MethodName: "ListCalendars",
+			Handler:    _Events_ListCalendars_Handler,
+		},
+		{
+			MethodName: "ShareCalendar",
+			Handler:    _Events_ShareCalendar_Handler,
+		},
+		{
+			MethodName: "UnshareCalendar",
+			Handler:    _Events_UnshareCalendar_Handler,
+		},
+		{
+			MethodName: "ListCalendarGrants",
+			Handler:    _Events_ListCalendarGrants_Handler,
+		},
+		{
+			MethodName: "SnoozeReminder",
+			Handler:    _Events_SnoozeReminder_Handler,
+		},
+		{
+			MethodName: "AckReminder",
+			Handler:    _Events_AckReminder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Events_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "EventService.proto",
 }
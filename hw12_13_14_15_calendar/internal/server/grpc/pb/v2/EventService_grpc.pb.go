@@ -0,0 +1,394 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.12.4
+// source: v2/EventService.proto
+
+package pbv2
+
+import (
+	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Events_CreateEvent_FullMethodName       = "/calendar.v2.Events/CreateEvent"
+	Events_UpdateEvent_FullMethodName       = "/calendar.v2.Events/UpdateEvent"
+	Events_GetEvent_FullMethodName          = "/calendar.v2.Events/GetEvent"
+	Events_DeleteEvent_FullMethodName       = "/calendar.v2.Events/DeleteEvent"
+	Events_GetEventsForDay_FullMethodName   = "/calendar.v2.Events/GetEventsForDay"
+	Events_GetEventsForWeek_FullMethodName  = "/calendar.v2.Events/GetEventsForWeek"
+	Events_SearchEvents_FullMethodName      = "/calendar.v2.Events/SearchEvents"
+	Events_BatchCreateEvents_FullMethodName = "/calendar.v2.Events/BatchCreateEvents"
+)
+
+// EventsClient is the client API for Events service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Events of v2 carry the times as RFC 3339 timestamps and the offsets as durations, with the time zone
+// the event is planned in. The events are shared with v1, the calendars, the sharing and the watch stay in v1.
+type EventsClient interface {
+	CreateEvent(ctx context.Context, in *NewEventRequest, opts ...grpc.CallOption) (*ScalarEventResponse, error)
+	UpdateEvent(ctx context.Context, in *UpdateEventRequest, opts ...grpc.CallOption) (*ScalarEventResponse, error)
+	GetEvent(ctx context.Context, in *EventIdRequest, opts ...grpc.CallOption) (*ScalarEventResponse, error)
+	DeleteEvent(ctx context.Context, in *EventIdRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	GetEventsForDay(ctx context.Context, in *DateRequest, opts ...grpc.CallOption) (*VectorEventResponse, error)
+	GetEventsForWeek(ctx context.Context, in *DateRequest, opts ...grpc.CallOption) (*VectorEventResponse, error)
+	SearchEvents(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchEventResponse, error)
+	BatchCreateEvents(ctx context.Context, in *BatchCreateEventsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error)
+}
+
+type eventsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventsClient(cc grpc.ClientConnInterface) EventsClient {
+	return &eventsClient{cc}
+}
+
+func (c *eventsClient) CreateEvent(ctx context.Context, in *NewEventRequest, opts ...grpc.CallOption) (*ScalarEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScalarEventResponse)
+	err := c.cc.Invoke(ctx, Events_CreateEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) UpdateEvent(ctx context.Context, in *UpdateEventRequest, opts ...grpc.CallOption) (*ScalarEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScalarEventResponse)
+	err := c.cc.Invoke(ctx, Events_UpdateEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) GetEvent(ctx context.Context, in *EventIdRequest, opts ...grpc.CallOption) (*ScalarEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScalarEventResponse)
+	err := c.cc.Invoke(ctx, Events_GetEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) DeleteEvent(ctx context.Context, in *EventIdRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, Events_DeleteEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) GetEventsForDay(ctx context.Context, in *DateRequest, opts ...grpc.CallOption) (*VectorEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VectorEventResponse)
+	err := c.cc.Invoke(ctx, Events_GetEventsForDay_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) GetEventsForWeek(ctx context.Context, in *DateRequest, opts ...grpc.CallOption) (*VectorEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VectorEventResponse)
+	err := c.cc.Invoke(ctx, Events_GetEventsForWeek_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) SearchEvents(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchEventResponse)
+	err := c.cc.Invoke(ctx, Events_SearchEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventsClient) BatchCreateEvents(ctx context.Context, in *BatchCreateEventsRequest, opts ...grpc.CallOption) (*BatchEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchEventResponse)
+	err := c.cc.Invoke(ctx, Events_BatchCreateEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EventsServer is the server API for Events service.
+// All implementations must embed UnimplementedEventsServer
+// for forward compatibility.
+//
+// Events of v2 carry the times as RFC 3339 timestamps and the offsets as durations, with the time zone
+// the event is planned in. The events are shared with v1, the calendars, the sharing and the watch stay in v1.
+type EventsServer interface {
+	CreateEvent(context.Context, *NewEventRequest) (*ScalarEventResponse, error)
+	UpdateEvent(context.Context, *UpdateEventRequest) (*ScalarEventResponse, error)
+	GetEvent(context.Context, *EventIdRequest) (*ScalarEventResponse, error)
+	DeleteEvent(context.Context, *EventIdRequest) (*empty.Empty, error)
+	GetEventsForDay(context.Context, *DateRequest) (*VectorEventResponse, error)
+	GetEventsForWeek(context.Context, *DateRequest) (*VectorEventResponse, error)
+	SearchEvents(context.Context, *SearchRequest) (*SearchEventResponse, error)
+	BatchCreateEvents(context.Context, *BatchCreateEventsRequest) (*BatchEventResponse, error)
+	mustEmbedUnimplementedEventsServer()
+}
+
+// UnimplementedEventsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEventsServer struct{}
+
+func (UnimplementedEventsServer) CreateEvent(context.Context, *NewEventRequest) (*ScalarEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEvent not implemented")
+}
+func (UnimplementedEventsServer) UpdateEvent(context.Context, *UpdateEventRequest) (*ScalarEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateEvent not implemented")
+}
+func (UnimplementedEventsServer) GetEvent(context.Context, *EventIdRequest) (*ScalarEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEvent not implemented")
+}
+func (UnimplementedEventsServer) DeleteEvent(context.Context, *EventIdRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEvent not implemented")
+}
+func (UnimplementedEventsServer) GetEventsForDay(context.Context, *DateRequest) (*VectorEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEventsForDay not implemented")
+}
+func (UnimplementedEventsServer) GetEventsForWeek(context.Context, *DateRequest) (*VectorEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEventsForWeek not implemented")
+}
+func (UnimplementedEventsServer) SearchEvents(context.Context, *SearchRequest) (*SearchEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchEvents not implemented")
+}
+func (UnimplementedEventsServer) BatchCreateEvents(context.Context, *BatchCreateEventsRequest) (*BatchEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCreateEvents not implemented")
+}
+func (UnimplementedEventsServer) mustEmbedUnimplementedEventsServer() {}
+func (UnimplementedEventsServer) testEmbeddedByValue()                {}
+
+// UnsafeEventsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventsServer will
+// result in compilation errors.
+type UnsafeEventsServer interface {
+	mustEmbedUnimplementedEventsServer()
+}
+
+func RegisterEventsServer(s grpc.ServiceRegistrar, srv EventsServer) {
+	// If the following call pancis, it indicates UnimplementedEventsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Events_ServiceDesc, srv)
+}
+
+func _Events_CreateEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NewEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).CreateEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_CreateEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).CreateEvent(ctx, req.(*NewEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_UpdateEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).UpdateEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_UpdateEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).UpdateEvent(ctx, req.(*UpdateEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).GetEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_GetEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).GetEvent(ctx, req.(*EventIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_DeleteEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).DeleteEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_DeleteEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).DeleteEvent(ctx, req.(*EventIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_GetEventsForDay_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).GetEventsForDay(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_GetEventsForDay_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).GetEventsForDay(ctx, req.(*DateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_GetEventsForWeek_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).GetEventsForWeek(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_GetEventsForWeek_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).GetEventsForWeek(ctx, req.(*DateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_SearchEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).SearchEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_SearchEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).SearchEvents(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Events_BatchCreateEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventsServer).BatchCreateEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Events_BatchCreateEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventsServer).BatchCreateEvents(ctx, req.(*BatchCreateEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Events_ServiceDesc is the grpc.ServiceDesc for Events service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Events_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calendar.v2.Events",
+	HandlerType: (*EventsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEvent",
+			Handler:    _Events_CreateEvent_Handler,
+		},
+		{
+			MethodName: "UpdateEvent",
+			Handler:    _Events_UpdateEvent_Handler,
+		},
+		{
+			MethodName: "GetEvent",
+			Handler:    _Events_GetEvent_Handler,
+		},
+		{
+			MethodName: "DeleteEvent",
+			Handler:    _Events_DeleteEvent_Handler,
+		},
+		{
+			MethodName: "GetEventsForDay",
+			Handler:    _Events_GetEventsForDay_Handler,
+		},
+		{
+			MethodName: "GetEventsForWeek",
+			Handler:    _Events_GetEventsForWeek_Handler,
+		},
+		{
+			MethodName: "SearchEvents",
+			Handler:    _Events_SearchEvents_Handler,
+		},
+		{
+			MethodName: "BatchCreateEvents",
+			Handler:    _Events_BatchCreateEvents_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v2/EventService.proto",
+}
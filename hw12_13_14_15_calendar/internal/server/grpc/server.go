@@ -2,20 +2,35 @@ package internalgrpc
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/events"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/gateway"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/middleware"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/openapi"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	pbv2 "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb/v2"
 	httpmiddleware "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/http/middleware"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
@@ -24,24 +39,94 @@ type Server struct {
 	httpPort int
 	logger   Logger
 	app      events.Application
+	limiter  RateLimiter
+	tlsConf  TLS
+	auth     Auth
 	server   *grpc.Server
 	gwServer *http.Server
+	gwClient *grpc.ClientConn
+	health   *health.Server
+	ready    atomic.Bool
+
+	// listeners are closed by Stop, Start returning on ctx done leaves them serving until the drain is over
+	listeners []net.Listener
+
+	// streams is canceled by Stop, the open streams like the watchers would keep GracefulStop waiting otherwise
+	streams     context.Context
+	stopStreams context.CancelFunc
 }
 
+// ReadinessPath answers 200 while the server accepts the requests, the gRPC clients use the health service.
+const ReadinessPath = "/readyz"
+
 type Logger interface {
 	Info(msg string, args ...any)
 	Warn(msg string, args ...any)
 	Error(msg string, args ...any)
 	Debug(msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+	DebugContext(ctx context.Context, msg string, args ...any)
+}
+
+type RateLimiter interface {
+	Allow(method, client string) (bool, time.Duration)
+}
+
+// sharedV1Limits applies the limits of the v1 methods to the v2 ones of the same names.
+// The versions share the buckets, so alternating them does not double the calls.
+type sharedV1Limits struct {
+	RateLimiter
+}
+
+func (l sharedV1Limits) Allow(method, client string) (bool, time.Duration) {
+	if name, ok := strings.CutPrefix(method, "/"+pbv2.Events_ServiceDesc.ServiceName+"/"); ok {
+		method = "/" + pb.Events_ServiceDesc.ServiceName + "/" + name
+	}
+	return l.RateLimiter.Allow(method, client)
+}
+
+// TLS secures the gRPC and the HTTP listeners, the loopback configuration connects the gateway to the gRPC server.
+type TLS interface {
+	Config() *tls.Config
+	LoopbackConfig() *tls.Config
 }
 
-func NewServer(host string, grpcPort int, httpPort int, logger Logger, app events.Application) *Server {
+// Auth tells how the callers without client certificates are treated. TrustUserHeader takes their identity
+// from x-user-email, for the proxy authenticating them. AllowAnonymous lets them in without identity,
+// with the full access, for the trusted networks only.
+type Auth struct {
+	TrustUserHeader bool
+	AllowAnonymous  bool
+}
+
+// NewServer creates the gRPC server with the gateway, the calls are not limited if limiter is nil
+// and the listeners are not secured if tlsConf is nil.
+func NewServer(
+	host string,
+	grpcPort int,
+	httpPort int,
+	logger Logger,
+	app events.Application,
+	limiter RateLimiter,
+	tlsConf TLS,
+	auth Auth,
+) *Server {
+	streams, stopStreams := context.WithCancel(context.Background())
 	return &Server{
 		host:     host,
 		grpcPort: grpcPort,
 		httpPort: httpPort,
 		logger:   logger,
 		app:      app,
+		limiter:  limiter,
+		tlsConf:  tlsConf,
+		auth:     auth,
+		health:   health.NewServer(),
+
+		streams:     streams,
+		stopStreams: stopStreams,
 	}
 }
 
@@ -52,50 +137,210 @@ func (s *Server) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	s.listeners = append(s.listeners, lsn)
 
 	httpBindAddr := fmt.Sprintf("%v:%v", s.host, s.httpPort)
 	s.logger.Info(fmt.Sprintf("Starting HTTP on %v...", httpBindAddr))
+	gwLsn, err := net.Listen("tcp", httpBindAddr)
+	if err != nil {
+		return err
+	}
+	s.listeners = append(s.listeners, gwLsn)
 
-	gwClient, err := grpc.NewClient(grpcBindAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	serverCreds, gwCreds := insecure.NewCredentials(), insecure.NewCredentials()
+	if s.tlsConf != nil {
+		serverCreds, gwCreds = credentials.NewTLS(s.tlsConf.Config()), credentials.NewTLS(s.tlsConf.LoopbackConfig())
+	}
+	s.gwClient, err = grpc.NewClient(lsn.Addr().String(), grpc.WithTransportCredentials(gwCreds))
 	if err != nil {
 		return err
 	}
 
-	gwMux := runtime.NewServeMux()
-	err = pb.RegisterEventsHandler(ctx, gwMux, gwClient)
+	gatewayToken, err := newGatewayToken()
+	if err != nil {
+		return err
+	}
+	gwMux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(gateway.EventStreamContentType, gateway.NewSSEMarshaler()),
+		runtime.WithIncomingHeaderMatcher(gateway.IncomingHeaderMatcher),
+		runtime.WithMetadata(gateway.IdentityMetadata(gatewayToken)),
+		runtime.WithOutgoingHeaderMatcher(gateway.OutgoingHeaderMatcher),
+	)
+	err = pb.RegisterEventsHandler(ctx, gwMux, s.gwClient)
+	if err != nil {
+		return err
+	}
+	err = pbv2.RegisterEventsHandler(ctx, gwMux, s.gwClient)
 	if err != nil {
 		return err
 	}
 
-	gwMuxWithLogging := httpmiddleware.NewLoggingMiddleware(s.logger, gwMux)
-	s.gwServer = &http.Server{Addr: httpBindAddr, Handler: gwMuxWithLogging, ReadTimeout: time.Second * 10}
-	go func() {
-		if err := s.gwServer.ListenAndServe(); err != http.ErrServerClosed {
-			panic(err)
-		}
-	}()
+	gwMuxWithLogging := httpmiddleware.NewRequestIDMiddleware(
+		httpmiddleware.NewIdentityMiddleware(s.auth.TrustUserHeader,
+			httpmiddleware.NewLoggingMiddleware(s.logger,
+				httpmiddleware.NewRecoveryMiddleware(s.logger,
+					gateway.NewLegacyPathMiddleware(gateway.NewLastEventIDMiddleware(gwMux)),
+				),
+			),
+		),
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc(ReadinessPath, s.readiness)
+	for path, spec := range openapi.Documents() {
+		mux.Handle(path, openapi.Handler(spec))
+	}
+	mux.Handle("/", gwMuxWithLogging)
+	s.gwServer = &http.Server{Handler: mux, ReadTimeout: time.Second * 10}
+	if s.tlsConf != nil {
+		s.gwServer.TLSConfig = s.tlsConf.Config()
+	}
+
+	identityConf := middleware.IdentityConf{
+		GatewayToken:    gatewayToken,
+		TrustUserHeader: s.auth.TrustUserHeader,
+		AllowAnonymous:  s.auth.AllowAnonymous,
+	}
+	unary := []grpc.UnaryServerInterceptor{
+		middleware.RequestIDUnaryInterceptor(),
+		middleware.IdentityUnaryInterceptor(identityConf),
+		middleware.LogFieldsUnaryInterceptor(),
+		logging.UnaryServerInterceptor(middleware.InterceptorLogger(s.logger)),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		s.endStreamsInterceptor,
+		middleware.RequestIDStreamInterceptor(),
+		middleware.IdentityStreamInterceptor(identityConf),
+		middleware.LogFieldsStreamInterceptor(),
+		logging.StreamServerInterceptor(middleware.InterceptorLogger(s.logger)),
+	}
+	if s.limiter != nil {
+		limiter := sharedV1Limits{s.limiter}
+		unary = append(unary, middleware.RateLimitUnaryInterceptor(limiter))
+		stream = append(stream, middleware.RateLimitStreamInterceptor(limiter))
+	}
+	unary = append(unary, middleware.RecoveryUnaryInterceptor(s.logger))
+	stream = append(stream, middleware.RecoveryStreamInterceptor(s.logger))
 
 	s.server = grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			logging.UnaryServerInterceptor(middleware.InterceptorLogger(s.logger)),
-		),
+		grpc.Creds(serverCreds),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
 	)
 	pb.RegisterEventsServer(s.server, events.NewService(s.logger, s.app))
+	pbv2.RegisterEventsServer(s.server, events.NewV2Service(s.logger, s.app))
+	healthpb.RegisterHealthServer(s.server, s.health)
 	reflection.Register(s.server)
 
+	serveErr := make(chan error, 2)
+	go func() {
+		var err error
+		if s.gwServer.TLSConfig != nil {
+			err = s.gwServer.ServeTLS(gwLsn, "", "")
+		} else {
+			err = s.gwServer.Serve(gwLsn)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("failed to serve HTTP: %w", err)
+		}
+	}()
 	go func() {
+		// Serve returns nil once the server is stopped
 		if err := s.server.Serve(lsn); err != nil {
-			panic(err)
+			serveErr <- fmt.Errorf("failed to serve GRPC: %w", err)
 		}
 	}()
+	s.setReady(true)
 
-	<-ctx.Done()
-	return nil
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-serveErr:
+		return err
+	}
 }
 
+// Stop reports not ready, then lets the calls in flight finish: the gRPC ones first, including the ones
+// of the HTTP requests, while the HTTP server still answers the readiness probes with 503. Then the HTTP
+// server is shut down. The calls still running when ctx is done are cut.
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping adapters...")
+	s.setReady(false)
+
+	var errs []error
+	s.stopStreams()
+	if s.server != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("failed to drain GRPC calls: %w", ctx.Err()))
+			s.server.Stop()
+			<-stopped
+		}
+	}
+	if s.gwServer != nil {
+		if err := s.gwServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to drain HTTP requests: %w", err))
+			s.gwServer.Close()
+		}
+	}
+	if s.gwClient != nil {
+		s.gwClient.Close()
+	}
+	// the servers close the listeners they serve, the ones Start has not served yet are closed here
+	for _, lsn := range s.listeners {
+		lsn.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// endStreamsInterceptor ends the stream once Stop is called, the caller is told to resume elsewhere or later.
+func (s *Server) endStreamsInterceptor(
+	srv any,
+	stream grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	defer context.AfterFunc(s.streams, cancel)()
+
+	wrapped := grpcmiddleware.WrapServerStream(stream)
+	wrapped.WrappedContext = ctx
+	err := handler(srv, wrapped)
+	if s.streams.Err() != nil && stream.Context().Err() == nil {
+		return status.Error(codes.Unavailable, "the server is stopping")
+	}
+	return err
+}
+
+// newGatewayToken generates the token the gateway sends to be trusted with the identity it forwards.
+func newGatewayToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
 
-	defer s.server.Stop()
-	return s.gwServer.Shutdown(ctx)
+func (s *Server) setReady(ready bool) {
+	s.ready.Store(ready)
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus("", status)
+}
+
+// readiness answers 503 before the server is started and once it is stopping.
+func (s *Server) readiness(w http.ResponseWriter, _ *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
 }
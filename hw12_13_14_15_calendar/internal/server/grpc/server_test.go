@@ -1,21 +1,47 @@
 package internalgrpc
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	app "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/app/calendar"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/requestid"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/events"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/gateway"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/middleware"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/openapi"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	pbv2 "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/grpc/pb/v2"
+	httpmiddleware "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/server/http/middleware"
 	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const bufSize = 1024 * 1024
@@ -24,12 +50,17 @@ var lis *bufconn.Listener
 
 func init() {
 	lis = bufconn.Listen(bufSize)
-	s := grpc.NewServer()
+	identityConf := middleware.IdentityConf{TrustUserHeader: true, AllowAnonymous: true}
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(middleware.IdentityUnaryInterceptor(identityConf)),
+		grpc.ChainStreamInterceptor(middleware.IdentityStreamInterceptor(identityConf)),
+	)
 	storage := memorystorage.New()
 	logger := logger.New("INFO", "stdout")
 	app := app.New(logger, storage)
 	service := events.NewService(logger, app)
 	pb.RegisterEventsServer(s, service)
+	pbv2.RegisterEventsServer(s, events.NewV2Service(logger, app))
 	go func() {
 		if err := s.Serve(lis); err != nil {
 			log.Fatalf("Server exited with error: %v", err)
@@ -116,3 +147,862 @@ func TestCRUDSuccess(t *testing.T) {
 		require.NoError(t, err)
 	}
 }
+
+func TestPartialUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewEventsClient(conn)
+
+	original := &pb.TransientEvent{
+		Title:       "title 3",
+		StartTime:   time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC).Unix(),
+		EndTime:     time.Date(2024, 1, 3, 12, 30, 0, 0, time.UTC).Unix(),
+		Description: "description 3",
+		OwnerEmail:  "user3@example.com",
+		Notify:      "15m",
+	}
+	resp, err := client.CreateEvent(ctx, &pb.NewEventRequest{Event: original})
+	require.NoError(t, err)
+	id := resp.GetEvent().GetId()
+
+	resp, err = client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+		Id:         id,
+		Event:      &pb.TransientEvent{Title: "updated title"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+	})
+	require.NoError(t, err)
+	event := resp.GetEvent()
+	require.Equal(t, "updated title", event.Title)
+	require.Equal(t, original.StartTime, event.StartTime)
+	require.Equal(t, original.EndTime, event.EndTime)
+	require.Equal(t, original.Description, event.Description)
+	require.Equal(t, original.OwnerEmail, event.OwnerEmail)
+	require.Equal(t, original.Notify, event.Notify)
+
+	_, err = client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+		Id:         id,
+		Event:      &pb.TransientEvent{Title: "updated title"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"unknown"}},
+	})
+	require.Error(t, err)
+
+	_, err = client.DeleteEvent(ctx, &pb.EventIdRequest{Id: id})
+	require.NoError(t, err)
+}
+
+func TestV2Events(t *testing.T) {
+	ctx := context.Background()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pbv2.NewEventsClient(conn)
+	v1Client := pb.NewEventsClient(conn)
+
+	// late in the evening in New York is the next day in UTC
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	start := time.Date(2024, 11, 25, 23, 0, 0, 250_000_000, newYork)
+	original := &pbv2.TransientEvent{
+		Title:        "v2 title",
+		StartTime:    timestamppb.New(start),
+		EndTime:      timestamppb.New(start.Add(45 * time.Minute)),
+		TimeZone:     "America/New_York",
+		OwnerEmail:   "v2@example.com",
+		NotifyBefore: durationpb.New(15 * time.Minute),
+		Reminders:    []*pbv2.Reminder{{Before: durationpb.New(time.Hour), Channel: pbv2.Reminder_EMAIL}},
+	}
+	resp, err := client.CreateEvent(ctx, &pbv2.NewEventRequest{Event: original})
+	require.NoError(t, err)
+	event := resp.GetEvent()
+	id := event.GetId()
+	require.True(t, start.Equal(event.GetStartTime().AsTime()))
+	require.True(t, start.Add(45*time.Minute).Equal(event.GetEndTime().AsTime()))
+	require.Equal(t, "America/New_York", event.GetTimeZone())
+	require.Equal(t, 15*time.Minute, event.GetNotifyBefore().AsDuration())
+	// like in v1 the reminders include the one of notify_before
+	require.Len(t, event.GetReminders(), 2)
+	for _, r := range event.GetReminders() {
+		require.True(t, start.Add(-r.GetBefore().AsDuration()).Equal(r.GetNotifyTime().AsTime()))
+	}
+
+	t.Run("day in the time zone", func(t *testing.T) {
+		date := timestamppb.New(time.Date(2024, 11, 25, 12, 0, 0, 0, time.UTC))
+		day, err := client.GetEventsForDay(ctx, &pbv2.DateRequest{
+			Owner: "v2@example.com", Date: date, TimeZone: "America/New_York",
+		})
+		require.NoError(t, err)
+		require.Len(t, day.GetEvents(), 1)
+		require.Equal(t, id, day.GetEvents()[0].GetId())
+
+		day, err = client.GetEventsForDay(ctx, &pbv2.DateRequest{Owner: "v2@example.com", Date: date})
+		require.NoError(t, err)
+		require.Empty(t, day.GetEvents())
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			event *pbv2.TransientEvent
+		}{
+			{name: "unknown time zone", event: &pbv2.TransientEvent{
+				Title: "t", StartTime: original.StartTime, EndTime: original.EndTime,
+				TimeZone: "Mars/Olympus_Mons", OwnerEmail: "v2@example.com",
+			}},
+			{name: "next day in the time zone", event: &pbv2.TransientEvent{
+				Title: "t", StartTime: original.StartTime, EndTime: timestamppb.New(start.Add(90 * time.Minute)),
+				TimeZone: "America/New_York", OwnerEmail: "v2@example.com",
+			}},
+			{name: "no start", event: &pbv2.TransientEvent{
+				Title: "t", EndTime: original.EndTime, OwnerEmail: "v2@example.com",
+			}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := client.CreateEvent(ctx, &pbv2.NewEventRequest{Event: tt.event})
+				require.Equal(t, codes.InvalidArgument, status.Code(err), "%v", err)
+			})
+		}
+
+		_, err := client.GetEventsForDay(ctx, &pbv2.DateRequest{
+			Owner: "v2@example.com", Date: timestamppb.Now(), TimeZone: "Mars/Olympus_Mons",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), "%v", err)
+	})
+
+	t.Run("v1 update keeps the time zone", func(t *testing.T) {
+		_, err := v1Client.UpdateEvent(ctx, &pb.UpdateEventRequest{
+			Id: id,
+			Event: &pb.TransientEvent{
+				Title:      "v1 title",
+				StartTime:  start.Unix(),
+				EndTime:    start.Add(30 * time.Minute).Unix(),
+				OwnerEmail: "v2@example.com",
+			},
+		})
+		require.NoError(t, err)
+
+		resp, err := client.GetEvent(ctx, &pbv2.EventIdRequest{Id: id})
+		require.NoError(t, err)
+		require.Equal(t, "v1 title", resp.GetEvent().GetTitle())
+		require.Equal(t, "America/New_York", resp.GetEvent().GetTimeZone())
+		require.Nil(t, resp.GetEvent().GetNotifyBefore())
+	})
+
+	t.Run("partial update", func(t *testing.T) {
+		resp, err := client.UpdateEvent(ctx, &pbv2.UpdateEventRequest{
+			Id:         id,
+			Event:      &pbv2.TransientEvent{NotifyBefore: durationpb.New(30 * time.Minute)},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"notify_before"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Minute, resp.GetEvent().GetNotifyBefore().AsDuration())
+		require.Equal(t, "v1 title", resp.GetEvent().GetTitle())
+		require.Equal(t, "America/New_York", resp.GetEvent().GetTimeZone())
+	})
+
+	_, err = client.DeleteEvent(ctx, &pbv2.EventIdRequest{Id: id})
+	require.NoError(t, err)
+}
+
+func TestBatchOperations(t *testing.T) {
+	ctx := context.Background()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewEventsClient(conn)
+
+	events := []*pb.TransientEvent{
+		{
+			Title:      "batch 1",
+			StartTime:  time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC).Unix(),
+			EndTime:    time.Date(2024, 2, 1, 12, 30, 0, 0, time.UTC).Unix(),
+			OwnerEmail: "batch@example.com",
+		},
+		{
+			Title:      "",
+			StartTime:  time.Date(2024, 2, 1, 14, 0, 0, 0, time.UTC).Unix(),
+			EndTime:    time.Date(2024, 2, 1, 14, 30, 0, 0, time.UTC).Unix(),
+			OwnerEmail: "batch@example.com",
+		},
+	}
+
+	_, err = client.BatchCreateEvents(ctx, &pb.BatchCreateEventsRequest{Events: events, Atomic: true})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := client.BatchCreateEvents(ctx, &pb.BatchCreateEventsRequest{Events: events})
+	require.NoError(t, err)
+	require.Len(t, resp.GetResults(), 2)
+	require.Nil(t, resp.GetResults()[0].GetError())
+	require.Equal(t, "batch 1", resp.GetResults()[0].GetEvent().GetTitle())
+	require.Equal(t, int32(codes.InvalidArgument), resp.GetResults()[1].GetError().GetCode())
+	id := resp.GetResults()[0].GetEvent().GetId()
+
+	resp, err = client.BatchGetEvents(ctx, &pb.BatchEventIdsRequest{Ids: []string{id, "missing"}})
+	require.NoError(t, err)
+	require.Equal(t, id, resp.GetResults()[0].GetEvent().GetId())
+	require.Equal(t, int32(codes.NotFound), resp.GetResults()[1].GetError().GetCode())
+
+	resp, err = client.BatchDeleteEvents(ctx, &pb.BatchEventIdsRequest{Ids: []string{id}, Atomic: true})
+	require.NoError(t, err)
+	require.Equal(t, id, resp.GetResults()[0].GetId())
+	require.Nil(t, resp.GetResults()[0].GetError())
+}
+
+func TestWatchEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewEventsClient(conn)
+
+	stream, err := client.WatchEvents(ctx, &pb.WatchRequest{Owner: "watch@example.com"})
+	require.NoError(t, err)
+	// the subscription is established asynchronously, so keep creating events until one is received
+	received := make(chan *pb.EventChange, 1)
+	go func() {
+		change, err := stream.Recv()
+		if err == nil {
+			received <- change
+		}
+	}()
+
+	var change *pb.EventChange
+	for day := 1; change == nil && day < 28; day++ {
+		_, err = client.CreateEvent(ctx, &pb.NewEventRequest{Event: &pb.TransientEvent{
+			Title:      "watched",
+			StartTime:  time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC).Unix(),
+			EndTime:    time.Date(2024, 3, day, 12, 30, 0, 0, time.UTC).Unix(),
+			OwnerEmail: "watch@example.com",
+		}})
+		require.NoError(t, err)
+
+		select {
+		case change = <-received:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	require.NotNil(t, change)
+	require.Equal(t, pb.EventChange_CREATED, change.GetType())
+	require.Equal(t, "watched", change.GetEvent().GetTitle())
+	require.NotEmpty(t, change.GetRevisionToken())
+
+	invalid, err := client.WatchEvents(ctx, &pb.WatchRequest{Owner: "watch@example.com", RevisionToken: "abc"})
+	require.NoError(t, err)
+	_, err = invalid.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGatewayServerSentEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	gwMux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(gateway.EventStreamContentType, gateway.NewSSEMarshaler()),
+		runtime.WithIncomingHeaderMatcher(gateway.IncomingHeaderMatcher),
+	)
+	err = pb.RegisterEventsHandlerClient(ctx, gwMux, pb.NewEventsClient(conn))
+	require.NoError(t, err)
+	// the access log middleware must keep the stream flushing
+	l := logger.New("INFO", "stdout")
+	ts := httptest.NewServer(httpmiddleware.NewRequestIDMiddleware(
+		httpmiddleware.NewLoggingMiddleware(l,
+			httpmiddleware.NewRecoveryMiddleware(l, gateway.NewLastEventIDMiddleware(gwMux)),
+		),
+	))
+	defer ts.Close()
+
+	client := pb.NewEventsClient(conn)
+	resp, err := client.CreateEvent(ctx, &pb.NewEventRequest{Event: &pb.TransientEvent{
+		Title:      "sse",
+		StartTime:  time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC).Unix(),
+		EndTime:    time.Date(2024, 4, 1, 12, 30, 0, 0, time.UTC).Unix(),
+		OwnerEmail: "sse@example.com",
+	}})
+	require.NoError(t, err)
+
+	// resuming from revision 1 replays the creation above
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/events:watch?owner=sse@example.com", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", gateway.EventStreamContentType)
+	req.Header.Set("Last-Event-ID", "1")
+	req.Header.Set(requestid.Header, "sse-request")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, gateway.EventStreamContentType, res.Header.Get("Content-Type"))
+	require.Equal(t, "sse-request", res.Header.Get(requestid.Header))
+
+	reader := bufio.NewReader(res.Body)
+	var id, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NotEmpty(t, id)
+	require.Contains(t, data, resp.GetEvent().GetId())
+	require.Contains(t, data, "CREATED")
+}
+
+func TestSharedCalendarAccess(t *testing.T) {
+	ctx := context.Background()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewEventsClient(conn)
+	owner := metadata.AppendToOutgoingContext(ctx, "x-user-email", "team-lead@example.com")
+	member := metadata.AppendToOutgoingContext(ctx, "x-user-email", "member@example.com")
+
+	calendar, err := client.CreateCalendar(owner, &pb.NewCalendarRequest{Name: "team"})
+	require.NoError(t, err)
+	require.Equal(t, "team-lead@example.com", calendar.GetOwnerEmail())
+
+	newEvent := &pb.NewEventRequest{Event: &pb.TransientEvent{
+		Title:      "standup",
+		StartTime:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).Unix(),
+		EndTime:    time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC).Unix(),
+		CalendarId: calendar.GetId(),
+	}}
+	_, err = client.CreateEvent(member, newEvent)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.ShareCalendar(member, &pb.ShareCalendarRequest{
+		CalendarId: calendar.GetId(), Email: "member@example.com", Role: pb.CalendarGrant_EDITOR,
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.ShareCalendar(owner, &pb.ShareCalendarRequest{
+		CalendarId: calendar.GetId(), Email: "member@example.com", Role: pb.CalendarGrant_EDITOR,
+	})
+	require.NoError(t, err)
+
+	created, err := client.CreateEvent(member, newEvent)
+	require.NoError(t, err)
+	require.Equal(t, "team-lead@example.com", created.GetEvent().GetOwnerEmail())
+	require.Equal(t, calendar.GetId(), created.GetEvent().GetCalendarId())
+
+	events, err := client.GetEventsForDay(member, &pb.DateRequest{
+		CalendarId: calendar.GetId(),
+		Date:       time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Unix(),
+	})
+	require.NoError(t, err)
+	require.Len(t, events.GetEvents(), 1)
+
+	grants, err := client.ListCalendarGrants(owner, &pb.CalendarIdRequest{CalendarId: calendar.GetId()})
+	require.NoError(t, err)
+	require.Len(t, grants.GetGrants(), 1)
+	require.Equal(t, pb.CalendarGrant_EDITOR, grants.GetGrants()[0].GetRole())
+}
+
+func TestReminderActions(t *testing.T) {
+	ctx := context.Background()
+
+	//nolint:staticcheck
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewEventsClient(conn)
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	resp, err := client.CreateEvent(ctx, &pb.NewEventRequest{Event: &pb.TransientEvent{
+		Title:      "standup",
+		StartTime:  start.Unix(),
+		EndTime:    start.Add(15 * time.Minute).Unix(),
+		OwnerEmail: "reminders@example.com",
+		Reminders:  []*pb.Reminder{{Before: "30m", Channel: pb.Reminder_EMAIL}},
+	}})
+	require.NoError(t, err)
+	event := resp.GetEvent()
+	require.Len(t, event.Reminders, 1)
+	reminderID := event.Reminders[0].Id
+
+	_, err = client.SnoozeReminder(ctx, &pb.SnoozeReminderRequest{
+		EventId: event.Id, ReminderId: reminderID, Duration: "48h",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.AckReminder(ctx, &pb.ReminderIdRequest{EventId: event.Id, ReminderId: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	snoozed, err := client.SnoozeReminder(ctx, &pb.SnoozeReminderRequest{
+		EventId: event.Id, ReminderId: reminderID, Duration: "10m",
+	})
+	require.NoError(t, err)
+	require.Equal(t, reminderID, snoozed.Id)
+	require.InDelta(t, time.Now().Add(10*time.Minute).Unix(), snoozed.SnoozedUntil, 1)
+	require.False(t, snoozed.Notified)
+
+	acked, err := client.AckReminder(ctx, &pb.ReminderIdRequest{EventId: event.Id, ReminderId: reminderID})
+	require.NoError(t, err)
+	require.True(t, acked.Notified)
+
+	got, err := client.GetEvent(ctx, &pb.EventIdRequest{Id: event.Id})
+	require.NoError(t, err)
+	require.True(t, got.GetEvent().Reminders[0].Notified)
+	require.Equal(t, snoozed.SnoozedUntil, got.GetEvent().Reminders[0].SnoozedUntil)
+}
+
+// blockingApp holds GetEvent until it is released or the call is canceled.
+type blockingApp struct {
+	events.Application
+	started chan struct{}
+	release chan struct{}
+}
+
+func (a blockingApp) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
+	a.started <- struct{}{}
+	select {
+	case <-a.release:
+		return model.Event{ID: eventID}, nil
+	case <-ctx.Done():
+		return model.Event{}, ctx.Err()
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lsn.Close()
+	return lsn.Addr().(*net.TCPAddr).Port
+}
+
+func waitReady(t *testing.T, httpPort int) {
+	t.Helper()
+	waitReadiness(t, httpPort, http.StatusOK)
+}
+
+// waitReadiness waits for the readiness probe to answer with the status.
+func waitReadiness(t *testing.T, httpPort int, status int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		url := fmt.Sprintf("http://127.0.0.1:%d%v", httpPort, ReadinessPath)
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == status
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServerGracefulStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		release bool
+		code    codes.Code
+	}{
+		{name: "call finishes while draining", release: true, code: codes.OK},
+		{name: "call is cut at the deadline", code: codes.Unavailable},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			blocking := blockingApp{
+				Application: app.New(logger.New("ERROR", "stdout"), memorystorage.New()),
+				started:     make(chan struct{}, 1),
+				release:     make(chan struct{}),
+			}
+			grpcPort, httpPort := freePort(t), freePort(t)
+			server := NewServer("127.0.0.1", grpcPort, httpPort, logger.New("ERROR", "stdout"), blocking, nil, nil,
+				Auth{AllowAnonymous: true})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			started := make(chan error, 1)
+			go func() { started <- server.Start(ctx) }()
+
+			waitReady(t, httpPort)
+
+			conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", grpcPort),
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+			called := make(chan error, 1)
+			go func() {
+				_, err := pb.NewEventsClient(conn).GetEvent(context.Background(), &pb.EventIdRequest{Id: "1"})
+				called <- err
+			}()
+			<-blocking.started
+
+			cancel()
+			require.NoError(t, <-started)
+			waitReadiness(t, httpPort, http.StatusOK)
+
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer stopCancel()
+			stopped := make(chan error, 1)
+			go func() { stopped <- server.Stop(stopCtx) }()
+
+			waitReadiness(t, httpPort, http.StatusServiceUnavailable)
+			check, err := server.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+			require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check.GetStatus())
+
+			if tc.release {
+				close(blocking.release)
+				require.NoError(t, <-stopped)
+			} else {
+				require.ErrorContains(t, <-stopped, "failed to drain GRPC calls")
+			}
+			require.Equal(t, tc.code, status.Code(<-called))
+		})
+	}
+}
+
+func TestServerStopEndsWatchers(t *testing.T) {
+	l := logger.New("ERROR", "stdout")
+	grpcPort, httpPort := freePort(t), freePort(t)
+	server := NewServer("127.0.0.1", grpcPort, httpPort, l, app.New(l, memorystorage.New()), nil, nil,
+		Auth{AllowAnonymous: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- server.Start(ctx) }()
+	waitReady(t, httpPort)
+
+	conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", grpcPort),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewEventsClient(conn)
+	for day := 1; day <= 2; day++ {
+		_, err = client.CreateEvent(context.Background(), &pb.NewEventRequest{Event: &pb.TransientEvent{
+			Title:      "watched",
+			StartTime:  time.Date(2024, 4, day, 12, 0, 0, 0, time.UTC).Unix(),
+			EndTime:    time.Date(2024, 4, day, 12, 30, 0, 0, time.UTC).Unix(),
+			OwnerEmail: "watch@example.com",
+		}})
+		require.NoError(t, err)
+	}
+
+	// both watchers resume from revision 1, so they are open once the second creation is received
+	watcher, err := client.WatchEvents(context.Background(), &pb.WatchRequest{
+		Owner: "watch@example.com", RevisionToken: "1",
+	})
+	require.NoError(t, err)
+	_, err = watcher.Recv()
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		fmt.Sprintf("http://127.0.0.1:%d/api/v1/events:watch?owner=watch@example.com", httpPort), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", gateway.EventStreamContentType)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	reader := bufio.NewReader(res.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			break
+		}
+	}
+
+	cancel()
+	require.NoError(t, <-started)
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer stopCancel()
+	require.NoError(t, server.Stop(stopCtx), "the watchers do not hold the drain")
+
+	_, err = watcher.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err, "the event stream is ended")
+}
+
+func TestServerServeError(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	server := NewServer("127.0.0.1", freePort(t), busy.Addr().(*net.TCPAddr).Port, logger.New("ERROR", "stdout"),
+		app.New(logger.New("ERROR", "stdout"), memorystorage.New()), nil, nil, Auth{})
+	err = server.Start(context.Background())
+	require.ErrorContains(t, err, "address already in use")
+	require.NoError(t, server.Stop(context.Background()))
+}
+
+func TestGatewayIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    Auth
+		headers map[string]string
+		status  int
+	}{
+		{
+			name:    "header not trusted",
+			headers: map[string]string{"X-User-Email": "caller@example.com"},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "metadata header not trusted",
+			headers: map[string]string{"Grpc-Metadata-X-User-Email": "caller@example.com"},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name: "gateway token of caller",
+			auth: Auth{TrustUserHeader: true},
+			headers: map[string]string{
+				"Grpc-Metadata-X-Gateway-Token": "guess",
+				"Grpc-Metadata-X-User-Email":    "caller@example.com",
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:    "trusted header",
+			auth:    Auth{TrustUserHeader: true},
+			headers: map[string]string{"X-User-Email": "caller@example.com"},
+			status:  http.StatusOK,
+		},
+		{name: "anonymous", status: http.StatusUnauthorized},
+		{name: "anonymous allowed", auth: Auth{AllowAnonymous: true}, status: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			httpPort := freePort(t)
+			l := logger.New("ERROR", "stdout")
+			server := NewServer("127.0.0.1", freePort(t), httpPort, l, app.New(l, memorystorage.New()), nil, nil, tc.auth)
+			ctx, cancel := context.WithCancel(context.Background())
+			started := make(chan error, 1)
+			go func() { started <- server.Start(ctx) }()
+			defer func() {
+				cancel()
+				require.NoError(t, <-started)
+				require.NoError(t, server.Stop(context.Background()))
+			}()
+			waitReady(t, httpPort)
+
+			url := fmt.Sprintf("http://127.0.0.1:%d%v/calendars?email=caller@example.com", httpPort, gateway.APIPrefix)
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+			require.NoError(t, err)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
+
+// pathParam matches the path parameters of both the OpenAPI paths and the HTTP rules, {id} and {calendar_id=*}.
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// gatewayRoutes lists the bindings of the proto the gateway is generated from, like "GET /api/v1/events/{}".
+func gatewayRoutes(file protoreflect.FileDescriptor) []string {
+	var routes []string
+	methods := file.Services().ByName("Events").Methods()
+	for i := 0; i < methods.Len(); i++ {
+		rule := proto.GetExtension(methods.Get(i).Options(), annotations.E_Http).(*annotations.HttpRule)
+		for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			var method, path string
+			switch p := r.GetPattern().(type) {
+			case *annotations.HttpRule_Get:
+				method, path = http.MethodGet, p.Get
+			case *annotations.HttpRule_Post:
+				method, path = http.MethodPost, p.Post
+			case *annotations.HttpRule_Put:
+				method, path = http.MethodPut, p.Put
+			case *annotations.HttpRule_Patch:
+				method, path = http.MethodPatch, p.Patch
+			case *annotations.HttpRule_Delete:
+				method, path = http.MethodDelete, p.Delete
+			}
+			routes = append(routes, method+" "+pathParam.ReplaceAllString(path, "{}"))
+		}
+	}
+	return routes
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	httpPort := freePort(t)
+	l := logger.New("ERROR", "stdout")
+	server := NewServer("127.0.0.1", freePort(t), httpPort, l, app.New(l, memorystorage.New()), nil, nil,
+		Auth{TrustUserHeader: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- server.Start(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-started)
+		require.NoError(t, server.Stop(context.Background()))
+	}()
+	waitReady(t, httpPort)
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", httpPort)
+
+	do := func(method, path string) (*http.Response, []byte) {
+		t.Helper()
+		reqCtx, reqCancel := context.WithTimeout(context.Background(), time.Second)
+		defer reqCancel()
+		var body io.Reader
+		if method != http.MethodGet && method != http.MethodDelete {
+			body = strings.NewReader("{}")
+		}
+		req, err := http.NewRequestWithContext(reqCtx, method, baseURL+path, body)
+		require.NoError(t, err)
+		req.Header.Set("x-user-email", "contract@example.com")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	// the gateway answers the paths it has no route for with 404 "Not Found" and the wrong methods with 405
+	routed := func(resp *http.Response, data []byte) bool {
+		var st struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &st)
+		notFound := resp.StatusCode == http.StatusNotFound && st.Message == http.StatusText(http.StatusNotFound)
+		return !notFound && resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented
+	}
+
+	documents := []struct {
+		path   string
+		prefix string
+		file   protoreflect.FileDescriptor
+	}{
+		{path: openapi.Path, prefix: gateway.APIPrefix, file: pb.File_EventService_proto},
+		{path: openapi.V2Path, prefix: "/api/v2", file: pbv2.File_v2_EventService_proto},
+	}
+	for _, doc := range documents {
+		resp, data := do(http.MethodGet, doc.path)
+		require.Equal(t, http.StatusOK, resp.StatusCode, doc.path)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var spec struct {
+			Swagger string                    `json:"swagger"`
+			Paths   map[string]map[string]any `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(data, &spec))
+		require.Equal(t, "2.0", spec.Swagger)
+
+		var documented []string
+		for path, operations := range spec.Paths {
+			require.True(t, strings.HasPrefix(path, doc.prefix+"/"), path)
+			for method := range operations {
+				documented = append(documented, strings.ToUpper(method)+" "+pathParam.ReplaceAllString(path, "{}"))
+			}
+		}
+		require.ElementsMatch(t, gatewayRoutes(doc.file), documented)
+
+		for _, route := range documented {
+			method, path, _ := strings.Cut(route, " ")
+			t.Run(route, func(t *testing.T) {
+				resp, data := do(method, strings.ReplaceAll(path, "{}", "x"))
+				require.True(t, routed(resp, data), "%v: %v %s", route, resp.StatusCode, data)
+			})
+		}
+	}
+
+	t.Run("unknown route", func(t *testing.T) {
+		resp, data := do(http.MethodGet, gateway.APIPrefix+"/nothing")
+		require.False(t, routed(resp, data))
+	})
+
+	t.Run("legacy alias", func(t *testing.T) {
+		resp, data := do(http.MethodGet, "/events/query/day?owner=contract@example.com&date=0")
+		require.Equal(t, http.StatusOK, resp.StatusCode, "%s", data)
+		require.Equal(t, "true", resp.Header.Get("Deprecation"))
+		require.Equal(t, `<`+gateway.APIPrefix+`/events/query/day>; rel="successor-version"`, resp.Header.Get("Link"))
+	})
+}
+
+type methodsLimiter []string
+
+func (l *methodsLimiter) Allow(method, _ string) (bool, time.Duration) {
+	*l = append(*l, method)
+	return true, 0
+}
+
+func TestSharedV1Limits(t *testing.T) {
+	var methods methodsLimiter
+	limiter := sharedV1Limits{&methods}
+	for _, method := range []string{
+		pbv2.Events_CreateEvent_FullMethodName,
+		pb.Events_CreateEvent_FullMethodName,
+		pb.Events_WatchEvents_FullMethodName,
+		healthpb.Health_Check_FullMethodName,
+	} {
+		limiter.Allow(method, "ip:10.0.0.1")
+	}
+
+	require.Equal(t, []string{
+		"/calendar.Events/CreateEvent",
+		"/calendar.Events/CreateEvent",
+		"/calendar.Events/WatchEvents",
+		"/grpc.health.v1.Health/Check",
+	}, []string(methods))
+}
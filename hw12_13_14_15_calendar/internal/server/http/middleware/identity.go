@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+)
+
+// IdentityMiddleware identifies the caller by the verified client certificate, or by the x-user-email header
+// if trustUserHeader is set for the proxy authenticating the callers. The identity is put in the request context.
+type IdentityMiddleware struct {
+	trustUserHeader bool
+	handler         http.Handler
+}
+
+func NewIdentityMiddleware(trustUserHeader bool, handlerToWrap http.Handler) *IdentityMiddleware {
+	return &IdentityMiddleware{trustUserHeader: trustUserHeader, handler: handlerToWrap}
+}
+
+func (m *IdentityMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	email, ok := identity.FromTLS(r.TLS)
+	if !ok && m.trustUserHeader {
+		email = r.Header.Get(identity.MetadataKey)
+	}
+	if email != "" {
+		r = r.WithContext(identity.WithUser(r.Context(), email))
+	}
+	m.handler.ServeHTTP(w, r)
+}
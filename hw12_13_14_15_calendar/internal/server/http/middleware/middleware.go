@@ -1,11 +1,16 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
 )
 
+const TraceparentHeader = "Traceparent"
+
+// LoggingMiddleware writes the access log line of every request once it is served.
 type LoggingMiddleware struct {
 	logger  Logger
 	handler http.Handler
@@ -18,14 +23,23 @@ func NewLoggingMiddleware(logger Logger, handlerToWrap http.Handler) *LoggingMid
 func (m *LoggingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
-	m.handler.ServeHTTP(w, r)
+	ctx := logger.WithTraceID(r.Context(), logger.TraceIDFromTraceparent(r.Header.Get(TraceparentHeader)))
+	email, _ := identity.UserFromContext(ctx)
+	ctx = logger.WithOwner(ctx, email)
+	r = r.WithContext(ctx)
+
+	rec := record(w)
+	m.handler.ServeHTTP(rec, r)
 
 	params := []any{
 		"proto", r.Proto,
 		"method", r.Method,
 		"path", r.URL.Path,
+		"status", rec.statusCode(),
+		"bytes", rec.bytes,
+		"duration", time.Since(start).String(),
 		"user-agent", r.UserAgent(),
 		"ip", r.RemoteAddr,
 	}
-	m.logger.Info(fmt.Sprintf("Processed request in %v", time.Since(start)), params...)
+	m.logger.InfoContext(ctx, "Processed request", params...)
 }
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/requestid"
+	"github.com/stretchr/testify/require"
+)
+
+func readLog(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := make([]map[string]any, 0)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	var forwarded string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(requestid.Header)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		requestID string
+		status    int
+		bytes     float64
+	}{
+		{name: "generated id", path: "/hello", status: http.StatusCreated, bytes: 5},
+		{name: "propagated id", path: "/hello", requestID: "req-42", status: http.StatusCreated, bytes: 5},
+		{name: "invalid id", path: "/hello", requestID: "bad id", status: http.StatusCreated, bytes: 5},
+		{name: "panic", path: "/panic", requestID: "req-43", status: http.StatusInternalServerError, bytes: 22},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "access.log")
+			l := logger.New("INFO", output)
+			defer l.Close()
+			handler := NewRequestIDMiddleware(NewLoggingMiddleware(l, NewRecoveryMiddleware(l, mux)))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.requestID != "" {
+				req.Header.Set(requestid.Header, tc.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			require.Equal(t, tc.status, recorder.Code)
+			id := recorder.Header().Get(requestid.Header)
+			require.True(t, requestid.Valid(id))
+			if requestid.Valid(tc.requestID) {
+				require.Equal(t, tc.requestID, id)
+			} else {
+				require.NotEqual(t, tc.requestID, id)
+			}
+
+			lines := readLog(t, output)
+			access := lines[len(lines)-1]
+			require.Equal(t, "Processed request", access["msg"])
+			require.Equal(t, float64(tc.status), access["status"])
+			require.Equal(t, tc.bytes, access["bytes"])
+			require.Equal(t, id, access[logger.FieldRequestID])
+
+			if tc.path == "/panic" {
+				require.Len(t, lines, 2)
+				require.Equal(t, "boom", lines[0]["panic"])
+				require.Contains(t, lines[0]["stack"], "recovery.go")
+				require.Equal(t, id, lines[0][logger.FieldRequestID])
+			} else {
+				require.Equal(t, id, forwarded, "the id is forwarded to the gateway")
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoveryMiddleware answers 500 to the request the handler panicked on, the panic is logged with the stack.
+type RecoveryMiddleware struct {
+	logger  Logger
+	handler http.Handler
+}
+
+func NewRecoveryMiddleware(logger Logger, handlerToWrap http.Handler) *RecoveryMiddleware {
+	return &RecoveryMiddleware{logger: logger, handler: handlerToWrap}
+}
+
+func (m *RecoveryMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := record(w)
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+			// the server aborts the response on purpose, it does not log it either
+			panic(p)
+		}
+
+		m.logger.ErrorContext(r.Context(), "Panic recovered", "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+		if !rec.written() {
+			http.Error(rec, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}()
+
+	m.handler.ServeHTTP(rec, r)
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/requestid"
+)
+
+// RequestIDMiddleware reuses the X-Request-ID of the caller or generates one. The id is echoed in the response,
+// attached to the lines logged for the request and left in the request header for the gateway to forward.
+type RequestIDMiddleware struct {
+	handler http.Handler
+}
+
+func NewRequestIDMiddleware(handlerToWrap http.Handler) *RequestIDMiddleware {
+	return &RequestIDMiddleware{handler: handlerToWrap}
+}
+
+func (m *RequestIDMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := requestid.Ensure(r.Header.Get(requestid.Header))
+	r.Header.Set(requestid.Header, id)
+	w.Header().Set(requestid.Header, id)
+
+	m.handler.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+}
@@ -2,6 +2,7 @@ package internalhttp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -23,6 +24,10 @@ type Logger interface {
 	Warn(msg string, args ...any)
 	Error(msg string, args ...any)
 	Debug(msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+	DebugContext(ctx context.Context, msg string, args ...any)
 }
 
 const ReadTimeout = 10
@@ -41,18 +46,26 @@ func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/hello", helloHandler.GetHello)
 
-	muxWithLogging := middleware.NewLoggingMiddleware(s.logger, mux)
+	handler := middleware.NewRecoveryMiddleware(s.logger, mux)
+	muxWithLogging := middleware.NewRequestIDMiddleware(
+		middleware.NewIdentityMiddleware(false, middleware.NewLoggingMiddleware(s.logger, handler)),
+	)
 
 	s.httpServer = &http.Server{Addr: bindAddr, Handler: muxWithLogging, ReadTimeout: time.Second * ReadTimeout}
 
+	serveErr := make(chan error, 1)
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != http.ErrServerClosed {
-			panic(err)
+		if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
 		}
 	}()
 
-	<-ctx.Done()
-	return nil
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-serveErr:
+		return err
+	}
 }
 
 func (s *Server) Stop(ctx context.Context) error {
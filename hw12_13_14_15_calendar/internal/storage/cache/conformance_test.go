@@ -0,0 +1,24 @@
+package cachestorage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	cachestorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/cache"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Control {
+		t.Helper()
+
+		st := cachestorage.New(memorystorage.New(), 100, time.Minute)
+		require.NoError(t, st.Connect(context.Background()))
+		t.Cleanup(func() { st.Close(context.Background()) })
+		return st
+	})
+}
@@ -0,0 +1,283 @@
+// Package cachestorage caches the event lookups of another storage.
+package cachestorage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/lrucache"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+// maxWindowDays limits the periods assembled from the cached days, the longer ones are read through.
+const maxWindowDays = 31
+
+const dayLayout = "2006-01-02"
+
+// Storage reads the events by id and the events of an owner by UTC day through the cache.
+// The writes made through Storage invalidate the entries they affect,
+// the writes of the other processes are seen once the entries expire.
+type Storage struct {
+	storage.Control
+	cache *lrucache.Cache
+	// mu orders the invalidations with the reads storing their results,
+	// a read started before an invalidation does not store what it read
+	mu      sync.Mutex
+	version uint64
+}
+
+type txKey struct{}
+
+// transaction collects the invalidations to repeat once the transaction is over,
+// the reads in between may see the state before the commit.
+type transaction struct {
+	keys  []lrucache.Key
+	clear bool
+}
+
+func New(inner storage.Control, capacity int, ttl time.Duration) *Storage {
+	return &Storage{
+		Control: inner,
+		cache:   lrucache.New(capacity, ttl),
+	}
+}
+
+func (s *Storage) Stats() lrucache.Stats {
+	return s.cache.Stats()
+}
+
+// Handler exposes the cache statistics as JSON.
+func (s *Storage) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.Stats()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s *Storage) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return s.Control.InTransaction(ctx, fn)
+	}
+
+	tx := &transaction{}
+	err := s.Control.InTransaction(ctx, func(ctx context.Context) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+
+	if tx.clear {
+		s.clear(ctx)
+	} else {
+		s.invalidate(ctx, tx.keys...)
+	}
+	return err
+}
+
+func (s *Storage) Truncate(ctx context.Context) error {
+	defer s.clear(ctx)
+	return s.Control.Truncate(ctx)
+}
+
+func (s *Storage) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
+	if ctx.Value(txKey{}) != nil {
+		return s.Control.GetEvent(ctx, eventID)
+	}
+
+	key := eventKey(eventID)
+	if value, ok := s.cache.Get(key); ok {
+		return copyEvent(value.(model.Event)), nil
+	}
+
+	version := s.currentVersion()
+	event, err := s.Control.GetEvent(ctx, eventID)
+	if err != nil {
+		return model.Event{}, err
+	}
+	s.store(version, key, copyEvent(event))
+	return event, nil
+}
+
+// ListOwnerEventsForPeriod assembles the period from the days it covers.
+func (s *Storage) ListOwnerEventsForPeriod(
+	ctx context.Context,
+	ownerEmail string,
+	startDate,
+	endDate time.Time,
+) ([]model.Event, error) {
+	first := day(startDate)
+	if ctx.Value(txKey{}) != nil || endDate.Before(startDate) || endDate.Sub(first) > maxWindowDays*24*time.Hour {
+		return s.Control.ListOwnerEventsForPeriod(ctx, ownerEmail, startDate, endDate)
+	}
+
+	result := make([]model.Event, 0)
+	for date := first; date.Before(endDate); date = date.AddDate(0, 0, 1) {
+		events, err := s.dayEvents(ctx, ownerEmail, date)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range events {
+			if !ev.StartTime.Before(startDate) && ev.StartTime.Before(endDate) {
+				result = append(result, copyEvent(ev))
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *Storage) dayEvents(ctx context.Context, ownerEmail string, date time.Time) ([]model.Event, error) {
+	key := dayKey(ownerEmail, date)
+	if value, ok := s.cache.Get(key); ok {
+		return value.([]model.Event), nil
+	}
+
+	version := s.currentVersion()
+	events, err := s.Control.ListOwnerEventsForPeriod(ctx, ownerEmail, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	s.store(version, key, events)
+	return events, nil
+}
+
+func (s *Storage) AddEvent(ctx context.Context, event model.Event) error {
+	if err := s.Control.AddEvent(ctx, event); err != nil {
+		return err
+	}
+	s.invalidate(ctx, eventKeys(event)...)
+	return nil
+}
+
+func (s *Storage) UpdateEvent(ctx context.Context, event model.Event) error {
+	existing, err := s.Control.GetEvent(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.Control.UpdateEvent(ctx, event); err != nil {
+		return err
+	}
+	s.invalidate(ctx, append(eventKeys(existing), eventKeys(event)...)...)
+	return nil
+}
+
+func (s *Storage) DeleteEvent(ctx context.Context, eventID string) error {
+	existing, err := s.Control.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if err := s.Control.DeleteEvent(ctx, eventID); err != nil {
+		return err
+	}
+	s.invalidate(ctx, eventKeys(existing)...)
+	return nil
+}
+
+func (s *Storage) ClaimDueReminders(
+	ctx context.Context,
+	since, now, claimUntil time.Time,
+	limit int,
+) ([]model.DueReminder, error) {
+	result, err := s.Control.ClaimDueReminders(ctx, since, now, claimUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]lrucache.Key, 0, 2*len(result))
+	for _, due := range result {
+		keys = append(keys, eventKeys(due.Event)...)
+	}
+	s.invalidate(ctx, keys...)
+	return result, nil
+}
+
+// The writes below do not tell which events they change, the whole cache is dropped.
+
+func (s *Storage) SetReminderNotified(ctx context.Context, reminderID string) error {
+	defer s.clear(ctx)
+	return s.Control.SetReminderNotified(ctx, reminderID)
+}
+
+func (s *Storage) SnoozeReminder(ctx context.Context, reminderID string, until time.Time) error {
+	defer s.clear(ctx)
+	return s.Control.SnoozeReminder(ctx, reminderID, until)
+}
+
+func (s *Storage) DeleteEventsOlderThan(ctx context.Context, time time.Time) error {
+	defer s.clear(ctx)
+	return s.Control.DeleteEventsOlderThan(ctx, time)
+}
+
+func (s *Storage) DeleteEvents(ctx context.Context, eventIDs []string) error {
+	defer s.clear(ctx)
+	return s.Control.DeleteEvents(ctx, eventIDs)
+}
+
+func (s *Storage) currentVersion() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// store caches the value read at version unless it has been invalidated since.
+func (s *Storage) store(version uint64, key lrucache.Key, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.version == version {
+		s.cache.Set(key, value)
+	}
+}
+
+func (s *Storage) invalidate(ctx context.Context, keys ...lrucache.Key) {
+	if tx, ok := ctx.Value(txKey{}).(*transaction); ok {
+		tx.keys = append(tx.keys, keys...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	for _, key := range keys {
+		s.cache.Remove(key)
+	}
+}
+
+func (s *Storage) clear(ctx context.Context) {
+	if tx, ok := ctx.Value(txKey{}).(*transaction); ok {
+		tx.clear = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	s.cache.Clear()
+}
+
+func eventKey(eventID string) lrucache.Key {
+	return lrucache.Key("event:" + eventID)
+}
+
+func dayKey(ownerEmail string, date time.Time) lrucache.Key {
+	return lrucache.Key("day:" + ownerEmail + ":" + date.Format(dayLayout))
+}
+
+func eventKeys(event model.Event) []lrucache.Key {
+	return []lrucache.Key{eventKey(event.ID), dayKey(event.OwnerEmail, day(event.StartTime))}
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// copyEvent keeps the callers from changing the reminders of the cached event.
+func copyEvent(event model.Event) model.Event {
+	event.Reminders = slices.Clone(event.Reminders)
+	return event
+}
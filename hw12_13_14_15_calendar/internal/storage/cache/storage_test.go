@@ -0,0 +1,122 @@
+package cachestorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStorage struct {
+	storage.Control
+	gets  int
+	lists int
+}
+
+func (s *countingStorage) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
+	s.gets++
+	return s.Control.GetEvent(ctx, eventID)
+}
+
+func (s *countingStorage) ListOwnerEventsForPeriod(
+	ctx context.Context,
+	ownerEmail string,
+	startDate,
+	endDate time.Time,
+) ([]model.Event, error) {
+	s.lists++
+	return s.Control.ListOwnerEventsForPeriod(ctx, ownerEmail, startDate, endDate)
+}
+
+func TestStorageReadThrough(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStorage{Control: memorystorage.New()}
+	st := New(inner, 100, time.Minute)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := model.Event{
+		ID: "id1", Title: "meeting", StartTime: start, EndTime: start.Add(time.Hour), OwnerEmail: "a@example.com",
+	}
+	require.NoError(t, st.AddEvent(ctx, event))
+
+	for i := 0; i < 3; i++ {
+		ev, err := st.GetEvent(ctx, "id1")
+		require.NoError(t, err)
+		require.Equal(t, "meeting", ev.Title)
+	}
+	require.Equal(t, 1, inner.gets)
+
+	week, err := st.ListOwnerEventsForPeriod(ctx, "a@example.com", start.Add(-time.Hour), start.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.Len(t, week, 1)
+	require.Equal(t, 8, inner.lists, "the period is read by days")
+	dayEvents, err := st.ListOwnerEventsForPeriod(ctx, "a@example.com", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, dayEvents, 1)
+	require.Equal(t, 8, inner.lists, "the days are reused")
+	dayEvents, err = st.ListOwnerEventsForPeriod(ctx, "a@example.com", start.Add(time.Minute), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, dayEvents, "the cached day is filtered by the period")
+
+	moved := event
+	moved.Title = "moved meeting"
+	moved.StartTime = start.AddDate(0, 0, 1)
+	require.NoError(t, st.UpdateEvent(ctx, moved))
+	ev, err := st.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+	require.Equal(t, "moved meeting", ev.Title)
+	week, err = st.ListOwnerEventsForPeriod(ctx, "a@example.com", start.Add(-time.Hour), start.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.Len(t, week, 1)
+	require.Equal(t, moved.StartTime, week[0].StartTime, "both days of the moved event are invalidated")
+
+	require.NoError(t, st.DeleteEvent(ctx, "id1"))
+	_, err = st.GetEvent(ctx, "id1")
+	require.Error(t, err)
+
+	stats := st.Stats()
+	require.Equal(t, uint64(2+1+1+6), stats.Hits)
+	require.Equal(t, uint64(1+8+1+2+1), stats.Misses)
+}
+
+func TestStorageStaleRead(t *testing.T) {
+	ctx := context.Background()
+	st := New(memorystorage.New(), 100, time.Minute)
+	require.NoError(t, st.AddEvent(ctx, model.Event{ID: "id1", Title: "meeting", OwnerEmail: "a@example.com"}))
+
+	// the read started before the update does not store the old event
+	version := st.currentVersion()
+	stale, err := st.Control.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+	require.NoError(t, st.UpdateEvent(ctx, model.Event{ID: "id1", Title: "renamed", OwnerEmail: "a@example.com"}))
+	st.store(version, eventKey("id1"), stale)
+
+	ev, err := st.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+	require.Equal(t, "renamed", ev.Title)
+}
+
+func TestStorageTransaction(t *testing.T) {
+	ctx := context.Background()
+	st := New(memorystorage.New(), 100, time.Minute)
+	require.NoError(t, st.AddEvent(ctx, model.Event{ID: "id1", Title: "meeting", OwnerEmail: "a@example.com"}))
+	committed, err := st.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+
+	err = st.InTransaction(ctx, func(ctx context.Context) error {
+		if err := st.UpdateEvent(ctx, model.Event{ID: "id1", Title: "renamed", OwnerEmail: "a@example.com"}); err != nil {
+			return err
+		}
+		// the read outside of the transaction stores the committed event again
+		st.store(st.currentVersion(), eventKey("id1"), committed)
+		return nil
+	})
+	require.NoError(t, err)
+
+	ev, err := st.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+	require.Equal(t, "renamed", ev.Title, "the invalidations are repeated after the transaction")
+}
@@ -0,0 +1,124 @@
+package changefeed
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const subscriberBuffer = 64
+
+type subscriber struct {
+	ownerEmail string
+	ch         chan model.Change
+}
+
+// Hub fans out event changes to the subscribers of the event owner.
+// A subscriber that does not keep up is disconnected instead of blocking the publisher.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int]*subscriber
+	nextID      int
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[int]*subscriber)}
+}
+
+func (h *Hub) Subscribe(ownerEmail string) (<-chan model.Change, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextID
+	h.nextID++
+	sub := &subscriber{ownerEmail: ownerEmail, ch: make(chan model.Change, subscriberBuffer)}
+	h.subscribers[id] = sub
+
+	return sub.ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[id]; ok {
+			delete(h.subscribers, id)
+			close(sub.ch)
+		}
+	}
+}
+
+func (h *Hub) Publish(change model.Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, sub := range h.subscribers {
+		if sub.ownerEmail != change.Event.OwnerEmail {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			delete(h.subscribers, id)
+			close(sub.ch)
+		}
+	}
+}
+
+// CloseAll disconnects every subscriber, so they have to resume from their last revision.
+func (h *Hub) CloseAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, sub := range h.subscribers {
+		delete(h.subscribers, id)
+		close(sub.ch)
+	}
+}
+
+// Stream merges the backlog read after subscribing with the live changes skipping duplicates.
+// The returned channel is closed when ctx is done or the live subscription is closed.
+func Stream(
+	ctx context.Context,
+	fromRevision int64,
+	backlog []model.Change,
+	live <-chan model.Change,
+	unsubscribe func(),
+) <-chan model.Change {
+	out := make(chan model.Change)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		last := fromRevision
+		send := func(change model.Change) bool {
+			if change.Revision <= last {
+				return true
+			}
+			select {
+			case out <- change:
+				last = change.Revision
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, change := range backlog {
+			if !send(change) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change, ok := <-live:
+				if !ok || !send(change) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,68 @@
+package changefeed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+func change(revision int64, owner string) model.Change {
+	return model.Change{Revision: revision, Type: model.ChangeCreated, Event: model.Event{OwnerEmail: owner}}
+}
+
+func TestHubPublishByOwner(t *testing.T) {
+	hub := NewHub()
+	ch, unsubscribe := hub.Subscribe("user@example.com")
+	defer unsubscribe()
+
+	hub.Publish(change(1, "user2@example.com"))
+	hub.Publish(change(2, "user@example.com"))
+
+	require.Equal(t, int64(2), (<-ch).Revision)
+	require.Empty(t, ch)
+}
+
+func TestHubDisconnectsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	ch, unsubscribe := hub.Subscribe("user@example.com")
+	defer unsubscribe()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Publish(change(int64(i+1), "user@example.com"))
+	}
+
+	received := 0
+	for range ch {
+		received++
+	}
+	require.Equal(t, subscriberBuffer, received)
+}
+
+func TestStreamSkipsDuplicates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHub()
+	live, unsubscribe := hub.Subscribe("user@example.com")
+	hub.Publish(change(3, "user@example.com"))
+	hub.Publish(change(4, "user@example.com"))
+
+	backlog := []model.Change{change(2, "user@example.com"), change(3, "user@example.com")}
+	out := Stream(ctx, 1, backlog, live, unsubscribe)
+
+	for _, expected := range []int64{2, 3, 4} {
+		select {
+		case c := <-out:
+			require.Equal(t, expected, c.Revision)
+		case <-time.After(time.Second):
+			require.FailNow(t, "change was not received")
+		}
+	}
+
+	hub.CloseAll()
+	_, ok := <-out
+	require.False(t, ok)
+}
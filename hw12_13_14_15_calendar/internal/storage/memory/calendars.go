@@ -0,0 +1,137 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+func (s *Storage) AddCalendar(ctx context.Context, calendar model.Calendar) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.calendars[calendar.ID]; ok {
+		return customerrors.AlreadyExists{Message: fmt.Sprintf("Calendar with id = \"%v\" already exists", calendar.ID)}
+	}
+	return s.write(ctx, walRecord{Op: opPutCalendar, Calendar: &calendar})
+}
+
+func (s *Storage) GetCalendar(ctx context.Context, calendarID string) (model.Calendar, error) {
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	calendar, ok := s.calendars[calendarID]
+	if !ok {
+		return model.Calendar{}, calendarNotFound(calendarID)
+	}
+	return calendar, nil
+}
+
+func (s *Storage) ListCalendars(ctx context.Context, email string) ([]model.Calendar, error) {
+	result := make([]model.Calendar, 0)
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id, calendar := range s.calendars {
+		if _, granted := s.grants[id][email]; granted || calendar.OwnerEmail == email {
+			result = append(result, calendar)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (s *Storage) SetCalendarGrant(ctx context.Context, grant model.Grant) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.calendars[grant.CalendarID]; !ok {
+		return calendarNotFound(grant.CalendarID)
+	}
+	return s.write(ctx, walRecord{Op: opPutGrant, Grant: &grant})
+}
+
+func (s *Storage) GetCalendarGrant(ctx context.Context, calendarID, email string) (model.Grant, error) {
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, ok := s.grants[calendarID][email]
+	if !ok {
+		return model.Grant{}, grantNotFound(calendarID, email)
+	}
+	return model.Grant{CalendarID: calendarID, Email: email, Role: role}, nil
+}
+
+func (s *Storage) ListCalendarGrants(ctx context.Context, calendarID string) ([]model.Grant, error) {
+	result := make([]model.Grant, 0)
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for email, role := range s.grants[calendarID] {
+		result = append(result, model.Grant{CalendarID: calendarID, Email: email, Role: role})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Email < result[j].Email
+	})
+	return result, nil
+}
+
+func (s *Storage) DeleteCalendarGrant(ctx context.Context, calendarID, email string) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.grants[calendarID][email]; !ok {
+		return grantNotFound(calendarID, email)
+	}
+	return s.write(ctx, walRecord{Op: opDeleteGrant, Grant: &model.Grant{CalendarID: calendarID, Email: email}})
+}
+
+func (s *Storage) ListCalendarEventsForPeriod(
+	ctx context.Context,
+	calendarID string,
+	startDate,
+	endDate time.Time,
+) ([]model.Event, error) {
+	result := make([]model.Event, 0)
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, ev := range s.events {
+		if ev.CalendarID != calendarID {
+			continue
+		}
+
+		if !ev.StartTime.Before(startDate) && ev.StartTime.Before(endDate) {
+			result = append(result, *ev)
+		}
+	}
+
+	sortByStartTime(result)
+	return result, nil
+}
+
+func calendarNotFound(calendarID string) error {
+	return customerrors.NotFound{Message: fmt.Sprintf("Calendar with id = \"%v\" not found", calendarID)}
+}
+
+func grantNotFound(calendarID, email string) error {
+	return customerrors.NotFound{
+		Message: fmt.Sprintf("Access to calendar with id = \"%v\" is not granted to %v", calendarID, email),
+	}
+}
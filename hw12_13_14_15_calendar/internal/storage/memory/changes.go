@@ -0,0 +1,90 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/changefeed"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const maxChanges = 1000
+
+// loggedChange is the change with the time it was made, which DeleteChangesOlderThan compares.
+type loggedChange struct {
+	model.Change
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// transaction keeps the records applied within it and the changes published on commit.
+type transaction struct {
+	records []walRecord
+	changes []walRecord
+}
+
+func (s *Storage) WatchChanges(
+	ctx context.Context, ownerEmail string, fromRevision int64,
+) (<-chan model.Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if fromRevision > 0 && fromRevision < s.revision &&
+		(len(s.changes) == 0 || s.changes[0].Revision > fromRevision+1) {
+		return nil, customerrors.OutOfRange{Message: fmt.Sprintf("Revision %d is no longer available", fromRevision)}
+	}
+	if fromRevision > s.revision {
+		return nil, customerrors.OutOfRange{Message: fmt.Sprintf("Revision %d is not reached yet", fromRevision)}
+	}
+
+	backlog := make([]model.Change, 0)
+	if fromRevision > 0 {
+		for _, change := range s.changes {
+			if change.Revision > fromRevision && change.Event.OwnerEmail == ownerEmail {
+				backlog = append(backlog, change.Change)
+			}
+		}
+	}
+
+	live, unsubscribe := s.hub.Subscribe(ownerEmail)
+	return changefeed.Stream(ctx, fromRevision, backlog, live, unsubscribe), nil
+}
+
+// changeRecord logs the change of the event, the revision is assigned on commit.
+func changeRecord(changeType model.ChangeType, event model.Event) walRecord {
+	return walRecord{Op: opChange, Change: &loggedChange{Change: model.Change{Type: changeType, Event: event}}}
+}
+
+// numberChanges assigns the revisions following the last one to the changes of the records.
+func (s *Storage) numberChanges(records []walRecord) {
+	revision := s.revision
+	now := time.Now().UTC()
+	for i, rec := range records {
+		if rec.Op != opChange {
+			continue
+		}
+		revision++
+		change := *rec.Change
+		change.Revision = revision
+		change.ChangedAt = now
+		records[i].Change = &change
+	}
+}
+
+func (s *Storage) appendChange(change loggedChange) {
+	s.changes = append(s.changes, change)
+	if len(s.changes) > maxChanges {
+		s.changes = s.changes[len(s.changes)-maxChanges:]
+	}
+}
+
+// deleteChanges drops the changes up to the revision.
+func (s *Storage) deleteChanges(revision int64) {
+	i := slices.IndexFunc(s.changes, func(change loggedChange) bool { return change.Revision > revision })
+	if i < 0 {
+		i = len(s.changes)
+	}
+	s.changes = slices.Delete(s.changes, 0, i)
+}
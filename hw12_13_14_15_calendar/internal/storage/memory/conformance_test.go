@@ -0,0 +1,35 @@
+package memorystorage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Control {
+		t.Helper()
+
+		st := memorystorage.New()
+		require.NoError(t, st.Connect(context.Background()))
+		t.Cleanup(func() { st.Close(context.Background()) })
+		return st
+	})
+}
+
+func TestConformancePersistent(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Control {
+		t.Helper()
+
+		st := memorystorage.NewPersistent(filepath.Join(t.TempDir(), "calendar.json"), time.Millisecond, true)
+		require.NoError(t, st.Connect(context.Background()))
+		t.Cleanup(func() { st.Close(context.Background()) })
+		return st
+	})
+}
@@ -0,0 +1,363 @@
+package memorystorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const (
+	opTruncate      = "truncate"
+	opPutEvent      = "putEvent"
+	opDeleteEvent   = "deleteEvent"
+	opArchiveEvent  = "archiveEvent"
+	opPutCalendar   = "putCalendar"
+	opPutGrant      = "putGrant"
+	opDeleteGrant   = "deleteGrant"
+	opChange        = "change"
+	opDeleteChanges = "deleteChanges"
+)
+
+// snapshot is the state of the storage written to the file.
+type snapshot struct {
+	// Seq is the last record of the write-ahead log covered by the snapshot.
+	Seq       int64            `json:"seq"`
+	Revision  int64            `json:"revision"`
+	Events    []model.Event    `json:"events"`
+	Archive   []model.Event    `json:"archive"`
+	Calendars []model.Calendar `json:"calendars"`
+	Grants    []model.Grant    `json:"grants"`
+	Changes   []loggedChange   `json:"changes"`
+}
+
+// walRecord is a write logged between the snapshots, the records repeat the resulting state, not the calls.
+type walRecord struct {
+	Seq      int64           `json:"seq"`
+	Op       string          `json:"op"`
+	ID       string          `json:"id,omitempty"`
+	Event    *model.Event    `json:"event,omitempty"`
+	Calendar *model.Calendar `json:"calendar,omitempty"`
+	Grant    *model.Grant    `json:"grant,omitempty"`
+	Change   *loggedChange   `json:"change,omitempty"`
+	Revision int64           `json:"revision,omitempty"`
+}
+
+// NewPersistent creates the storage kept in the snapshot file at path.
+// The snapshot is written every snapshotInterval, if it is positive, and on Close.
+// With wal the writes between the snapshots are appended to the log next to the snapshot.
+func NewPersistent(path string, snapshotInterval time.Duration, wal bool) *Storage {
+	s := New()
+	s.path = path
+	s.snapshotInterval = snapshotInterval
+	s.walEnabled = wal
+	return s
+}
+
+func (s *Storage) walPath() string {
+	return s.path + ".wal"
+}
+
+// load restores the snapshot and replays the log written after it.
+func (s *Storage) load() error {
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("cannot read snapshot: %w", err)
+	default:
+		var snap snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return fmt.Errorf("cannot decode snapshot %s: %w", s.path, err)
+		}
+		s.restore(snap)
+	}
+
+	return s.replay()
+}
+
+func (s *Storage) restore(snap snapshot) {
+	s.seq = snap.Seq
+	s.revision = snap.Revision
+	for _, ev := range snap.Events {
+		s.putEvent(ev)
+	}
+	for _, ev := range snap.Archive {
+		s.archive[ev.ID] = ev
+	}
+	for _, calendar := range snap.Calendars {
+		s.calendars[calendar.ID] = calendar
+	}
+	for _, grant := range snap.Grants {
+		s.putGrant(grant)
+	}
+	s.changes = snap.Changes
+}
+
+// replay applies the log records which are not in the snapshot yet.
+// The last record may be cut by a crash, it is dropped.
+func (s *Storage) replay() error {
+	file, err := os.Open(s.walPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open write-ahead log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read write-ahead log: %w", err)
+		}
+
+		var rec walRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("cannot decode write-ahead log record: %w", err)
+		}
+		if rec.Seq <= s.seq {
+			continue
+		}
+		s.apply(rec)
+		s.seq = rec.Seq
+	}
+}
+
+func (s *Storage) apply(rec walRecord) {
+	switch rec.Op {
+	case opTruncate:
+		s.clear()
+	case opPutEvent:
+		if existing, ok := s.events[rec.Event.ID]; ok {
+			s.index.remove(existing)
+		}
+		s.putEvent(*rec.Event)
+	case opDeleteEvent:
+		if existing, ok := s.events[rec.ID]; ok {
+			s.index.remove(existing)
+			delete(s.events, rec.ID)
+		}
+	case opArchiveEvent:
+		s.archive[rec.Event.ID] = *rec.Event
+	case opPutCalendar:
+		s.calendars[rec.Calendar.ID] = *rec.Calendar
+	case opPutGrant:
+		s.putGrant(*rec.Grant)
+	case opDeleteGrant:
+		delete(s.grants[rec.Grant.CalendarID], rec.Grant.Email)
+	case opChange:
+		s.revision = rec.Change.Revision
+		s.appendChange(*rec.Change)
+	case opDeleteChanges:
+		s.deleteChanges(rec.Revision)
+	}
+}
+
+func (s *Storage) putEvent(event model.Event) {
+	s.events[event.ID] = &event
+	s.index.add(&event)
+}
+
+func (s *Storage) putGrant(grant model.Grant) {
+	if s.grants[grant.CalendarID] == nil {
+		s.grants[grant.CalendarID] = make(map[string]model.Role)
+	}
+	s.grants[grant.CalendarID][grant.Email] = grant.Role
+}
+
+// write logs the records and applies them, it must be called with the write lock held.
+// The state and the watchers see the records after the log is synced, a failed write changes nothing.
+// Within a transaction the records are applied at once and logged on commit, the changes are published on commit.
+func (s *Storage) write(ctx context.Context, records ...walRecord) error {
+	if tx, ok := ctx.Value(txKey{}).(*transaction); ok {
+		for _, rec := range records {
+			if rec.Op == opChange {
+				tx.changes = append(tx.changes, rec)
+				continue
+			}
+			s.apply(rec)
+			tx.records = append(tx.records, rec)
+		}
+		return nil
+	}
+
+	s.numberChanges(records)
+	if err := s.log(records); err != nil {
+		return err
+	}
+	s.applyLogged(records)
+	return nil
+}
+
+// applyLogged applies the logged records and publishes their changes.
+func (s *Storage) applyLogged(records []walRecord) {
+	for _, rec := range records {
+		s.apply(rec)
+		if rec.Op == opChange {
+			s.hub.Publish(rec.Change.Change)
+		}
+	}
+}
+
+// log appends the records to the write-ahead log and syncs it, it must be called with the write lock held.
+// A failed write is cut off, so the records logged later do not follow a broken line.
+func (s *Storage) log(records []walRecord) error {
+	if s.wal == nil || len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	seq := s.seq
+	for _, rec := range records {
+		seq++
+		rec.Seq = seq
+		if err := encoder.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	info, err := s.wal.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot write write-ahead log: %w", err)
+	}
+	// the records of a call are written at once, so a crash cuts the last one only
+	if _, err := s.wal.Write(buf.Bytes()); err != nil {
+		return errors.Join(fmt.Errorf("cannot write write-ahead log: %w", err), s.wal.Truncate(info.Size()))
+	}
+	if err := s.wal.Sync(); err != nil {
+		return errors.Join(fmt.Errorf("cannot sync write-ahead log: %w", err), s.wal.Truncate(info.Size()))
+	}
+	s.seq = seq
+	return nil
+}
+
+// runSnapshots writes the snapshots until ctx is done.
+func (s *Storage) runSnapshots(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a failed snapshot is retried on the next tick, the log keeps the writes meanwhile
+			_ = s.saveSnapshot()
+		}
+	}
+}
+
+// saveSnapshot replaces the snapshot file and empties the log the snapshot covers.
+// Writers wait for the snapshot, so it never catches a transaction halfway.
+func (s *Storage) saveSnapshot() error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := json.Marshal(s.snapshot())
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("cannot write snapshot: %w", err)
+	}
+
+	if s.wal == nil {
+		return nil
+	}
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("cannot truncate write-ahead log: %w", err)
+	}
+	_, err = s.wal.Seek(0, io.SeekStart)
+	return err
+}
+
+func (s *Storage) snapshot() snapshot {
+	snap := snapshot{
+		Seq:       s.seq,
+		Revision:  s.revision,
+		Events:    make([]model.Event, 0, len(s.events)),
+		Archive:   make([]model.Event, 0, len(s.archive)),
+		Calendars: make([]model.Calendar, 0, len(s.calendars)),
+		Grants:    make([]model.Grant, 0),
+		Changes:   s.changes,
+	}
+	for _, ev := range s.events {
+		snap.Events = append(snap.Events, *ev)
+	}
+	for _, ev := range s.archive {
+		snap.Archive = append(snap.Archive, ev)
+	}
+	for _, calendar := range s.calendars {
+		snap.Calendars = append(snap.Calendars, calendar)
+	}
+	for calendarID, grants := range s.grants {
+		for email, role := range grants {
+			snap.Grants = append(snap.Grants, model.Grant{CalendarID: calendarID, Email: email, Role: role})
+		}
+	}
+
+	// the stable order keeps the snapshots of the same state identical
+	sortByStartTime(snap.Events)
+	sortByStartTime(snap.Archive)
+	sort.Slice(snap.Calendars, func(i, j int) bool { return snap.Calendars[i].ID < snap.Calendars[j].ID })
+	sort.Slice(snap.Grants, func(i, j int) bool {
+		if snap.Grants[i].CalendarID != snap.Grants[j].CalendarID {
+			return snap.Grants[i].CalendarID < snap.Grants[j].CalendarID
+		}
+		return snap.Grants[i].Email < snap.Grants[j].Email
+	})
+	return snap
+}
+
+// writeFileAtomic writes the data to a temporary file renamed over the target, readers see either version.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// the rename is durable once the directory is synced
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
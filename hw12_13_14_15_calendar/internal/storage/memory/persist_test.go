@@ -0,0 +1,173 @@
+package memorystorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	storage := NewPersistent(path, 0, false)
+	require.NoError(t, storage.Connect(ctx))
+	require.NoError(t, storage.AddCalendar(ctx, model.Calendar{ID: "team", Name: "team", OwnerEmail: "a@example.com"}))
+	require.NoError(t, storage.SetCalendarGrant(ctx, model.Grant{
+		CalendarID: "team", Email: "b@example.com", Role: model.RoleViewer,
+	}))
+	require.NoError(t, storage.AddEvent(ctx, model.Event{
+		ID:         "id1",
+		Title:      "meeting",
+		StartTime:  start,
+		EndTime:    start.Add(time.Hour),
+		OwnerEmail: "a@example.com",
+		CalendarID: "team",
+		Reminders:  []model.Reminder{{ID: "r1", Channel: model.ChannelEmail, NotifyTime: start.Add(-time.Hour)}},
+	}))
+	require.NoError(t, storage.SetReminderNotified(ctx, "r1"))
+	require.NoError(t, storage.Close(ctx))
+
+	restored := NewPersistent(path, 0, false)
+	require.NoError(t, restored.Connect(ctx))
+	defer restored.Close(ctx)
+
+	ev, err := restored.GetEvent(ctx, "id1")
+	require.NoError(t, err)
+	require.Equal(t, "meeting", ev.Title)
+	require.True(t, ev.Reminders[0].Notified)
+	grant, err := restored.GetCalendarGrant(ctx, "team", "b@example.com")
+	require.NoError(t, err)
+	require.Equal(t, model.RoleViewer, grant.Role)
+
+	matches, err := restored.SearchEvents(ctx, "a@example.com", "meeting", start, start, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, matches, 1, "the search index is rebuilt")
+
+	require.NoError(t, restored.AddEvent(ctx, model.Event{ID: "id2", OwnerEmail: "a@example.com"}))
+	changes, err := restored.WatchChanges(ctx, "a@example.com", 1)
+	require.NoError(t, err)
+	change := <-changes
+	require.Equal(t, int64(2), change.Revision, "revisions continue after restart")
+}
+
+func TestStorageWriteAheadLog(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+
+	storage := NewPersistent(path, 0, true)
+	require.NoError(t, storage.Connect(ctx))
+	for _, id := range []string{"id1", "id2", "id3"} {
+		require.NoError(t, storage.AddEvent(ctx, model.Event{ID: id, Title: id, OwnerEmail: "a@example.com"}))
+	}
+	require.NoError(t, storage.DeleteEvent(ctx, "id2"))
+	require.NoError(t, storage.DeleteChangesOlderThan(ctx, time.Now().Add(time.Hour)))
+	err := storage.InTransaction(ctx, func(ctx context.Context) error {
+		if err := storage.AddEvent(ctx, model.Event{ID: "id4", OwnerEmail: "a@example.com"}); err != nil {
+			return err
+		}
+		return context.Canceled
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the crash leaves the last record cut, the storage is not closed
+	wal, err := os.OpenFile(path+".wal", os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = wal.WriteString(`{"seq":100,"op":"deleteEvent","id":"id1"`)
+	require.NoError(t, err)
+	require.NoError(t, wal.Close())
+
+	restored := NewPersistent(path, 0, true)
+	require.NoError(t, restored.Connect(ctx))
+
+	events, err := restored.ListOwnerEventsForPeriod(ctx, "a@example.com", time.Time{}, time.Time{}.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, []string{"id1", "id3"}, []string{events[0].ID, events[1].ID})
+	require.Equal(t, int64(4), restored.revision)
+	require.Empty(t, restored.changes, "the pruning of the changes is replayed")
+
+	require.NoError(t, restored.AddEvent(ctx, model.Event{ID: "id5", OwnerEmail: "a@example.com"}))
+	require.NoError(t, restored.wal.Close())
+
+	// the records covered by the snapshot written on connect are skipped
+	again := NewPersistent(path, 0, true)
+	require.NoError(t, again.Connect(ctx))
+	defer again.Close(ctx)
+	events, err = again.ListOwnerEventsForPeriod(ctx, "a@example.com", time.Time{}, time.Time{}.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, int64(5), again.revision)
+}
+
+func TestStoragePeriodicSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+
+	storage := NewPersistent(path, 10*time.Millisecond, true)
+	require.NoError(t, storage.Connect(ctx))
+	defer storage.Close(ctx)
+	require.NoError(t, storage.AddEvent(ctx, model.Event{ID: "id1", OwnerEmail: "a@example.com"}))
+
+	require.Eventually(t, func() bool {
+		restored := New()
+		restored.path = path
+		if err := restored.load(); err != nil {
+			return false
+		}
+		info, err := os.Stat(path + ".wal")
+		return err == nil && info.Size() == 0 && len(restored.events) == 1
+	}, time.Second, 10*time.Millisecond, "the snapshot covers the write and the log is emptied")
+}
+
+func TestStorageWriteAheadLogFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+
+	storage := NewPersistent(path, 0, true)
+	require.NoError(t, storage.Connect(ctx))
+	changes, err := storage.WatchChanges(ctx, "a@example.com", 0)
+	require.NoError(t, err)
+
+	// the log cannot be written through the read only handle
+	wal := storage.wal
+	storage.wal, err = os.Open(path + ".wal")
+	require.NoError(t, err)
+
+	require.Error(t, storage.AddEvent(ctx, model.Event{ID: "id1", OwnerEmail: "a@example.com"}))
+	err = storage.InTransaction(ctx, func(ctx context.Context) error {
+		return storage.AddEvent(ctx, model.Event{ID: "id2", OwnerEmail: "a@example.com"})
+	})
+	require.Error(t, err)
+
+	for _, id := range []string{"id1", "id2"} {
+		_, err = storage.GetEvent(ctx, id)
+		require.ErrorAs(t, err, &customerrors.NotFound{}, "the failed write is not applied")
+	}
+	select {
+	case change := <-changes:
+		require.FailNow(t, "the failed write is published", "change %v", change)
+	default:
+	}
+
+	require.NoError(t, storage.wal.Close())
+	storage.wal = wal
+	require.NoError(t, storage.AddEvent(ctx, model.Event{ID: "id3", OwnerEmail: "a@example.com"}))
+	change := <-changes
+	require.Equal(t, int64(1), change.Revision, "the failed writes take no revisions")
+	require.NoError(t, storage.wal.Close())
+
+	restored := NewPersistent(path, 0, true)
+	require.NoError(t, restored.Connect(ctx))
+	defer restored.Close(ctx)
+	require.Len(t, restored.events, 1)
+	require.Equal(t, int64(1), restored.revision)
+}
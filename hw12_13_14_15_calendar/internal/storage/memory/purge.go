@@ -0,0 +1,78 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+func (s *Storage) ListEventsToPurge(ctx context.Context, filter model.PurgeFilter, limit int) ([]model.Event, error) {
+	result := make([]model.Event, 0)
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, ev := range s.events {
+		if filter.Matches(*ev) {
+			result = append(result, *ev)
+		}
+	}
+
+	sortByStartTime(result)
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (s *Storage) ArchiveEvents(ctx context.Context, eventIDs []string) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]walRecord, 0, len(eventIDs))
+	for _, id := range eventIDs {
+		if ev, ok := s.events[id]; ok {
+			records = append(records, walRecord{Op: opArchiveEvent, Event: copyEvent(ev)})
+		}
+	}
+	return s.write(ctx, records...)
+}
+
+// DeleteEvents skips the events which are already removed.
+func (s *Storage) DeleteEvents(ctx context.Context, eventIDs []string) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]walRecord, 0, 2*len(eventIDs))
+	deleted := make(map[string]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		existing, ok := s.events[id]
+		if !ok || deleted[id] {
+			continue
+		}
+		deleted[id] = true
+		records = append(records, walRecord{Op: opDeleteEvent, ID: id}, changeRecord(model.ChangeDeleted, *existing))
+	}
+	return s.write(ctx, records...)
+}
+
+// DeleteChangesOlderThan drops the changes made up to time, the change log is bounded by its size besides.
+func (s *Storage) DeleteChangesOlderThan(ctx context.Context, time time.Time) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := 0
+	for i < len(s.changes) && !s.changes[i].ChangedAt.After(time) {
+		i++
+	}
+	if i == 0 {
+		return nil
+	}
+
+	return s.write(ctx, walRecord{Op: opDeleteChanges, Revision: s.changes[i-1].Revision})
+}
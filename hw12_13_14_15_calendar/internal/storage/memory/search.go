@@ -0,0 +1,123 @@
+package memorystorage
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+type tokenIndex map[string]map[string]struct{}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (idx tokenIndex) add(event *model.Event) {
+	for _, token := range eventTokens(event) {
+		ids, ok := idx[token]
+		if !ok {
+			ids = make(map[string]struct{})
+			idx[token] = ids
+		}
+		ids[event.ID] = struct{}{}
+	}
+}
+
+func (idx tokenIndex) remove(event *model.Event) {
+	for _, token := range eventTokens(event) {
+		delete(idx[token], event.ID)
+		if len(idx[token]) == 0 {
+			delete(idx, token)
+		}
+	}
+}
+
+// lookup returns ids of the events containing every term.
+func (idx tokenIndex) lookup(terms []string) map[string]struct{} {
+	result := make(map[string]struct{})
+	for i, term := range terms {
+		ids := idx[term]
+		if i == 0 {
+			for id := range ids {
+				result[id] = struct{}{}
+			}
+			continue
+		}
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+	return result
+}
+
+func eventTokens(event *model.Event) []string {
+	return append(tokenize(event.Title), tokenize(event.Description)...)
+}
+
+func rank(event *model.Event, terms []string) float64 {
+	return titleWeight*termFrequency(event.Title, terms) + descriptionWeight*termFrequency(event.Description, terms)
+}
+
+func termFrequency(text string, terms []string) float64 {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return 0
+	}
+	cnt := 0
+	for _, token := range tokens {
+		if containsTerm(terms, token) {
+			cnt++
+		}
+	}
+	return float64(cnt) / float64(len(tokens))
+}
+
+// highlight wraps every word of the text matching one of the terms into highlight markers.
+func highlight(text string, terms []string) string {
+	var sb strings.Builder
+	word := make([]rune, 0)
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		if containsTerm(terms, strings.ToLower(string(word))) {
+			sb.WriteString(model.HighlightStart)
+			sb.WriteString(string(word))
+			sb.WriteString(model.HighlightStop)
+		} else {
+			sb.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		sb.WriteRune(r)
+	}
+	flush()
+
+	return sb.String()
+}
+
+func containsTerm(terms []string, token string) bool {
+	for _, term := range terms {
+		if term == token {
+			return true
+		}
+	}
+	return false
+}
@@ -2,74 +2,280 @@ package memorystorage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/changefeed"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 )
 
 type Storage struct {
-	events map[string]*model.Event
-	mu     sync.RWMutex
+	events    map[string]*model.Event
+	archive   map[string]model.Event
+	index     tokenIndex
+	calendars map[string]model.Calendar
+	grants    map[string]map[string]model.Role
+	changes   []loggedChange
+	revision  int64
+	hub       *changefeed.Hub
+	mu        sync.RWMutex
+	txMu      sync.RWMutex
+
+	path             string
+	snapshotInterval time.Duration
+	walEnabled       bool
+	wal              *os.File
+	seq              int64
+	stopSnapshots    context.CancelFunc
+	snapshotsDone    chan struct{}
 }
 
+type txKey struct{}
+
 func New() *Storage {
-	return &Storage{events: make(map[string]*model.Event)}
+	return &Storage{
+		events:    make(map[string]*model.Event),
+		archive:   make(map[string]model.Event),
+		index:     make(tokenIndex),
+		calendars: make(map[string]model.Calendar),
+		grants:    make(map[string]map[string]model.Role),
+		hub:       changefeed.NewHub(),
+	}
 }
 
+// Connect restores the persistent storage, the storage created by New starts empty.
 func (s *Storage) Connect(_ context.Context) error {
-	// TODO
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if s.walEnabled {
+		s.wal, err = os.OpenFile(s.walPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("cannot open write-ahead log: %w", err)
+		}
+	}
+	// the restored state is compacted into the snapshot, which also drops a record cut by a crash
+	if err := s.saveSnapshot(); err != nil {
+		return err
+	}
+
+	if s.snapshotInterval > 0 {
+		var ctx context.Context
+		ctx, s.stopSnapshots = context.WithCancel(context.Background())
+		s.snapshotsDone = make(chan struct{})
+		go s.runSnapshots(ctx, s.snapshotsDone)
+	}
 	return nil
 }
 
+// Close writes the final snapshot of the persistent storage.
 func (s *Storage) Close(_ context.Context) error {
-	// TODO
+	s.hub.CloseAll()
+	if s.path == "" {
+		return nil
+	}
+
+	if s.stopSnapshots != nil {
+		s.stopSnapshots()
+		<-s.snapshotsDone
+	}
+
+	err := s.saveSnapshot()
+	if s.wal != nil {
+		err = errors.Join(err, s.wal.Close())
+	}
+	return err
+}
+
+// InTransaction runs fn exclusively from other writers and restores the previous state if fn fails.
+func (s *Storage) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.RLock()
+	saved := s.saveState()
+	s.mu.RUnlock()
+
+	tx := &transaction{}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.restoreState(saved)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.numberChanges(tx.changes)
+	if err := s.log(append(tx.records, tx.changes...)); err != nil {
+		s.restoreState(saved)
+		return err
+	}
+	s.applyLogged(tx.changes)
 	return nil
 }
 
-func (s *Storage) Truncate(_ context.Context) error {
+// state is the data a transaction restores on rollback. The changes, the revision and the log records
+// made within a transaction are kept in it until the commit, so they need no restoring.
+type state struct {
+	events    map[string]model.Event
+	archive   map[string]model.Event
+	calendars map[string]model.Calendar
+	grants    map[string]map[string]model.Role
+}
+
+// saveState deep copies the data, it must be called with the lock held.
+// The copy takes time and memory in proportion to the whole store on every transaction,
+// including each purge batch, which is acceptable for the development sizes this storage is meant for.
+func (s *Storage) saveState() state {
+	saved := state{
+		events:    make(map[string]model.Event, len(s.events)),
+		archive:   make(map[string]model.Event, len(s.archive)),
+		calendars: maps.Clone(s.calendars),
+		grants:    make(map[string]map[string]model.Role, len(s.grants)),
+	}
+	for id, ev := range s.events {
+		saved.events[id] = *cloneEvent(ev)
+	}
+	for id, ev := range s.archive {
+		saved.archive[id] = *cloneEvent(&ev)
+	}
+	for calendarID, grants := range s.grants {
+		saved.grants[calendarID] = maps.Clone(grants)
+	}
+	return saved
+}
+
+// restoreState must be called with the write lock held.
+func (s *Storage) restoreState(saved state) {
+	s.events = make(map[string]*model.Event, len(saved.events))
+	s.index = make(tokenIndex)
+	for _, ev := range saved.events {
+		s.putEvent(ev)
+	}
+	s.archive = saved.archive
+	s.calendars = saved.calendars
+	s.grants = saved.grants
+}
+
+// txLock keeps the calls outside of a transaction from interleaving with it,
+// so the readers never see the data which a rollback may restore.
+func (s *Storage) txLock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) != nil {
+		return func() {}
+	}
+	s.txMu.RLock()
+	return s.txMu.RUnlock
+}
+
+func (s *Storage) Truncate(ctx context.Context) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(ctx, walRecord{Op: opTruncate})
+}
+
+func (s *Storage) clear() {
 	s.events = make(map[string]*model.Event)
-	return nil
+	s.archive = make(map[string]model.Event)
+	s.index = make(tokenIndex)
+	s.calendars = make(map[string]model.Calendar)
+	s.grants = make(map[string]map[string]model.Role)
+	s.changes = nil
 }
 
-func (s *Storage) AddEvent(_ context.Context, event model.Event) error {
+func (s *Storage) AddEvent(ctx context.Context, event model.Event) error {
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.events[event.ID] = &event
-	return nil
+	if _, ok := s.events[event.ID]; ok {
+		return customerrors.AlreadyExists{Message: fmt.Sprintf("Event with id = \"%v\" already exists", event.ID)}
+	}
+	event.Reminders = slices.Clone(event.Reminders)
+	return s.write(ctx, walRecord{Op: opPutEvent, Event: &event}, changeRecord(model.ChangeCreated, event))
 }
 
-func (s *Storage) UpdateEvent(_ context.Context, event model.Event) error {
+func (s *Storage) UpdateEvent(ctx context.Context, event model.Event) error {
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.events[event.ID]
+	existing, ok := s.events[event.ID]
 	if !ok {
 		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", event.ID)}
 	}
-	s.events[event.ID] = &event
+	event.Reminders = slices.Clone(event.Reminders)
+	records := []walRecord{{Op: opPutEvent, Event: &event}}
+	if existing.OwnerEmail != event.OwnerEmail {
+		records = append(records,
+			changeRecord(model.ChangeDeleted, *existing),
+			changeRecord(model.ChangeCreated, event),
+		)
+	} else {
+		records = append(records, changeRecord(model.ChangeUpdated, event))
+	}
 
-	return nil
+	return s.write(ctx, records...)
+}
+
+func (s *Storage) SetReminderNotified(ctx context.Context, reminderID string) error {
+	return s.updateReminder(ctx, reminderID, func(reminder *model.Reminder) {
+		reminder.Notified = true
+		reminder.ClaimedUntil = time.Time{}
+	})
+}
+
+func (s *Storage) SnoozeReminder(ctx context.Context, reminderID string, until time.Time) error {
+	return s.updateReminder(ctx, reminderID, func(reminder *model.Reminder) {
+		reminder.SnoozedUntil = until
+		reminder.Notified = false
+		reminder.ClaimedUntil = time.Time{}
+	})
 }
 
-func (s *Storage) SetEventNotified(_ context.Context, eventID string) error {
+func (s *Storage) updateReminder(ctx context.Context, reminderID string, update func(reminder *model.Reminder)) error {
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.events[eventID]
-	if !ok {
-		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
+	for _, ev := range s.events {
+		for i, reminder := range ev.Reminders {
+			if reminder.ID != reminderID {
+				continue
+			}
+			// reminders are copied on write, since events handed out share them
+			updated := cloneEvent(ev)
+			update(&updated.Reminders[i])
+			return s.write(ctx, walRecord{Op: opPutEvent, Event: updated})
+		}
 	}
-	event := s.events[eventID]
-	event.Notified = true
 
-	return nil
+	return customerrors.NotFound{Message: fmt.Sprintf("Reminder with id = \"%v\" not found", reminderID)}
 }
 
-func (s *Storage) GetEvent(_ context.Context, eventID string) (model.Event, error) {
+func (s *Storage) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -81,12 +287,13 @@ func (s *Storage) GetEvent(_ context.Context, eventID string) (model.Event, erro
 }
 
 func (s *Storage) ListOwnerEventsForPeriod(
-	_ context.Context,
+	ctx context.Context,
 	ownerEmail string,
 	startDate,
 	endDate time.Time,
 ) ([]model.Event, error) {
 	result := make([]model.Event, 0)
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -95,51 +302,170 @@ func (s *Storage) ListOwnerEventsForPeriod(
 			continue
 		}
 
-		if (startDate.Before(ev.StartTime) || startDate.Equal(ev.StartTime)) &&
-			(endDate.After(ev.StartTime) || endDate.Equal(ev.StartTime)) {
+		if !ev.StartTime.Before(startDate) && ev.StartTime.Before(endDate) {
 			result = append(result, *ev)
 		}
 	}
 
+	sortByStartTime(result)
 	return result, nil
 }
 
-func (s *Storage) ListEventsToBeNotified(
-	_ context.Context,
+func sortByStartTime(events []model.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+}
+
+func (s *Storage) ListRemindersToBeNotified(
+	ctx context.Context,
 	startTime,
 	endTime time.Time,
-) ([]model.Event, error) {
-	result := make([]model.Event, 0)
+) ([]model.DueReminder, error) {
+	result := make([]model.DueReminder, 0)
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for _, ev := range s.events {
-		if (startTime.Before(ev.NotifyTime) || startTime.Equal(ev.NotifyTime)) &&
-			(endTime.After(ev.NotifyTime) || endTime.Equal(ev.NotifyTime)) {
-			result = append(result, *ev)
+		for _, reminder := range ev.Reminders {
+			dueTime := reminder.DueTime()
+			if !reminder.Notified &&
+				(startTime.Before(dueTime) || startTime.Equal(dueTime)) &&
+				(endTime.After(dueTime) || endTime.Equal(dueTime)) {
+				result = append(result, model.DueReminder{Reminder: reminder, Event: *ev})
+			}
 		}
 	}
 
 	return result, nil
 }
 
-func (s *Storage) DeleteEvent(_ context.Context, eventID string) error {
+// ClaimDueReminders marks up to limit due reminders as dispatching until the claim expires.
+func (s *Storage) ClaimDueReminders(
+	ctx context.Context,
+	since, now, claimUntil time.Time,
+	limit int,
+) ([]model.DueReminder, error) {
+	defer s.txLock(ctx)()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.events[eventID]
+	result := make([]model.DueReminder, 0)
+	for _, ev := range s.events {
+		for _, reminder := range ev.Reminders {
+			if reminder.Claimable(since, now) {
+				result = append(result, model.DueReminder{Reminder: reminder, Event: *ev})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].DueTime().Equal(result[j].DueTime()) {
+			return result[i].DueTime().Before(result[j].DueTime())
+		}
+		return result[i].ID < result[j].ID
+	})
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+
+	records := make([]walRecord, 0, len(result))
+	claimed := make(map[string]*model.Event, len(result))
+	for i, due := range result {
+		ev, ok := claimed[due.Event.ID]
+		if !ok {
+			ev = cloneEvent(s.events[due.Event.ID])
+			claimed[ev.ID] = ev
+			records = append(records, walRecord{Op: opPutEvent, Event: ev})
+		}
+		j := slices.IndexFunc(ev.Reminders, func(r model.Reminder) bool { return r.ID == due.ID })
+		ev.Reminders[j].ClaimedUntil = claimUntil
+		result[i].ClaimedUntil = claimUntil
+		result[i].Event = *ev
+	}
+
+	if err := s.write(ctx, records...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Storage) SearchEvents(
+	ctx context.Context,
+	ownerEmail,
+	query string,
+	startDate,
+	endDate time.Time,
+	limit,
+	offset int,
+) ([]model.EventMatch, error) {
+	result := make([]model.EventMatch, 0)
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	defer s.txLock(ctx)()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id := range s.index.lookup(terms) {
+		ev := s.events[id]
+		if ev.OwnerEmail != ownerEmail || ev.StartTime.Before(startDate) || ev.StartTime.After(endDate) {
+			continue
+		}
+
+		result = append(result, model.EventMatch{
+			Event:                *ev,
+			Rank:                 rank(ev, terms),
+			TitleHighlight:       highlight(ev.Title, terms),
+			DescriptionHighlight: highlight(ev.Description, terms),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rank != result[j].Rank {
+			return result[i].Rank > result[j].Rank
+		}
+		if !result[i].StartTime.Equal(result[j].StartTime) {
+			return result[i].StartTime.Before(result[j].StartTime)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	if offset >= len(result) {
+		return make([]model.EventMatch, 0), nil
+	}
+	result = result[offset:]
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (s *Storage) DeleteEvent(ctx context.Context, eventID string) error {
+	defer s.txLock(ctx)()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.events[eventID]
 	if !ok {
 		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
 	}
 
-	delete(s.events, eventID)
-	return nil
+	return s.write(ctx, walRecord{Op: opDeleteEvent, ID: eventID}, changeRecord(model.ChangeDeleted, *existing))
 }
 
 func (s *Storage) DeleteEventsOlderThan(
-	_ context.Context,
+	ctx context.Context,
 	time time.Time,
 ) error {
+	defer s.txLock(ctx)()
 	result := make([]model.Event, 0)
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -150,9 +476,23 @@ func (s *Storage) DeleteEventsOlderThan(
 		}
 	}
 
+	records := make([]walRecord, 0, 2*len(result))
 	for _, ev := range result {
-		delete(s.events, ev.ID)
+		records = append(records, walRecord{Op: opDeleteEvent, ID: ev.ID}, changeRecord(model.ChangeDeleted, ev))
 	}
 
-	return nil
+	return s.write(ctx, records...)
+}
+
+// cloneEvent copies the event with its reminders.
+func cloneEvent(ev *model.Event) *model.Event {
+	event := *ev
+	event.Reminders = slices.Clone(ev.Reminders)
+	return &event
+}
+
+// copyEvent detaches the logged event from the stored one, which the later writes change.
+func copyEvent(ev *model.Event) *model.Event {
+	event := *ev
+	return &event
 }
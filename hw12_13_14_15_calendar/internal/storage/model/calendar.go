@@ -0,0 +1,39 @@
+package model
+
+type Role string
+
+const (
+	RoleFreeBusy Role = "free_busy"
+	RoleViewer   Role = "viewer"
+	RoleEditor   Role = "editor"
+	RoleOwner    Role = "owner"
+)
+
+var roleLevels = map[Role]int{
+	RoleFreeBusy: 1,
+	RoleViewer:   2,
+	RoleEditor:   3,
+	RoleOwner:    4,
+}
+
+type Calendar struct {
+	ID         string
+	Name       string
+	OwnerEmail string
+}
+
+type Grant struct {
+	CalendarID string
+	Email      string
+	Role       Role
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Allows tells whether the role includes everything the required role is permitted to do.
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] > 0 && roleLevels[r] >= roleLevels[required]
+}
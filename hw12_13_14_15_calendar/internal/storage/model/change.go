@@ -0,0 +1,15 @@
+package model
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+type Change struct {
+	Revision int64
+	Type     ChangeType
+	Event    Event
+}
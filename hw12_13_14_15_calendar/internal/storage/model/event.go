@@ -7,9 +7,11 @@ type Event struct {
 	Title        string
 	StartTime    time.Time
 	EndTime      time.Time
+	TimeZone     string // the IANA name the event is planned in, empty if the client did not tell it
 	Description  string
 	NotifyBefore string
 	NotifyTime   time.Time
 	OwnerEmail   string
-	Notified     bool
+	CalendarID   string
+	Reminders    []Reminder
 }
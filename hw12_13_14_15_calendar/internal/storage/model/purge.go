@@ -0,0 +1,37 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// PurgeFilter selects the events started before the retention boundary, they are listed by start time.
+// Events after the AfterTime/AfterID position only are listed when it is set.
+type PurgeFilter struct {
+	Before           time.Time
+	OwnerEmail       string
+	CalendarID       string
+	ExcludeOwners    []string
+	ExcludeCalendars []string
+	AfterTime        time.Time
+	AfterID          string
+}
+
+func (f PurgeFilter) Matches(ev Event) bool {
+	if !ev.StartTime.Before(f.Before) {
+		return false
+	}
+	if f.OwnerEmail != "" && ev.OwnerEmail != f.OwnerEmail {
+		return false
+	}
+	if f.CalendarID != "" && ev.CalendarID != f.CalendarID {
+		return false
+	}
+	if slices.Contains(f.ExcludeOwners, ev.OwnerEmail) || slices.Contains(f.ExcludeCalendars, ev.CalendarID) {
+		return false
+	}
+	if f.AfterID != "" {
+		return ev.StartTime.After(f.AfterTime) || (ev.StartTime.Equal(f.AfterTime) && ev.ID > f.AfterID)
+	}
+	return true
+}
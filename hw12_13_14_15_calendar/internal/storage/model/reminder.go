@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+)
+
+// Reminder is delivered through its channel at NotifyTime unless snoozed, an empty target means the event owner.
+// A scheduler dispatching the reminder claims it until ClaimedUntil, the reminder is retried when the claim expires.
+type Reminder struct {
+	ID           string
+	Before       string
+	Channel      Channel
+	Target       string
+	NotifyTime   time.Time
+	SnoozedUntil time.Time
+	ClaimedUntil time.Time
+	Notified     bool
+}
+
+type DueReminder struct {
+	Reminder
+	Event Event
+}
+
+func (r Reminder) DueTime() time.Time {
+	if !r.SnoozedUntil.IsZero() {
+		return r.SnoozedUntil
+	}
+	return r.NotifyTime
+}
+
+// Claimable tells whether the reminder is due and nobody is dispatching it at the moment.
+func (r Reminder) Claimable(since, now time.Time) bool {
+	dueTime := r.DueTime()
+	return !r.Notified && !dueTime.Before(since) && !dueTime.After(now) && r.ClaimedUntil.Before(now)
+}
+
+func (c Channel) Valid() bool {
+	return c == ChannelEmail || c == ChannelWebhook
+}
+
+// SameDelivery tells whether both reminders are sent the same way at the same time.
+func (r Reminder) SameDelivery(other Reminder) bool {
+	return r.Before == other.Before && r.Channel == other.Channel && r.Target == other.Target &&
+		r.NotifyTime.Equal(other.NotifyTime)
+}
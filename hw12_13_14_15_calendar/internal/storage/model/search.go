@@ -0,0 +1,13 @@
+package model
+
+const (
+	HighlightStart = "<b>"
+	HighlightStop  = "</b>"
+)
+
+type EventMatch struct {
+	Event
+	Rank                 float64
+	TitleHighlight       string
+	DescriptionHighlight string
+}
@@ -0,0 +1,212 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/stdlib"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/changefeed"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const changesChannel = "event_changes"
+
+// changesLock is the advisory lock the transactions writing the events hold till the commit,
+// the trigger of migration 0012 takes the same one.
+const changesLock = 1701147252
+
+//nolint:tagliatelle
+type changePayload struct {
+	ID           string     `json:"id"`
+	Title        string     `json:"title"`
+	StartTime    time.Time  `json:"start_time"`
+	EndTime      time.Time  `json:"end_time"`
+	Description  *string    `json:"description"`
+	NotifyBefore *string    `json:"notify_before"`
+	NotifyTime   *time.Time `json:"notify_time"`
+	OwnerEmail   string     `json:"owner_email"`
+	CalendarID   *string    `json:"calendar_id"`
+}
+
+func (s *Storage) WatchChanges(
+	ctx context.Context, ownerEmail string, fromRevision int64,
+) (<-chan model.Change, error) {
+	if err := s.ensureListener(); err != nil {
+		return nil, err
+	}
+
+	live, unsubscribe := s.hub.Subscribe(ownerEmail)
+
+	backlog := make([]model.Change, 0)
+	if fromRevision > 0 {
+		var err error
+		backlog, err = s.queryChanges(ctx,
+			`SELECT revision, change_type, payload FROM event_changes
+			WHERE owner_email = $1 AND revision > $2 ORDER BY revision`,
+			ownerEmail,
+			fromRevision,
+		)
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		// the check follows the query, so the changes pruned meanwhile are not missed
+		if err := s.checkRevision(ctx, fromRevision); err != nil {
+			unsubscribe()
+			return nil, err
+		}
+	}
+
+	return changefeed.Stream(ctx, fromRevision, backlog, live, unsubscribe), nil
+}
+
+// ensureListener starts the single LISTEN connection shared by all watchers.
+func (s *Storage) ensureListener() error {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+
+	if s.stopListener != nil {
+		return nil
+	}
+
+	conn, err := stdlib.AcquireConn(s.db)
+	if err != nil {
+		return fmt.Errorf("cannot acquire listener connection: %w", err)
+	}
+	if err := conn.Listen(changesChannel); err != nil {
+		stdlib.ReleaseConn(s.db, conn)
+		return fmt.Errorf("cannot listen to %s: %w", changesChannel, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopListener = cancel
+	go s.listen(ctx, conn)
+
+	return nil
+}
+
+func (s *Storage) listen(ctx context.Context, conn *pgx.Conn) {
+	defer func() {
+		stdlib.ReleaseConn(s.db, conn)
+
+		s.listenerMu.Lock()
+		s.stopListener = nil
+		s.listenerMu.Unlock()
+
+		// watchers reconnect and resume from their last revision
+		s.hub.CloseAll()
+	}()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return
+		}
+
+		revision, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		changes, err := s.queryChanges(ctx,
+			"SELECT revision, change_type, payload FROM event_changes WHERE revision = $1",
+			revision,
+		)
+		if err != nil {
+			return
+		}
+		for _, change := range changes {
+			s.hub.Publish(change)
+		}
+	}
+}
+
+// lockChanges makes the transactions writing the events commit one by one, so the revisions the trigger
+// assigns grow in the commit order and the watchers skipping the revisions they have seen miss nothing.
+// It must be called within a transaction before the first write of the events, the trigger would take the lock
+// after the row locks otherwise, which deadlocks the concurrent writers of several events.
+// The lock is global, so the event writes of all the instances run one transaction at a time.
+func (s *Storage) lockChanges(ctx context.Context) error {
+	_, err := s.conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", changesLock)
+	return err
+}
+
+func (s *Storage) stopListening() {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+
+	if s.stopListener != nil {
+		s.stopListener()
+	}
+}
+
+// checkRevision fails if the changes following fromRevision are pruned already.
+func (s *Storage) checkRevision(ctx context.Context, fromRevision int64) error {
+	var oldest int64
+	err := s.db.QueryRowContext(ctx, "SELECT coalesce(min(revision), 0) FROM event_changes").Scan(&oldest)
+	if err != nil {
+		return err
+	}
+	if fromRevision < oldest-1 {
+		return customerrors.OutOfRange{Message: fmt.Sprintf("Revision %d is no longer available", fromRevision)}
+	}
+	return nil
+}
+
+func (s *Storage) queryChanges(ctx context.Context, query string, args ...any) ([]model.Change, error) {
+	result := make([]model.Change, 0)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var change model.Change
+		var changeType string
+		var data []byte
+		if err := rows.Scan(&change.Revision, &changeType, &data); err != nil {
+			return nil, err
+		}
+
+		var payload changePayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("cannot decode change %d: %w", change.Revision, err)
+		}
+
+		change.Type = model.ChangeType(changeType)
+		change.Event = model.Event{
+			ID:         payload.ID,
+			Title:      payload.Title,
+			StartTime:  payload.StartTime.UTC(),
+			EndTime:    payload.EndTime.UTC(),
+			OwnerEmail: payload.OwnerEmail,
+		}
+		if payload.Description != nil {
+			change.Event.Description = *payload.Description
+		}
+		if payload.NotifyBefore != nil {
+			change.Event.NotifyBefore = *payload.NotifyBefore
+		}
+		if payload.NotifyTime != nil {
+			change.Event.NotifyTime = payload.NotifyTime.UTC()
+		}
+		if payload.CalendarID != nil {
+			change.Event.CalendarID = *payload.CalendarID
+		}
+		result = append(result, change)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,33 @@
+package sqlstorage_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	sqlstorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/sql"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// dsnEnv names the variable with the connection string of a disposable database, its data are deleted.
+const dsnEnv = "CALENDAR_TEST_POSTGRES_DSN"
+
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Control {
+		t.Helper()
+
+		ctx := context.Background()
+		st := sqlstorage.NewFromDSN(dsn, true)
+		require.NoError(t, st.Connect(ctx))
+		t.Cleanup(func() { st.Close(ctx) })
+		require.NoError(t, st.Truncate(ctx))
+		return st
+	})
+}
@@ -0,0 +1,93 @@
+package sqlstorage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+func (s *Storage) ListEventsToPurge(ctx context.Context, filter model.PurgeFilter, limit int) ([]model.Event, error) {
+	excludeOwners, err := jsonList(filter.ExcludeOwners)
+	if err != nil {
+		return nil, err
+	}
+	excludeCalendars, err := jsonList(filter.ExcludeCalendars)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events
+		WHERE start_time < $1
+		AND ($2 = '' OR owner_email = $2)
+		AND ($3 = '' OR calendar_id = $3)
+		AND owner_email NOT IN (SELECT json_array_elements_text($4::json))
+		AND coalesce(calendar_id, '') NOT IN (SELECT json_array_elements_text($5::json))
+		AND ($7 = '' OR (start_time, id) > ($6, $7))
+		ORDER BY start_time, id
+		LIMIT NULLIF($8, 0)`,
+		filter.Before,
+		filter.OwnerEmail,
+		filter.CalendarID,
+		excludeOwners,
+		excludeCalendars,
+		filter.AfterTime,
+		filter.AfterID,
+		limit,
+	)
+}
+
+// ArchiveEvents copies the events with their reminders to the archive table, archived events are kept as is.
+func (s *Storage) ArchiveEvents(ctx context.Context, eventIDs []string) error {
+	ids, err := jsonList(eventIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, `INSERT INTO events_archive
+		(id, title, start_time, end_time, time_zone, description, notify_before, notify_time, owner_email, calendar_id,
+		reminders)
+		SELECT id, title, start_time, end_time, time_zone, description, notify_before, notify_time, owner_email,
+		calendar_id,
+		`+remindersColumn+`::jsonb
+		FROM events
+		WHERE id IN (SELECT json_array_elements_text($1::json))
+		ON CONFLICT (id) DO NOTHING`,
+		ids,
+	)
+	return err
+}
+
+func (s *Storage) DeleteEvents(ctx context.Context, eventIDs []string) error {
+	ids, err := jsonList(eventIDs)
+	if err != nil {
+		return err
+	}
+
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockChanges(ctx); err != nil {
+			return err
+		}
+		_, err := s.conn(ctx).ExecContext(ctx,
+			"DELETE FROM events WHERE id IN (SELECT json_array_elements_text($1::json))",
+			ids,
+		)
+		return err
+	})
+}
+
+func (s *Storage) DeleteChangesOlderThan(ctx context.Context, time time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM event_changes WHERE changed_at <= $1", time)
+	return err
+}
+
+// jsonList encodes the values as a JSON array, since the driver does not support array parameters.
+func jsonList(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
@@ -0,0 +1,225 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+// remindersColumn aggregates the reminders of the selected event into a JSON array.
+const remindersColumn = `COALESCE((SELECT json_agg(r ORDER BY r.notify_time, r.id) FROM event_reminders r
+		WHERE r.event_id = events.id), '[]')`
+
+//nolint:tagliatelle
+type reminderPayload struct {
+	ID           string     `json:"id"`
+	NotifyBefore string     `json:"notify_before"`
+	Channel      string     `json:"channel"`
+	Target       *string    `json:"target"`
+	NotifyTime   time.Time  `json:"notify_time"`
+	SnoozedUntil *time.Time `json:"snoozed_until"`
+	ClaimedUntil *time.Time `json:"claimed_until"`
+	Notified     bool       `json:"notified_flag"`
+}
+
+func decodeReminders(data []byte) ([]model.Reminder, error) {
+	var payload []reminderPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("cannot decode reminders: %w", err)
+	}
+
+	reminders := make([]model.Reminder, 0, len(payload))
+	for _, p := range payload {
+		reminder := model.Reminder{
+			ID:         p.ID,
+			Before:     p.NotifyBefore,
+			Channel:    model.Channel(p.Channel),
+			NotifyTime: p.NotifyTime.UTC(),
+			Notified:   p.Notified,
+		}
+		if p.Target != nil {
+			reminder.Target = *p.Target
+		}
+		if p.SnoozedUntil != nil {
+			reminder.SnoozedUntil = p.SnoozedUntil.UTC()
+		}
+		if p.ClaimedUntil != nil {
+			reminder.ClaimedUntil = p.ClaimedUntil.UTC()
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, nil
+}
+
+// saveReminders replaces the reminders of the event, it must run within a transaction.
+func (s *Storage) saveReminders(ctx context.Context, event model.Event) error {
+	if _, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM event_reminders WHERE event_id = $1", event.ID); err != nil {
+		return err
+	}
+
+	for _, reminder := range event.Reminders {
+		_, err := s.conn(ctx).ExecContext(ctx, `INSERT INTO event_reminders
+			(id, event_id, notify_before, channel, target, notify_time, snoozed_until, claimed_until, notified_flag)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			reminder.ID,
+			event.ID,
+			reminder.Before,
+			string(reminder.Channel),
+			nullString(reminder.Target),
+			reminder.NotifyTime,
+			nullTime(reminder.SnoozedUntil),
+			nullTime(reminder.ClaimedUntil),
+			reminder.Notified,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) SetReminderNotified(ctx context.Context, reminderID string) error {
+	return s.updateReminder(ctx, reminderID, `UPDATE event_reminders
+		SET notified_flag = true, claimed_until = NULL
+		WHERE id = $1`,
+	)
+}
+
+func (s *Storage) SnoozeReminder(ctx context.Context, reminderID string, until time.Time) error {
+	return s.updateReminder(ctx, reminderID, `UPDATE event_reminders
+		SET snoozed_until = $2, notified_flag = false, claimed_until = NULL
+		WHERE id = $1`,
+		until,
+	)
+}
+
+func (s *Storage) updateReminder(ctx context.Context, reminderID, query string, args ...any) error {
+	res, err := s.conn(ctx).ExecContext(ctx, query, append([]any{reminderID}, args...)...)
+	return checkAffected(res, err, customerrors.NotFound{
+		Message: fmt.Sprintf("Reminder with id = \"%v\" not found", reminderID),
+	})
+}
+
+func (s *Storage) ListRemindersToBeNotified(
+	ctx context.Context,
+	startTime,
+	endTime time.Time,
+) ([]model.DueReminder, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT `+dueReminderColumns+`
+		FROM event_reminders r JOIN events e ON e.id = r.event_id
+		WHERE coalesce(r.snoozed_until, r.notify_time) BETWEEN $1 AND $2 AND NOT r.notified_flag`,
+		startTime,
+		endTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDueReminders(rows)
+}
+
+// ClaimDueReminders marks up to limit due reminders as dispatching until the claim expires.
+// Reminders claimed by concurrent schedulers are skipped, expired claims are taken over.
+// The claimed reminders are returned in the order they are due, RETURNING does not keep it.
+func (s *Storage) ClaimDueReminders(
+	ctx context.Context,
+	since, now, claimUntil time.Time,
+	limit int,
+) ([]model.DueReminder, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`WITH due AS (
+			SELECT id FROM event_reminders
+			WHERE NOT notified_flag
+			AND coalesce(snoozed_until, notify_time) BETWEEN $1 AND $2
+			AND (claimed_until IS NULL OR claimed_until < $2)
+			ORDER BY coalesce(snoozed_until, notify_time), id
+			LIMIT NULLIF($4, 0)
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE event_reminders r SET claimed_until = $3
+		FROM due, events e
+		WHERE r.id = due.id AND e.id = r.event_id
+		RETURNING `+dueReminderColumns,
+		since,
+		now,
+		claimUntil,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scanDueReminders(rows)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		iTime, jTime := result[i].DueTime(), result[j].DueTime()
+		if !iTime.Equal(jTime) {
+			return iTime.Before(jTime)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+const dueReminderColumns = `r.id, r.notify_before, r.channel, r.target, r.notify_time, r.snoozed_until, r.claimed_until,
+		e.id, e.title, e.start_time, e.end_time, e.description, e.owner_email, e.calendar_id`
+
+func scanDueReminders(rows *sql.Rows) ([]model.DueReminder, error) {
+	defer rows.Close()
+
+	result := make([]model.DueReminder, 0)
+	for rows.Next() {
+		var due model.DueReminder
+		var target, description, calendarID sql.NullString
+		var notifyTime, startTime, endTime time.Time
+		var snoozedUntil, claimedUntil sql.NullTime
+		err := rows.Scan(&due.ID,
+			&due.Before,
+			&due.Channel,
+			&target,
+			&notifyTime,
+			&snoozedUntil,
+			&claimedUntil,
+			&due.Event.ID,
+			&due.Event.Title,
+			&startTime,
+			&endTime,
+			&description,
+			&due.Event.OwnerEmail,
+			&calendarID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		due.Target = target.String
+		due.NotifyTime = notifyTime.UTC()
+		if snoozedUntil.Valid {
+			due.SnoozedUntil = snoozedUntil.Time.UTC()
+		}
+		if claimedUntil.Valid {
+			due.ClaimedUntil = claimedUntil.Time.UTC()
+		}
+		due.Event.StartTime = startTime.UTC()
+		due.Event.EndTime = endTime.UTC()
+		due.Event.Description = description.String
+		due.Event.CalendarID = calendarID.String
+		result = append(result, due)
+	}
+
+	return result, rows.Err()
+}
+
+func nullTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value, Valid: !value.IsZero()}
+}
@@ -5,21 +5,56 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/jackc/pgx"
 	_ "github.com/jackc/pgx/stdlib" // need import pgx
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/migrate"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/changefeed"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/migrations"
 )
 
+const eventColumns = `id, title, start_time, end_time, time_zone, description, notify_before, notify_time,
+		owner_email, calendar_id, ` + remindersColumn
+
+// uniqueViolation is the SQLSTATE of the duplicate keys.
+const uniqueViolation = "23505"
+
 type Storage struct {
-	dsn string
-	db  *sql.DB
+	dsn          string
+	autoMigrate  bool
+	db           *sql.DB
+	hub          *changefeed.Hub
+	listenerMu   sync.Mutex
+	stopListener context.CancelFunc
+}
+
+type txKey struct{}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-func New(host string, port int, dbname, user, password string) *Storage {
+// New creates the storage, with autoMigrate the pending migrations are applied on Connect.
+func New(host string, port int, dbname, user, password string, autoMigrate bool) *Storage {
+	return NewFromDSN(fmt.Sprintf("postgres://%v:%v@%v:%v/%v", user, password, host, port, dbname), autoMigrate)
+}
+
+// NewFromDSN creates the storage connecting to the database at dsn.
+func NewFromDSN(dsn string, autoMigrate bool) *Storage {
 	return &Storage{
-		dsn: fmt.Sprintf("postgres://%v:%v@%v:%v/%v", user, password, host, port, dbname),
+		dsn:         dsn,
+		autoMigrate: autoMigrate,
+		hub:         changefeed.NewHub(),
 	}
 }
 
@@ -30,22 +65,84 @@ func (s *Storage) Connect(ctx context.Context) error {
 		return fmt.Errorf("cannot open pgx driver: %w", err)
 	}
 
-	return s.db.PingContext(ctx)
+	if err := s.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if !s.autoMigrate {
+		return nil
+	}
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	return nil
+}
+
+// Migrator manages the schema of the connected database.
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	return migrate.New(s.db, migrations.FS, migrate.Postgres)
 }
 
 func (s *Storage) Close(_ context.Context) error {
+	s.stopListening()
 	return s.db.Close()
 }
 
+// InTransaction runs fn within a transaction carried by the context passed to fn.
+func (s *Storage) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("cannot rollback transaction: %w", rbErr))
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
 func (s *Storage) Truncate(ctx context.Context) error {
-	s.db.ExecContext(ctx, "TRUNCATE events CASCADE")
-	return nil
+	_, err := s.conn(ctx).ExecContext(ctx,
+		"TRUNCATE events, event_changes, events_archive, calendars, calendar_grants CASCADE",
+	)
+	return err
 }
 
 func (s *Storage) AddEvent(ctx context.Context, event model.Event) error {
-	res, err := s.db.ExecContext(ctx, `INSERT INTO events 
-		(id, title, start_time, end_time, description, notify_before, owner_email, notify_time) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockChanges(ctx); err != nil {
+			return err
+		}
+		if err := s.insertEvent(ctx, event); err != nil {
+			return err
+		}
+		return s.saveReminders(ctx, event)
+	})
+}
+
+func (s *Storage) insertEvent(ctx context.Context, event model.Event) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `INSERT INTO events
+		(id, title, start_time, end_time, description, notify_before, owner_email, notify_time, calendar_id, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
 		event.ID,
 		event.Title,
 		event.StartTime,
@@ -54,27 +151,28 @@ func (s *Storage) AddEvent(ctx context.Context, event model.Event) error {
 		event.NotifyBefore,
 		event.OwnerEmail,
 		event.NotifyTime,
+		nullString(event.CalendarID),
+		event.TimeZone,
 	)
-	if err != nil {
-		return err
-	}
-
-	cnt, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if cnt == 0 {
-		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", event.ID)}
-	}
-
-	return nil
+	return checkDuplicate(err, eventExists(event.ID))
 }
 
 func (s *Storage) UpdateEvent(ctx context.Context, event model.Event) error {
-	res, err := s.db.ExecContext(ctx, `UPDATE events
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockChanges(ctx); err != nil {
+			return err
+		}
+		if err := s.updateEvent(ctx, event); err != nil {
+			return err
+		}
+		return s.saveReminders(ctx, event)
+	})
+}
+
+func (s *Storage) updateEvent(ctx context.Context, event model.Event) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE events
 		SET title = $2, start_time = $3, end_time = $4, description = $5,
-		notify_before = $6, owner_email = $7, notify_time = $8
+		notify_before = $6, owner_email = $7, notify_time = $8, calendar_id = $9, time_zone = $10
 		WHERE id = $1`,
 		event.ID,
 		event.Title,
@@ -84,106 +182,35 @@ func (s *Storage) UpdateEvent(ctx context.Context, event model.Event) error {
 		event.NotifyBefore,
 		event.OwnerEmail,
 		event.NotifyTime,
+		nullString(event.CalendarID),
+		event.TimeZone,
 	)
-	if err != nil {
-		return err
-	}
-
-	cnt, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if cnt == 0 {
-		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", event.ID)}
-	}
-
-	return nil
-}
-
-func (s *Storage) SetEventNotified(ctx context.Context, eventID string) error {
-	res, err := s.db.ExecContext(ctx, `UPDATE events
-		SET notified_flag = $2 
-		WHERE id = $1`,
-		eventID,
-		true,
-	)
-	if err != nil {
-		return err
-	}
-
-	cnt, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if cnt == 0 {
-		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
-	}
-
-	return nil
+	return checkAffected(res, err, eventNotFound(event.ID))
 }
 
 func (s *Storage) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, title, start_time, end_time, description, notify_before, notify_time, owner_email, notified_flag 
-		FROM events WHERE id = $1`,
+	event, err := scanEvent(s.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE id = $1`,
 		eventID,
-	)
-	if errors.Is(row.Err(), sql.ErrNoRows) {
-		return model.Event{}, customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
-	}
-	if row.Err() != nil {
-		return model.Event{}, row.Err()
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Event{}, eventNotFound(eventID)
 	}
-	var event model.Event
-	var notify, description sql.NullString
-	var notifyTime sql.NullTime
-	err := row.Scan(&event.ID,
-		&event.Title,
-		&event.StartTime,
-		&event.EndTime,
-		&description,
-		&notify,
-		&notifyTime,
-		&event.OwnerEmail,
-		&event.Notified,
-	)
 	if err != nil {
 		return model.Event{}, err
 	}
 
-	if description.Valid {
-		event.Description = description.String
-	}
-
-	if notify.Valid {
-		event.NotifyBefore = notify.String
-	}
-
-	if notifyTime.Valid {
-		event.NotifyTime = notifyTime.Time
-	}
-
 	return event, nil
 }
 
 func (s *Storage) DeleteEvent(ctx context.Context, eventID string) error {
-	res, err := s.db.ExecContext(ctx, "delete from events where id = $1", eventID)
-	if err != nil {
-		return err
-	}
-
-	cnt, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if cnt == 0 {
-		return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
-	}
-
-	return nil
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockChanges(ctx); err != nil {
+			return err
+		}
+		res, err := s.conn(ctx).ExecContext(ctx, "delete from events where id = $1", eventID)
+		return checkAffected(res, err, eventNotFound(eventID))
+	})
 }
 
 func (s *Storage) ListOwnerEventsForPeriod(
@@ -192,18 +219,43 @@ func (s *Storage) ListOwnerEventsForPeriod(
 	startDate,
 	endDate time.Time,
 ) ([]model.Event, error) {
-	result := make([]model.Event, 0)
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, title, start_time, end_time, description, notify_before, notify_time, owner_email, notified_flag 
-		FROM events 
-		WHERE owner_email = $1 AND start_time >= $2 AND end_time <= $3`,
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events
+		WHERE owner_email = $1 AND start_time >= $2 AND start_time < $3
+		ORDER BY start_time, id`,
 		ownerEmail,
 		startDate,
 		endDate,
 	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return result, nil
-	}
+}
+
+func (s *Storage) SearchEvents(
+	ctx context.Context,
+	ownerEmail,
+	query string,
+	startDate,
+	endDate time.Time,
+	limit,
+	offset int,
+) ([]model.EventMatch, error) {
+	result := make([]model.EventMatch, 0)
+	headlineOptions := fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", model.HighlightStart, model.HighlightStop)
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT `+eventColumns+`, ts_rank(search_vector, q) AS rank,
+		ts_headline('simple', title, q, $7),
+		ts_headline('simple', coalesce(description, ''), q, $7)
+		FROM events, plainto_tsquery('simple', $2) q
+		WHERE owner_email = $1 AND search_vector @@ q AND start_time >= $3 AND start_time <= $4
+		ORDER BY rank DESC, start_time, id
+		LIMIT NULLIF($5, 0) OFFSET $6`,
+		ownerEmail,
+		query,
+		startDate,
+		endDate,
+		limit,
+		offset,
+		headlineOptions,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -211,107 +263,127 @@ func (s *Storage) ListOwnerEventsForPeriod(
 	defer rows.Close()
 
 	for rows.Next() {
-		var event model.Event
-		var notify, description sql.NullString
-		var notifyTime sql.NullTime
-		err := rows.Scan(&event.ID,
-			&event.Title,
-			&event.StartTime,
-			&event.EndTime,
-			&description,
-			&notify,
-			&notifyTime,
-			&event.OwnerEmail,
-			&event.Notified,
-		)
+		var match model.EventMatch
+		match.Event, err = scanEvent(rows, &match.Rank, &match.TitleHighlight, &match.DescriptionHighlight)
 		if err != nil {
 			return nil, err
 		}
-		if description.Valid {
-			event.Description = description.String
-		}
-
-		if notify.Valid {
-			event.NotifyBefore = notify.String
-		}
-
-		if notifyTime.Valid {
-			event.NotifyTime = notifyTime.Time
-		}
-		result = append(result, event)
+		result = append(result, match)
 	}
 
 	return result, rows.Err()
 }
 
-func (s *Storage) ListEventsToBeNotified(
+func (s *Storage) DeleteEventsOlderThan(
 	ctx context.Context,
-	startTime,
-	endTime time.Time,
-) ([]model.Event, error) {
-	result := make([]model.Event, 0)
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, title, start_time, end_time, description, notify_before, notify_time, owner_email 
-		FROM events 
-		WHERE notify_time >= $1 AND notify_time <= $2 AND notified_flag = false `,
-		startTime,
-		endTime,
-	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return result, nil
+	time time.Time,
+) error {
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockChanges(ctx); err != nil {
+			return err
+		}
+		_, err := s.conn(ctx).ExecContext(ctx, "delete from events where start_time < $1", time)
+		return err
+	})
+	if err != nil {
+		return err
 	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, "delete from event_changes where changed_at <= $1", time)
+	return err
+}
+
+func (s *Storage) queryEvents(ctx context.Context, query string, args ...any) ([]model.Event, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
+	result := make([]model.Event, 0)
 	for rows.Next() {
-		var event model.Event
-		var notify, description sql.NullString
-		var notifyTime sql.NullTime
-		err := rows.Scan(&event.ID,
-			&event.Title,
-			&event.StartTime,
-			&event.EndTime,
-			&description,
-			&notify,
-			&notifyTime,
-			&event.OwnerEmail,
-		)
+		event, err := scanEvent(rows)
 		if err != nil {
 			return nil, err
 		}
-		if description.Valid {
-			event.Description = description.String
-		}
-
-		if notify.Valid {
-			event.NotifyBefore = notify.String
-		}
-
-		if notifyTime.Valid {
-			event.NotifyTime = notifyTime.Time
-		}
 		result = append(result, event)
 	}
 
 	return result, rows.Err()
 }
 
-func (s *Storage) DeleteEventsOlderThan(
-	ctx context.Context,
-	time time.Time,
-) error {
-	res, err := s.db.ExecContext(ctx, "delete from events where start_time <= $1", time)
+// scanEvent reads the eventColumns followed by the extra columns.
+// The driver returns the times in the local zone, they are converted to UTC like the other storages keep them.
+func scanEvent(row scanner, extra ...any) (model.Event, error) {
+	var event model.Event
+	var notify, description, calendarID sql.NullString
+	var notifyTime sql.NullTime
+	var reminders []byte
+	dest := []any{
+		&event.ID,
+		&event.Title,
+		&event.StartTime,
+		&event.EndTime,
+		&event.TimeZone,
+		&description,
+		&notify,
+		&notifyTime,
+		&event.OwnerEmail,
+		&calendarID,
+		&reminders,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return model.Event{}, err
+	}
+
+	event.StartTime = event.StartTime.UTC()
+	event.EndTime = event.EndTime.UTC()
+	event.Description = description.String
+	event.NotifyBefore = notify.String
+	if notifyTime.Valid {
+		event.NotifyTime = notifyTime.Time.UTC()
+	}
+	event.CalendarID = calendarID.String
+
+	var err error
+	event.Reminders, err = decodeReminders(reminders)
+	if err != nil {
+		return model.Event{}, err
+	}
+	return event, nil
+}
+
+func checkAffected(res sql.Result, err error, notFound error) error {
 	if err != nil {
 		return err
 	}
 
-	_, err = res.RowsAffected()
+	cnt, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if cnt == 0 {
+		return notFound
+	}
+
 	return nil
 }
+
+// checkDuplicate replaces the unique violation with the exists error.
+func checkDuplicate(err error, exists error) error {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return exists
+	}
+	return err
+}
+
+func eventNotFound(eventID string) error {
+	return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
+}
+
+func eventExists(eventID string) error {
+	return customerrors.AlreadyExists{Message: fmt.Sprintf("Event with id = \"%v\" already exists", eventID)}
+}
@@ -0,0 +1,157 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+func (s *Storage) AddCalendar(ctx context.Context, calendar model.Calendar) error {
+	_, err := s.conn(ctx).ExecContext(ctx,
+		`INSERT INTO calendars (id, name, owner_email) VALUES ($1, $2, $3)`,
+		calendar.ID,
+		calendar.Name,
+		calendar.OwnerEmail,
+	)
+	return checkDuplicate(err, calendarExists(calendar.ID))
+}
+
+func (s *Storage) GetCalendar(ctx context.Context, calendarID string) (model.Calendar, error) {
+	var calendar model.Calendar
+	err := s.conn(ctx).QueryRowContext(ctx,
+		`SELECT id, name, owner_email FROM calendars WHERE id = $1`,
+		calendarID,
+	).Scan(&calendar.ID, &calendar.Name, &calendar.OwnerEmail)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Calendar{}, calendarNotFound(calendarID)
+	}
+	if err != nil {
+		return model.Calendar{}, err
+	}
+
+	return calendar, nil
+}
+
+func (s *Storage) ListCalendars(ctx context.Context, email string) ([]model.Calendar, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT id, name, owner_email FROM calendars
+		WHERE owner_email = $1 OR id IN (SELECT calendar_id FROM calendar_grants WHERE email = $1)
+		ORDER BY id`,
+		email,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]model.Calendar, 0)
+	for rows.Next() {
+		var calendar model.Calendar
+		if err := rows.Scan(&calendar.ID, &calendar.Name, &calendar.OwnerEmail); err != nil {
+			return nil, err
+		}
+		result = append(result, calendar)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) SetCalendarGrant(ctx context.Context, grant model.Grant) error {
+	if _, err := s.GetCalendar(ctx, grant.CalendarID); err != nil {
+		return err
+	}
+
+	_, err := s.conn(ctx).ExecContext(ctx,
+		`INSERT INTO calendar_grants (calendar_id, email, role) VALUES ($1, $2, $3)
+		ON CONFLICT (calendar_id, email) DO UPDATE SET role = excluded.role`,
+		grant.CalendarID,
+		grant.Email,
+		string(grant.Role),
+	)
+	return err
+}
+
+func (s *Storage) GetCalendarGrant(ctx context.Context, calendarID, email string) (model.Grant, error) {
+	grant := model.Grant{CalendarID: calendarID, Email: email}
+	err := s.conn(ctx).QueryRowContext(ctx,
+		`SELECT role FROM calendar_grants WHERE calendar_id = $1 AND email = $2`,
+		calendarID,
+		email,
+	).Scan(&grant.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Grant{}, grantNotFound(calendarID, email)
+	}
+	if err != nil {
+		return model.Grant{}, err
+	}
+
+	return grant, nil
+}
+
+func (s *Storage) ListCalendarGrants(ctx context.Context, calendarID string) ([]model.Grant, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT email, role FROM calendar_grants WHERE calendar_id = $1 ORDER BY email`,
+		calendarID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]model.Grant, 0)
+	for rows.Next() {
+		grant := model.Grant{CalendarID: calendarID}
+		if err := rows.Scan(&grant.Email, &grant.Role); err != nil {
+			return nil, err
+		}
+		result = append(result, grant)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) DeleteCalendarGrant(ctx context.Context, calendarID, email string) error {
+	res, err := s.conn(ctx).ExecContext(ctx,
+		`DELETE FROM calendar_grants WHERE calendar_id = $1 AND email = $2`,
+		calendarID,
+		email,
+	)
+	return checkAffected(res, err, grantNotFound(calendarID, email))
+}
+
+func (s *Storage) ListCalendarEventsForPeriod(
+	ctx context.Context,
+	calendarID string,
+	startDate,
+	endDate time.Time,
+) ([]model.Event, error) {
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events
+		WHERE calendar_id = $1 AND start_time >= $2 AND start_time < $3
+		ORDER BY start_time, id`,
+		calendarID,
+		timeValue(startDate),
+		timeValue(endDate),
+	)
+}
+
+func calendarNotFound(calendarID string) error {
+	return customerrors.NotFound{Message: fmt.Sprintf("Calendar with id = \"%v\" not found", calendarID)}
+}
+
+func calendarExists(calendarID string) error {
+	return customerrors.AlreadyExists{Message: fmt.Sprintf("Calendar with id = \"%v\" already exists", calendarID)}
+}
+
+func grantNotFound(calendarID, email string) error {
+	return customerrors.NotFound{
+		Message: fmt.Sprintf("Access to calendar with id = \"%v\" is not granted to %v", calendarID, email),
+	}
+}
@@ -0,0 +1,140 @@
+package sqlitestorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/changefeed"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+//nolint:tagliatelle
+type changePayload struct {
+	ID           string     `json:"id"`
+	Title        string     `json:"title"`
+	StartTime    time.Time  `json:"start_time"`
+	EndTime      time.Time  `json:"end_time"`
+	Description  *string    `json:"description"`
+	NotifyBefore *string    `json:"notify_before"`
+	NotifyTime   *time.Time `json:"notify_time"`
+	OwnerEmail   string     `json:"owner_email"`
+	CalendarID   *string    `json:"calendar_id"`
+}
+
+func (s *Storage) WatchChanges(
+	ctx context.Context, ownerEmail string, fromRevision int64,
+) (<-chan model.Change, error) {
+	live, unsubscribe := s.hub.Subscribe(ownerEmail)
+
+	backlog := make([]model.Change, 0)
+	if fromRevision > 0 {
+		var err error
+		backlog, err = s.queryChanges(ctx,
+			`SELECT revision, change_type, payload FROM event_changes
+			WHERE owner_email = $1 AND revision > $2 ORDER BY revision`,
+			ownerEmail,
+			fromRevision,
+		)
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		// the check follows the query, so the changes pruned meanwhile are not missed
+		if err := s.checkRevision(ctx, fromRevision); err != nil {
+			unsubscribe()
+			return nil, err
+		}
+	}
+
+	return changefeed.Stream(ctx, fromRevision, backlog, live, unsubscribe), nil
+}
+
+func (s *Storage) DeleteChangesOlderThan(ctx context.Context, time time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM event_changes WHERE changed_at <= $1", timeValue(time))
+	return err
+}
+
+// publishChanges hands the changes logged by the triggers since the last call to the watchers.
+// Watchers are disconnected if the log cannot be read, they resume from their last revision.
+func (s *Storage) publishChanges(ctx context.Context) {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	changes, err := s.queryChanges(ctx,
+		"SELECT revision, change_type, payload FROM event_changes WHERE revision > $1 ORDER BY revision",
+		s.published,
+	)
+	if err != nil {
+		s.hub.CloseAll()
+		return
+	}
+
+	for _, change := range changes {
+		s.hub.Publish(change)
+		s.published = change.Revision
+	}
+}
+
+// checkRevision fails if the changes following fromRevision are pruned already.
+func (s *Storage) checkRevision(ctx context.Context, fromRevision int64) error {
+	var oldest int64
+	err := s.conn(ctx).QueryRowContext(ctx, "SELECT coalesce(min(revision), 0) FROM event_changes").Scan(&oldest)
+	if err != nil {
+		return err
+	}
+	if fromRevision < oldest-1 {
+		return customerrors.OutOfRange{Message: fmt.Sprintf("Revision %d is no longer available", fromRevision)}
+	}
+	return nil
+}
+
+func (s *Storage) queryChanges(ctx context.Context, query string, args ...any) ([]model.Change, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]model.Change, 0)
+	for rows.Next() {
+		var change model.Change
+		var changeType string
+		var data []byte
+		if err := rows.Scan(&change.Revision, &changeType, &data); err != nil {
+			return nil, err
+		}
+
+		var payload changePayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("cannot decode change %d: %w", change.Revision, err)
+		}
+
+		change.Type = model.ChangeType(changeType)
+		change.Event = model.Event{
+			ID:         payload.ID,
+			Title:      payload.Title,
+			StartTime:  payload.StartTime,
+			EndTime:    payload.EndTime,
+			OwnerEmail: payload.OwnerEmail,
+		}
+		if payload.Description != nil {
+			change.Event.Description = *payload.Description
+		}
+		if payload.NotifyBefore != nil {
+			change.Event.NotifyBefore = *payload.NotifyBefore
+		}
+		if payload.NotifyTime != nil {
+			change.Event.NotifyTime = *payload.NotifyTime
+		}
+		if payload.CalendarID != nil {
+			change.Event.CalendarID = *payload.CalendarID
+		}
+		result = append(result, change)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,26 @@
+package sqlitestorage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	sqlitestorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/sqlite"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Control {
+		t.Helper()
+
+		ctx := context.Background()
+		st := sqlitestorage.New(filepath.Join(t.TempDir(), "calendar.db"), true)
+		require.NoError(t, st.Connect(ctx))
+		t.Cleanup(func() { st.Close(ctx) })
+		// the initial migration inserts the sample events
+		require.NoError(t, st.Truncate(ctx))
+		return st
+	})
+}
@@ -0,0 +1,243 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+// remindersColumn aggregates the reminders of the selected event into a JSON array.
+const remindersColumn = `(SELECT json_group_array(json_object(
+			'id', r.id, 'notify_before', r.notify_before, 'channel', r.channel, 'target', r.target,
+			'notify_time', r.notify_time, 'snoozed_until', r.snoozed_until, 'claimed_until', r.claimed_until,
+			'notified_flag', json(CASE WHEN r.notified_flag THEN 'true' ELSE 'false' END)
+		) ORDER BY r.notify_time, r.id) FROM event_reminders r WHERE r.event_id = events.id)`
+
+const dueCondition = `NOT r.notified_flag AND coalesce(r.snoozed_until, r.notify_time) BETWEEN $1 AND $2`
+
+//nolint:tagliatelle
+type reminderPayload struct {
+	ID           string     `json:"id"`
+	NotifyBefore string     `json:"notify_before"`
+	Channel      string     `json:"channel"`
+	Target       *string    `json:"target"`
+	NotifyTime   time.Time  `json:"notify_time"`
+	SnoozedUntil *time.Time `json:"snoozed_until"`
+	ClaimedUntil *time.Time `json:"claimed_until"`
+	Notified     bool       `json:"notified_flag"`
+}
+
+func decodeReminders(data []byte) ([]model.Reminder, error) {
+	var payload []reminderPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("cannot decode reminders: %w", err)
+	}
+
+	reminders := make([]model.Reminder, 0, len(payload))
+	for _, p := range payload {
+		reminder := model.Reminder{
+			ID:         p.ID,
+			Before:     p.NotifyBefore,
+			Channel:    model.Channel(p.Channel),
+			NotifyTime: p.NotifyTime,
+			Notified:   p.Notified,
+		}
+		if p.Target != nil {
+			reminder.Target = *p.Target
+		}
+		if p.SnoozedUntil != nil {
+			reminder.SnoozedUntil = *p.SnoozedUntil
+		}
+		if p.ClaimedUntil != nil {
+			reminder.ClaimedUntil = *p.ClaimedUntil
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, nil
+}
+
+// saveReminders replaces the reminders of the event, it must run within a transaction.
+func (s *Storage) saveReminders(ctx context.Context, event model.Event) error {
+	if _, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM event_reminders WHERE event_id = $1", event.ID); err != nil {
+		return err
+	}
+
+	for _, reminder := range event.Reminders {
+		_, err := s.conn(ctx).ExecContext(ctx, `INSERT INTO event_reminders
+			(id, event_id, notify_before, channel, target, notify_time, snoozed_until, claimed_until, notified_flag)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			reminder.ID,
+			event.ID,
+			reminder.Before,
+			string(reminder.Channel),
+			nullString(reminder.Target),
+			timeValue(reminder.NotifyTime),
+			nullTime(reminder.SnoozedUntil),
+			nullTime(reminder.ClaimedUntil),
+			reminder.Notified,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) SetReminderNotified(ctx context.Context, reminderID string) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE event_reminders
+		SET notified_flag = true, claimed_until = NULL
+		WHERE id = $1`,
+		reminderID,
+	)
+	return checkAffected(res, err, reminderNotFound(reminderID))
+}
+
+func (s *Storage) SnoozeReminder(ctx context.Context, reminderID string, until time.Time) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE event_reminders
+		SET snoozed_until = $2, notified_flag = false, claimed_until = NULL
+		WHERE id = $1`,
+		reminderID,
+		timeValue(until),
+	)
+	return checkAffected(res, err, reminderNotFound(reminderID))
+}
+
+func (s *Storage) ListRemindersToBeNotified(
+	ctx context.Context,
+	startTime,
+	endTime time.Time,
+) ([]model.DueReminder, error) {
+	return s.queryDueReminders(ctx,
+		`SELECT `+dueReminderColumns+`
+		FROM event_reminders r JOIN events e ON e.id = r.event_id
+		WHERE `+dueCondition,
+		timeValue(startTime),
+		timeValue(endTime),
+	)
+}
+
+// ClaimDueReminders marks up to limit due reminders as dispatching until the claim expires.
+// The claims are taken within a transaction, SQLite runs them one at a time.
+func (s *Storage) ClaimDueReminders(
+	ctx context.Context,
+	since, now, claimUntil time.Time,
+	limit int,
+) ([]model.DueReminder, error) {
+	var result []model.DueReminder
+	err := s.InTransaction(ctx, func(ctx context.Context) error {
+		claimed, err := s.claimReminders(ctx, since, now, claimUntil, limit)
+		if err != nil {
+			return err
+		}
+
+		result, err = s.queryDueReminders(ctx,
+			`SELECT `+dueReminderColumns+`
+			FROM event_reminders r JOIN events e ON e.id = r.event_id
+			WHERE r.id IN (SELECT value FROM json_each($1))
+			ORDER BY coalesce(r.snoozed_until, r.notify_time), r.id`,
+			claimed,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// claimReminders returns the ids of the claimed reminders as a JSON array.
+func (s *Storage) claimReminders(ctx context.Context, since, now, claimUntil time.Time, limit int) (string, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`UPDATE event_reminders SET claimed_until = $3
+		WHERE id IN (
+			SELECT r.id FROM event_reminders r
+			WHERE `+dueCondition+` AND (r.claimed_until IS NULL OR r.claimed_until < $2)
+			ORDER BY coalesce(r.snoozed_until, r.notify_time), r.id
+			LIMIT CASE WHEN $4 > 0 THEN $4 ELSE -1 END
+		)
+		RETURNING id`,
+		timeValue(since),
+		timeValue(now),
+		timeValue(claimUntil),
+		limit,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return jsonList(ids)
+}
+
+const dueReminderColumns = `r.id, r.notify_before, r.channel, r.target, r.notify_time, r.snoozed_until, r.claimed_until,
+		e.id, e.title, e.start_time, e.end_time, e.description, e.owner_email, e.calendar_id`
+
+func (s *Storage) queryDueReminders(ctx context.Context, query string, args ...any) ([]model.DueReminder, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]model.DueReminder, 0)
+	for rows.Next() {
+		var due model.DueReminder
+		var target, description, calendarID sql.NullString
+		var notifyTime, snoozedUntil, claimedUntil, startTime, endTime timestamp
+		err := rows.Scan(&due.ID,
+			&due.Before,
+			&due.Channel,
+			&target,
+			&notifyTime,
+			&snoozedUntil,
+			&claimedUntil,
+			&due.Event.ID,
+			&due.Event.Title,
+			&startTime,
+			&endTime,
+			&description,
+			&due.Event.OwnerEmail,
+			&calendarID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		due.Target = target.String
+		due.NotifyTime = notifyTime.Time
+		due.SnoozedUntil = snoozedUntil.Time
+		due.ClaimedUntil = claimedUntil.Time
+		due.Event.StartTime = startTime.Time
+		due.Event.EndTime = endTime.Time
+		due.Event.Description = description.String
+		due.Event.CalendarID = calendarID.String
+		result = append(result, due)
+	}
+
+	return result, rows.Err()
+}
+
+func reminderNotFound(reminderID string) error {
+	return customerrors.NotFound{Message: fmt.Sprintf("Reminder with id = \"%v\" not found", reminderID)}
+}
@@ -0,0 +1,86 @@
+package sqlitestorage
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+)
+
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+func (s *Storage) SearchEvents(
+	ctx context.Context,
+	ownerEmail,
+	query string,
+	startDate,
+	endDate time.Time,
+	limit,
+	offset int,
+) ([]model.EventMatch, error) {
+	result := make([]model.EventMatch, 0)
+	match := matchExpression(query)
+	if match == "" {
+		return result, nil
+	}
+
+	// bm25 scores the better matches with the lower negative values
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT `+eventColumns+`, -bm25(events_search, $7, $8) AS rank,
+		highlight(events_search, 0, $9, $10), highlight(events_search, 1, $9, $10)
+		FROM events_search JOIN events ON events.rowid = events_search.rowid
+		WHERE events_search MATCH $2 AND events.owner_email = $1
+		AND events.start_time >= $3 AND events.start_time <= $4
+		ORDER BY rank DESC, events.start_time, events.id
+		LIMIT CASE WHEN $5 > 0 THEN $5 ELSE -1 END OFFSET $6`,
+		ownerEmail,
+		match,
+		timeValue(startDate),
+		timeValue(endDate),
+		limit,
+		offset,
+		titleWeight,
+		descriptionWeight,
+		model.HighlightStart,
+		model.HighlightStop,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var match model.EventMatch
+		var titleHighlight, descriptionHighlight *string
+		match.Event, err = scanEvent(rows, &match.Rank, &titleHighlight, &descriptionHighlight)
+		if err != nil {
+			return nil, err
+		}
+		if titleHighlight != nil {
+			match.TitleHighlight = *titleHighlight
+		}
+		if descriptionHighlight != nil {
+			match.DescriptionHighlight = *descriptionHighlight
+		}
+		result = append(result, match)
+	}
+
+	return result, rows.Err()
+}
+
+// matchExpression requires every word of the query, the words are quoted to keep them from the query syntax.
+func matchExpression(query string) string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, term := range terms {
+		terms[i] = `"` + term + `"`
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,369 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/migrate"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/changefeed"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/migrations"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// timeLayout keeps the width fixed, so the times stored as text compare in the chronological order.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+const eventColumns = `events.id, events.title, events.start_time, events.end_time, events.time_zone,
+		events.description, events.notify_before, events.notify_time, events.owner_email, events.calendar_id,
+		` + remindersColumn
+
+type Storage struct {
+	dsn         string
+	autoMigrate bool
+	db          *sql.DB
+	hub         *changefeed.Hub
+	publishMu   sync.Mutex
+	published   int64
+}
+
+type txKey struct{}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// New creates the storage keeping the database in the file at path, ":memory:" keeps it in memory.
+// With autoMigrate the pending migrations are applied on Connect.
+func New(path string, autoMigrate bool) *Storage {
+	return &Storage{
+		dsn: "file:" + path +
+			"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)",
+		autoMigrate: autoMigrate,
+		hub:         changefeed.NewHub(),
+	}
+}
+
+func (s *Storage) Connect(ctx context.Context) error {
+	var err error
+	s.db, err = sql.Open("sqlite", s.dsn)
+	if err != nil {
+		return fmt.Errorf("cannot open sqlite driver: %w", err)
+	}
+	// SQLite allows a single writer, the only connection serializes them instead of failing with busy errors
+	s.db.SetMaxOpenConns(1)
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if s.autoMigrate {
+		migrator, err := s.Migrator()
+		if err != nil {
+			return err
+		}
+		if _, err := migrator.Up(ctx); err != nil {
+			return fmt.Errorf("cannot migrate database: %w", err)
+		}
+	}
+
+	// the changes logged before start are available to the resuming watchers only
+	err = s.db.QueryRowContext(ctx, "SELECT coalesce(max(revision), 0) FROM event_changes").Scan(&s.published)
+	if err != nil {
+		return fmt.Errorf("cannot read the change log: %w", err)
+	}
+	return nil
+}
+
+// Migrator manages the schema of the connected database.
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	return migrate.New(s.db, migrations.SQLite(), migrate.SQLite)
+}
+
+func (s *Storage) Close(_ context.Context) error {
+	s.hub.CloseAll()
+	return s.db.Close()
+}
+
+// InTransaction runs fn within a transaction carried by the context passed to fn.
+// The changes of the events are published once the transaction is committed.
+func (s *Storage) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("cannot rollback transaction: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.publishChanges(context.WithoutCancel(ctx))
+	return nil
+}
+
+func (s *Storage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+func (s *Storage) Truncate(ctx context.Context) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		for _, table := range []string{"events", "event_changes", "events_archive", "calendars"} {
+			if _, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) AddEvent(ctx context.Context, event model.Event) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.conn(ctx).ExecContext(ctx, `INSERT INTO events
+			(id, title, start_time, end_time, description, notify_before, owner_email, notify_time, calendar_id, time_zone)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			event.ID,
+			event.Title,
+			timeValue(event.StartTime),
+			timeValue(event.EndTime),
+			event.Description,
+			event.NotifyBefore,
+			event.OwnerEmail,
+			timeValue(event.NotifyTime),
+			nullString(event.CalendarID),
+			event.TimeZone,
+		)
+		if err != nil {
+			return checkDuplicate(err, eventExists(event.ID))
+		}
+		return s.saveReminders(ctx, event)
+	})
+}
+
+func (s *Storage) UpdateEvent(ctx context.Context, event model.Event) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		res, err := s.conn(ctx).ExecContext(ctx, `UPDATE events
+			SET title = $2, start_time = $3, end_time = $4, description = $5,
+			notify_before = $6, owner_email = $7, notify_time = $8, calendar_id = $9, time_zone = $10
+			WHERE id = $1`,
+			event.ID,
+			event.Title,
+			timeValue(event.StartTime),
+			timeValue(event.EndTime),
+			event.Description,
+			event.NotifyBefore,
+			event.OwnerEmail,
+			timeValue(event.NotifyTime),
+			nullString(event.CalendarID),
+			event.TimeZone,
+		)
+		if err := checkAffected(res, err, eventNotFound(event.ID)); err != nil {
+			return err
+		}
+		return s.saveReminders(ctx, event)
+	})
+}
+
+func (s *Storage) GetEvent(ctx context.Context, eventID string) (model.Event, error) {
+	row := s.conn(ctx).QueryRowContext(ctx, `SELECT `+eventColumns+` FROM events WHERE id = $1`, eventID)
+	event, err := scanEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Event{}, eventNotFound(eventID)
+	}
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	return event, nil
+}
+
+func (s *Storage) DeleteEvent(ctx context.Context, eventID string) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		res, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM events WHERE id = $1", eventID)
+		return checkAffected(res, err, eventNotFound(eventID))
+	})
+}
+
+func (s *Storage) ListOwnerEventsForPeriod(
+	ctx context.Context,
+	ownerEmail string,
+	startDate,
+	endDate time.Time,
+) ([]model.Event, error) {
+	return s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events
+		WHERE owner_email = $1 AND start_time >= $2 AND start_time < $3
+		ORDER BY start_time, id`,
+		ownerEmail,
+		timeValue(startDate),
+		timeValue(endDate),
+	)
+}
+
+func (s *Storage) DeleteEventsOlderThan(ctx context.Context, time time.Time) error {
+	return s.InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.conn(ctx).ExecContext(ctx,
+			"DELETE FROM events WHERE start_time < $1", timeValue(time)); err != nil {
+			return err
+		}
+		return s.DeleteChangesOlderThan(ctx, time)
+	})
+}
+
+func (s *Storage) queryEvents(ctx context.Context, query string, args ...any) ([]model.Event, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]model.Event, 0)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, event)
+	}
+
+	return result, rows.Err()
+}
+
+// scanEvent reads the eventColumns followed by the extra destinations.
+func scanEvent(row scanner, extra ...any) (model.Event, error) {
+	var event model.Event
+	var description, notify, calendarID sql.NullString
+	var startTime, endTime, notifyTime timestamp
+	var reminders []byte
+	dest := append([]any{
+		&event.ID,
+		&event.Title,
+		&startTime,
+		&endTime,
+		&event.TimeZone,
+		&description,
+		&notify,
+		&notifyTime,
+		&event.OwnerEmail,
+		&calendarID,
+		&reminders,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return model.Event{}, err
+	}
+
+	event.StartTime = startTime.Time
+	event.EndTime = endTime.Time
+	event.Description = description.String
+	event.NotifyBefore = notify.String
+	event.NotifyTime = notifyTime.Time
+	event.CalendarID = calendarID.String
+
+	var err error
+	event.Reminders, err = decodeReminders(reminders)
+	if err != nil {
+		return model.Event{}, err
+	}
+	return event, nil
+}
+
+func checkAffected(res sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if cnt == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// checkDuplicate replaces the primary key violation with the exists error.
+func checkDuplicate(err error, exists error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return exists
+	}
+	return err
+}
+
+// timestamp scans the times stored as text.
+type timestamp struct {
+	time.Time
+}
+
+func (t *timestamp) Scan(src any) error {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	default:
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+func timeValue(value time.Time) string {
+	return value.UTC().Format(timeLayout)
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(value time.Time) any {
+	if value.IsZero() {
+		return nil
+	}
+	return timeValue(value)
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func eventNotFound(eventID string) error {
+	return customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", eventID)}
+}
+
+func eventExists(eventID string) error {
+	return customerrors.AlreadyExists{Message: fmt.Sprintf("Event with id = \"%v\" already exists", eventID)}
+}
@@ -0,0 +1,51 @@
+package sqlitestorage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "calendar.db")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+	event := model.Event{
+		ID:         "xxx",
+		Title:      "meeting",
+		StartTime:  start,
+		EndTime:    start.Add(time.Hour),
+		OwnerEmail: "user@example.com",
+		Reminders:  []model.Reminder{{ID: "r1", Channel: model.ChannelEmail, NotifyTime: start.Add(-time.Minute)}},
+	}
+
+	storage := New(path, true)
+	require.NoError(t, storage.Connect(ctx))
+	require.NoError(t, storage.AddEvent(ctx, event))
+	require.NoError(t, storage.AddEvent(ctx, model.Event{ID: "xxx2", OwnerEmail: event.OwnerEmail}))
+	require.NoError(t, storage.Close(ctx))
+
+	storage = New(path, true)
+	require.NoError(t, storage.Connect(ctx))
+	defer storage.Close(ctx)
+
+	ev, err := storage.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event.StartTime, ev.StartTime, "times keep the nanoseconds")
+	require.Len(t, ev.Reminders, 1)
+	require.True(t, event.Reminders[0].NotifyTime.Equal(ev.Reminders[0].NotifyTime))
+
+	changes, err := storage.WatchChanges(ctx, event.OwnerEmail, 1)
+	require.NoError(t, err)
+	select {
+	case change := <-changes:
+		require.Equal(t, model.ChangeCreated, change.Type)
+		require.Equal(t, "xxx2", change.Event.ID)
+	case <-time.After(time.Second):
+		require.FailNow(t, "logged change was not resumed")
+	}
+}
@@ -2,12 +2,15 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/migrate"
 	memorystorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
 	sqlstorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/sql"
+	sqlitestorage "github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/sqlite"
 )
 
 type (
@@ -17,9 +20,41 @@ type (
 		GetEvent(ctx context.Context, eventID string) (model.Event, error)
 		DeleteEvent(ctx context.Context, eventID string) error
 		DeleteEventsOlderThan(ctx context.Context, time time.Time) error
+		ListEventsToPurge(ctx context.Context, filter model.PurgeFilter, limit int) ([]model.Event, error)
+		ArchiveEvents(ctx context.Context, eventIDs []string) error
+		DeleteEvents(ctx context.Context, eventIDs []string) error
+		DeleteChangesOlderThan(ctx context.Context, time time.Time) error
+		// ListOwnerEventsForPeriod and ListCalendarEventsForPeriod list the events starting in [startDate, endDate).
 		ListOwnerEventsForPeriod(ctx context.Context, ownerEmail string, startDate, endDate time.Time) ([]model.Event, error)
-		ListEventsToBeNotified(ctx context.Context, startTime, endTime time.Time) ([]model.Event, error)
-		SetEventNotified(ctx context.Context, eventID string) error
+		SearchEvents(
+			ctx context.Context,
+			ownerEmail, query string,
+			startDate, endDate time.Time,
+			limit, offset int,
+		) ([]model.EventMatch, error)
+		ListRemindersToBeNotified(ctx context.Context, startTime, endTime time.Time) ([]model.DueReminder, error)
+		ClaimDueReminders(
+			ctx context.Context,
+			since, now, claimUntil time.Time,
+			limit int,
+		) ([]model.DueReminder, error)
+		SetReminderNotified(ctx context.Context, reminderID string) error
+		SnoozeReminder(ctx context.Context, reminderID string, until time.Time) error
+		InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+		// WatchChanges fails with customerrors.OutOfRange if the changes following fromRevision are pruned.
+		WatchChanges(ctx context.Context, ownerEmail string, fromRevision int64) (<-chan model.Change, error)
+		AddCalendar(ctx context.Context, calendar model.Calendar) error
+		GetCalendar(ctx context.Context, calendarID string) (model.Calendar, error)
+		ListCalendars(ctx context.Context, email string) ([]model.Calendar, error)
+		SetCalendarGrant(ctx context.Context, grant model.Grant) error
+		GetCalendarGrant(ctx context.Context, calendarID, email string) (model.Grant, error)
+		ListCalendarGrants(ctx context.Context, calendarID string) ([]model.Grant, error)
+		DeleteCalendarGrant(ctx context.Context, calendarID, email string) error
+		ListCalendarEventsForPeriod(
+			ctx context.Context,
+			calendarID string,
+			startDate, endDate time.Time,
+		) ([]model.Event, error)
 	}
 
 	Control interface {
@@ -28,12 +63,31 @@ type (
 		Close(ctx context.Context) error
 		Truncate(ctx context.Context) error
 	}
-)
 
-func NewStorage(c config.StorageConf) Control {
-	if c.Mode == config.StorageModePostgres {
-		return sqlstorage.New(c.Host, c.Port, c.DBName, c.User, c.Password)
+	// Migratable is implemented by the storages keeping a database schema.
+	Migratable interface {
+		Migrator() (*migrate.Migrator, error)
 	}
+)
 
-	return memorystorage.New()
+func NewStorage(c config.StorageConf) (Control, error) {
+	switch c.Mode {
+	case config.StorageModePostgres:
+		return sqlstorage.New(c.Host, c.Port, c.DBName, c.User, c.Password, c.AutoMigrate), nil
+	case config.StorageModeSQLite:
+		return sqlitestorage.New(c.Path, c.AutoMigrate), nil
+	default:
+		if c.Path == "" {
+			return memorystorage.New(), nil
+		}
+
+		var interval time.Duration
+		if c.SnapshotInterval != "" {
+			var err error
+			if interval, err = time.ParseDuration(c.SnapshotInterval); err != nil {
+				return nil, fmt.Errorf("invalid snapshot interval: %w", err)
+			}
+		}
+		return memorystorage.NewPersistent(c.Path, interval, c.WAL), nil
+	}
 }
@@ -0,0 +1,1358 @@
+// Package storagetest checks the storage backends behave the same way.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/customerrors"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/storage/model"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the conformance suite, every test gets an empty connected storage from newStorage.
+// The times compared exactly are truncated to microseconds, the precision of Postgres.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Control) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, st storage.Control)
+	}{
+		{"AddSuccess", testAddSuccess},
+		{"AddDuplicate", testAddDuplicate},
+		{"GetSuccess", testGetSuccess},
+		{"GetError", testGetError},
+		{"DeleteSuccess", testDeleteSuccess},
+		{"DeleteError", testDeleteError},
+		{"UpdateSuccess", testUpdateSuccess},
+		{"UpdateError", testUpdateError},
+		{"ListEventsForRange", testListEventsForRange},
+		{"ListRemindersToBeNotified", testListRemindersToBeNotified},
+		{"SetReminderNotified", testSetReminderNotified},
+		{"ClaimDueReminders", testClaimDueReminders},
+		{"SnoozeReminder", testSnoozeReminder},
+		{"DeleteOlderThanSuccess", testDeleteOlderThanSuccess},
+		{"SearchEvents", testSearchEvents},
+		{"SearchEventsHighlight", testSearchEventsHighlight},
+		{"SearchEventsIndexUpdate", testSearchEventsIndexUpdate},
+		{"TransactionRollback", testTransactionRollback},
+		{"WatchChanges", testWatchChanges},
+		{"WatchChangesTransaction", testWatchChangesTransaction},
+		{"WatchChangesCommitOrder", testWatchChangesCommitOrder},
+		{"WatchChangesOutOfRange", testWatchChangesOutOfRange},
+		{"Calendars", testCalendars},
+		{"Purge", testPurge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func testAddSuccess(t *testing.T, st storage.Control) {
+	tests := []struct {
+		add         model.Event
+		expectedErr error
+	}{
+		{
+			add: model.Event{
+				ID:           "id1",
+				Title:        "meeting 1",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "",
+			},
+		},
+		{
+			add: model.Event{
+				ID:           "id2",
+				Title:        "meeting 2",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			},
+		},
+		{
+			add: model.Event{
+				ID:           "id3",
+				Title:        "meeting 3",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				TimeZone:     "Europe/Moscow",
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "2024-01-01T11:00:00Z00:00",
+			},
+		},
+		{
+			add: model.Event{
+				ID:           "id4",
+				Title:        "meeting 4",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			err := st.AddEvent(ctx, tt.add)
+			require.NoError(t, err)
+			ev, err := st.GetEvent(ctx, tt.add.ID)
+			require.NoError(t, err)
+			require.Equal(t, tt.add.Title, ev.Title)
+			require.Equal(t, tt.add.StartTime, ev.StartTime)
+			require.Equal(t, tt.add.EndTime, ev.EndTime)
+			require.Equal(t, tt.add.TimeZone, ev.TimeZone)
+			require.Equal(t, tt.add.Description, ev.Description)
+			require.Equal(t, tt.add.OwnerEmail, ev.OwnerEmail)
+			require.Equal(t, tt.add.NotifyBefore, ev.NotifyBefore)
+		})
+	}
+}
+
+func testAddDuplicate(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := model.Event{
+		ID: "xxx", Title: "meeting", StartTime: start, EndTime: start.Add(time.Hour), OwnerEmail: "user@example.com",
+	}
+	require.NoError(t, st.AddEvent(ctx, event))
+
+	duplicate := event
+	duplicate.Title = "another meeting"
+	require.ErrorAs(t, st.AddEvent(ctx, duplicate), &customerrors.AlreadyExists{})
+	ev, err := st.GetEvent(ctx, "xxx")
+	require.NoError(t, err)
+	require.Equal(t, "meeting", ev.Title, "the existing event is kept")
+
+	calendar := model.Calendar{ID: "team", Name: "team", OwnerEmail: "user@example.com"}
+	require.NoError(t, st.AddCalendar(ctx, calendar))
+	require.ErrorAs(t, st.AddCalendar(ctx, calendar), &customerrors.AlreadyExists{})
+}
+
+func testGetSuccess(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		id       string
+		expected model.Event
+	}{
+		{
+			id: "xxx",
+			expected: model.Event{
+				ID:           "xxx",
+				Title:        "meeting 1",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "",
+			},
+		},
+		{
+			id: "xxx2",
+			expected: model.Event{
+				ID:           "xxx2",
+				Title:        "meeting 2",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			ev, err := st.GetEvent(ctx, tt.id)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected.Title, ev.Title)
+			require.Equal(t, tt.expected.StartTime, ev.StartTime)
+			require.Equal(t, tt.expected.EndTime, ev.EndTime)
+			require.Equal(t, tt.expected.Description, ev.Description)
+			require.Equal(t, tt.expected.OwnerEmail, ev.OwnerEmail)
+			require.Equal(t, tt.expected.NotifyBefore, ev.NotifyBefore)
+		})
+	}
+}
+
+func testGetError(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		id  string
+		err error
+	}{
+		{
+			id:  "xxx1",
+			err: customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", "xxx1")},
+		},
+		{
+			id:  "xxx3",
+			err: customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", "xxx1")},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			_, err := st.GetEvent(ctx, tt.id)
+			var custErr customerrors.NotFound
+			require.ErrorAs(t, err, &custErr)
+		})
+	}
+}
+
+func testDeleteSuccess(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		id string
+	}{
+		{
+			id: "xxx",
+		},
+		{
+			id: "xxx2",
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			err := st.DeleteEvent(ctx, tt.id)
+			require.NoError(t, err)
+
+			_, err = st.GetEvent(ctx, tt.id)
+			var custErr customerrors.NotFound
+			require.ErrorAs(t, err, &custErr)
+		})
+	}
+}
+
+func testDeleteError(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		id  string
+		err error
+	}{
+		{
+			id:  "xxx1",
+			err: customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", "xxx1")},
+		},
+		{
+			id:  "xxx3",
+			err: customerrors.NotFound{Message: fmt.Sprintf("Event with id = \"%v\" not found", "xxx1")},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			err := st.DeleteEvent(ctx, tt.id)
+			var custErr customerrors.NotFound
+			require.ErrorAs(t, err, &custErr)
+		})
+	}
+}
+
+func testUpdateSuccess(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		update model.Event
+	}{
+		{
+			update: model.Event{
+				ID:           "xxx",
+				Title:        "meeting 1",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "",
+			},
+		},
+		{
+			update: model.Event{
+				ID:           "xxx2",
+				Title:        "meeting 2",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				TimeZone:     "Asia/Tokyo",
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			err := st.UpdateEvent(ctx, tt.update)
+			require.NoError(t, err)
+
+			ev, err := st.GetEvent(ctx, tt.update.ID)
+			require.NoError(t, err)
+			require.Equal(t, tt.update.Title, ev.Title)
+			require.Equal(t, tt.update.StartTime, ev.StartTime)
+			require.Equal(t, tt.update.EndTime, ev.EndTime)
+			require.Equal(t, tt.update.TimeZone, ev.TimeZone)
+			require.Equal(t, tt.update.Description, ev.Description)
+			require.Equal(t, tt.update.OwnerEmail, ev.OwnerEmail)
+			require.Equal(t, tt.update.NotifyBefore, ev.NotifyBefore)
+		})
+	}
+}
+
+func testUpdateError(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		update model.Event
+	}{
+		{
+			update: model.Event{
+				ID:           "xxx1",
+				Title:        "meeting 1",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "",
+			},
+		},
+		{
+			update: model.Event{
+				ID:           "xxx3",
+				Title:        "meeting 2",
+				StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+				OwnerEmail:   "user@example.com",
+				NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			err := st.UpdateEvent(ctx, tt.update)
+			var custErr customerrors.NotFound
+			require.ErrorAs(t, err, &custErr)
+		})
+	}
+}
+
+func testListEventsForRange(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx1.1",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user1@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx1.2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 14, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user1@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+		{
+			ID:           "xxx1.3",
+			Title:        "meeting 3",
+			StartTime:    time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 2, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user1@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+		{
+			ID:         "xxx1.5",
+			Title:      "midnight meeting",
+			StartTime:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 2, 0, 30, 0, 0, time.UTC),
+			OwnerEmail: "user1@example.com",
+		},
+		{
+			ID:           "xxx3",
+			Title:        "meeting 4",
+			StartTime:    time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 3, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user2@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+		{
+			ID:         "xxx1.0",
+			Title:      "overnight meeting",
+			StartTime:  time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 2, 0, 30, 0, 0, time.UTC),
+			OwnerEmail: "user1@example.com",
+		},
+		{
+			ID:         "xxx1.4",
+			Title:      "meeting 5",
+			StartTime:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+			OwnerEmail: "user1@example.com",
+		},
+	}
+	tests := []struct {
+		user        string
+		start       time.Time
+		end         time.Time
+		expectedIDs []string
+	}{
+		{
+			user:        "user1@example.com",
+			start:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"xxx1.1", "xxx1.4", "xxx1.2", "xxx1.0"},
+		},
+		{
+			user:        "user1@example.com",
+			start:       time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"xxx1.5", "xxx1.3"},
+		},
+		{
+			user:        "user2@example.com",
+			start:       time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"xxx3"},
+		},
+		{
+			user:        "user3@example.com",
+			start:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			result, err := st.ListOwnerEventsForPeriod(ctx, tt.user, tt.start, tt.end)
+			require.NoError(t, err)
+			ids := make([]string, 0, len(result))
+			for _, ev := range result {
+				ids = append(ids, ev.ID)
+			}
+			require.Equal(t, tt.expectedIDs, ids, "events starting within [start, end) ordered by start time")
+		})
+	}
+}
+
+func testListRemindersToBeNotified(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx1.1",
+			Title:        "meeting 1",
+			StartTime:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user1@example.com",
+			NotifyBefore: "",
+			Reminders: []model.Reminder{
+				{ID: "r1.1", Channel: model.ChannelEmail, NotifyTime: time.Now().Add(-30 * time.Second)},
+			},
+		},
+		{
+			ID:           "xxx1.2",
+			Title:        "meeting 2",
+			StartTime:    time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 1, 14, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user1@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			Reminders: []model.Reminder{
+				{ID: "r1.2", Channel: model.ChannelEmail, NotifyTime: time.Now().Add(-45 * time.Second)},
+				{ID: "r1.2.1", Channel: model.ChannelEmail, NotifyTime: time.Now().Add(-50 * time.Second), Notified: true},
+			},
+		},
+		{
+			ID:           "xxx1.3",
+			Title:        "meeting 3",
+			StartTime:    time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 2, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user1@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+			Reminders: []model.Reminder{
+				{ID: "r1.3", Channel: model.ChannelWebhook, NotifyTime: time.Now().Add(-180 * time.Second)},
+				{ID: "r1.3.1", Channel: model.ChannelEmail, NotifyTime: time.Now().Add(time.Hour)},
+			},
+		},
+		{
+			ID:           "xxx3",
+			Title:        "meeting 4",
+			StartTime:    time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC),
+			EndTime:      time.Date(2024, 1, 3, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:   "user2@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		user        string
+		start       time.Time
+		end         time.Time
+		expectedCnt int
+	}{
+		{
+			user:        "user1@example.com",
+			start:       time.Now().Add(-5 * time.Minute),
+			end:         time.Now(),
+			expectedCnt: 3,
+		},
+		{
+			user:        "user1@example.com",
+			start:       time.Now().Add(-2 * time.Minute),
+			end:         time.Now(),
+			expectedCnt: 2,
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			result, err := st.ListRemindersToBeNotified(ctx, tt.start, tt.end)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCnt, len(result))
+		})
+	}
+}
+
+func testSetReminderNotified(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	notifyTime := time.Now().Add(-time.Minute)
+	err := st.AddEvent(ctx, model.Event{
+		ID:         "xxx",
+		Title:      "meeting",
+		StartTime:  time.Now(),
+		EndTime:    time.Now().Add(time.Hour),
+		OwnerEmail: "user@example.com",
+		Reminders: []model.Reminder{
+			{ID: "email", Channel: model.ChannelEmail, NotifyTime: notifyTime},
+			{ID: "webhook", Channel: model.ChannelWebhook, Target: "http://localhost/hook", NotifyTime: notifyTime},
+		},
+	})
+	require.NoError(t, err)
+	before, err := st.GetEvent(ctx, "xxx")
+	require.NoError(t, err)
+
+	require.NoError(t, st.SetReminderNotified(ctx, "email"))
+	require.ErrorAs(t, st.SetReminderNotified(ctx, "unknown"), &customerrors.NotFound{})
+
+	due, err := st.ListRemindersToBeNotified(ctx, notifyTime.Add(-time.Second), time.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, "webhook", due[0].ID)
+	require.Equal(t, "xxx", due[0].Event.ID)
+
+	after, err := st.GetEvent(ctx, "xxx")
+	require.NoError(t, err)
+	require.True(t, after.Reminders[0].Notified)
+	require.False(t, before.Reminders[0].Notified, "events handed out earlier must not change")
+}
+
+func testClaimDueReminders(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond)
+	reminders := make([]model.Reminder, 0)
+	for i := 0; i < 10; i++ {
+		reminders = append(reminders, model.Reminder{
+			ID:         fmt.Sprintf("r%d", i),
+			Channel:    model.ChannelEmail,
+			NotifyTime: now.Add(-time.Duration(i) * time.Second),
+		})
+	}
+	reminders = append(reminders, model.Reminder{ID: "later", NotifyTime: now.Add(time.Hour)})
+	err := st.AddEvent(ctx, model.Event{ID: "xxx", Title: "meeting", OwnerEmail: "user@example.com",
+		StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), Reminders: reminders})
+	require.NoError(t, err)
+
+	since := now.Add(-time.Minute)
+	claimed := make(chan string, len(reminders))
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				due, err := st.ClaimDueReminders(ctx, since, now, now.Add(time.Minute), 1)
+				if err != nil || len(due) == 0 {
+					return
+				}
+				claimed <- due[0].ID
+			}
+		}()
+	}
+	wg.Wait()
+	close(claimed)
+
+	ids := make(map[string]int)
+	for id := range claimed {
+		ids[id]++
+	}
+	require.Len(t, ids, 10, "every due reminder is claimed")
+	for id, cnt := range ids {
+		require.Equal(t, 1, cnt, "reminder %s claimed more than once", id)
+	}
+
+	require.NoError(t, st.SetReminderNotified(ctx, "r0"))
+	due, err := st.ClaimDueReminders(ctx, since, now.Add(2*time.Minute), now.Add(3*time.Minute), 0)
+	require.NoError(t, err)
+	require.Len(t, due, 9, "expired claims are taken over, notified reminders are not")
+	require.Equal(t, "r9", due[0].ID, "the longest overdue reminders go first")
+	require.True(t, now.Add(3*time.Minute).Equal(due[0].ClaimedUntil))
+}
+
+func testSnoozeReminder(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	notifyTime := time.Now().Add(-time.Minute)
+	err := st.AddEvent(ctx, model.Event{
+		ID:         "xxx",
+		Title:      "meeting",
+		StartTime:  time.Now(),
+		EndTime:    time.Now().Add(time.Hour),
+		OwnerEmail: "user@example.com",
+		Reminders:  []model.Reminder{{ID: "email", Channel: model.ChannelEmail, NotifyTime: notifyTime}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, st.SetReminderNotified(ctx, "email"))
+
+	until := time.Now().Add(10 * time.Minute).Truncate(time.Microsecond)
+	require.NoError(t, st.SnoozeReminder(ctx, "email", until))
+	require.ErrorAs(t, st.SnoozeReminder(ctx, "unknown", until), &customerrors.NotFound{})
+
+	due, err := st.ListRemindersToBeNotified(ctx, notifyTime.Add(-time.Second), time.Now())
+	require.NoError(t, err)
+	require.Empty(t, due, "snoozed reminder is not due at its original time")
+
+	due, err = st.ListRemindersToBeNotified(ctx, until.Add(-time.Second), until.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, "email", due[0].ID)
+	require.True(t, until.Equal(due[0].SnoozedUntil))
+	require.False(t, due[0].Notified)
+}
+
+func testDeleteOlderThanSuccess(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:           "xxx",
+			Title:        "meeting 1",
+			StartTime:    time.Now().Add(-60 * time.Minute),
+			EndTime:      time.Now(),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "",
+		},
+		{
+			ID:           "xxx2",
+			Title:        "meeting 2",
+			StartTime:    time.Now().Add(-120 * time.Minute),
+			EndTime:      time.Now(),
+			OwnerEmail:   "user@example.com",
+			NotifyBefore: "2024-01-01T12:00:00Z00:00",
+		},
+	}
+	tests := []struct {
+		time    time.Time
+		cntLeft int
+	}{
+		{
+			time:    time.Now().Add(-200 * time.Minute),
+			cntLeft: 2,
+		},
+		{
+			time:    time.Now().Add(-100 * time.Minute),
+			cntLeft: 1,
+		},
+		{
+			time:    time.Now(),
+			cntLeft: 0,
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+
+			err := st.DeleteEventsOlderThan(ctx, tt.time)
+			require.NoError(t, err)
+
+			res, err := st.ListOwnerEventsForPeriod(ctx, "user@example.com", time.Now().Add(-3*time.Hour), time.Now())
+			require.NoError(t, err)
+			require.Equal(t, tt.cntLeft, len(res))
+		})
+	}
+}
+
+func testSearchEvents(t *testing.T, st storage.Control) {
+	prerequisites := []model.Event{
+		{
+			ID:          "xxx",
+			Title:       "Budget review",
+			Description: "Discuss the budget for the next quarter",
+			StartTime:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:     time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:  "user@example.com",
+		},
+		{
+			ID:          "xxx2",
+			Title:       "Team sync",
+			Description: "Budget questions, hiring",
+			StartTime:   time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			EndTime:     time.Date(2024, 1, 2, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:  "user@example.com",
+		},
+		{
+			ID:          "xxx3",
+			Title:       "Budget review",
+			Description: "",
+			StartTime:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:     time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+			OwnerEmail:  "user2@example.com",
+		},
+	}
+	tests := []struct {
+		owner       string
+		query       string
+		startDate   time.Time
+		endDate     time.Time
+		limit       int
+		offset      int
+		expectedIDs []string
+	}{
+		{
+			owner:       "user@example.com",
+			query:       "budget",
+			startDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			endDate:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"xxx", "xxx2"},
+		},
+		{
+			owner:       "user@example.com",
+			query:       "BUDGET quarter",
+			startDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			endDate:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"xxx"},
+		},
+		{
+			owner:       "user@example.com",
+			query:       "budget",
+			startDate:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			endDate:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"xxx2"},
+		},
+		{
+			owner:       "user@example.com",
+			query:       "budget",
+			startDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			endDate:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			limit:       1,
+			offset:      1,
+			expectedIDs: []string{"xxx2"},
+		},
+		{
+			owner:       "user@example.com",
+			query:       "planning",
+			startDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			endDate:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{},
+		},
+		{
+			owner:       "user3@example.com",
+			query:       "budget",
+			startDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			endDate:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{},
+		},
+	}
+
+	ctx := context.Background()
+	for _, e := range prerequisites {
+		err := st.AddEvent(ctx, e)
+		require.NoError(t, err)
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+
+			res, err := st.SearchEvents(ctx, tt.owner, tt.query, tt.startDate, tt.endDate, tt.limit, tt.offset)
+			require.NoError(t, err)
+			ids := make([]string, 0, len(res))
+			for _, m := range res {
+				ids = append(ids, m.ID)
+			}
+			require.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func testSearchEventsHighlight(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	err := st.AddEvent(ctx, model.Event{
+		ID:          "xxx",
+		Title:       "Budget review",
+		Description: "Discuss the budget, then lunch",
+		StartTime:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndTime:     time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		OwnerEmail:  "user@example.com",
+	})
+	require.NoError(t, err)
+
+	res, err := st.SearchEvents(ctx, "user@example.com", "budget",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 0, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "<b>Budget</b> review", res[0].TitleHighlight)
+	require.Equal(t, "Discuss the <b>budget</b>, then lunch", res[0].DescriptionHighlight)
+	require.Greater(t, res[0].Rank, 0.0)
+}
+
+func testSearchEventsIndexUpdate(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	event := model.Event{
+		ID:         "xxx",
+		Title:      "Budget review",
+		StartTime:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		OwnerEmail: "user@example.com",
+	}
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	err := st.AddEvent(ctx, event)
+	require.NoError(t, err)
+
+	event.Title = "Hiring review"
+	err = st.UpdateEvent(ctx, event)
+	require.NoError(t, err)
+
+	res, err := st.SearchEvents(ctx, "user@example.com", "budget", startDate, endDate, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	res, err = st.SearchEvents(ctx, "user@example.com", "hiring", startDate, endDate, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	err = st.DeleteEvent(ctx, event.ID)
+	require.NoError(t, err)
+
+	res, err = st.SearchEvents(ctx, "user@example.com", "hiring", startDate, endDate, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, res)
+}
+
+func testTransactionRollback(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	event := model.Event{
+		ID:         "xxx",
+		Title:      "meeting 1",
+		StartTime:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		OwnerEmail: "user@example.com",
+		Reminders: []model.Reminder{
+			{ID: "reminder", Channel: model.ChannelEmail, NotifyTime: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		},
+	}
+	err := st.AddEvent(ctx, event)
+	require.NoError(t, err)
+	require.NoError(t, st.AddCalendar(ctx, model.Calendar{ID: "team", Name: "team", OwnerEmail: "user@example.com"}))
+	grant := model.Grant{CalendarID: "team", Email: "viewer@example.com", Role: model.RoleViewer}
+	require.NoError(t, st.SetCalendarGrant(ctx, grant))
+
+	errFailed := errors.New("failed")
+	err = st.InTransaction(ctx, func(ctx context.Context) error {
+		if err := st.SetReminderNotified(ctx, "reminder"); err != nil {
+			return err
+		}
+		if err := st.DeleteEvent(ctx, event.ID); err != nil {
+			return err
+		}
+		err := st.AddEvent(ctx, model.Event{ID: "xxx2", Title: "meeting 2", OwnerEmail: "user@example.com"})
+		if err != nil {
+			return err
+		}
+		err = st.AddCalendar(ctx, model.Calendar{ID: "other", Name: "other", OwnerEmail: "user@example.com"})
+		if err != nil {
+			return err
+		}
+		promoted := grant
+		promoted.Role = model.RoleEditor
+		if err := st.SetCalendarGrant(ctx, promoted); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+
+	ev, err := st.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event.Title, ev.Title)
+	require.False(t, ev.Reminders[0].Notified)
+	_, err = st.GetEvent(ctx, "xxx2")
+	require.Error(t, err)
+	_, err = st.GetCalendar(ctx, "other")
+	require.ErrorAs(t, err, &customerrors.NotFound{})
+	restored, err := st.GetCalendarGrant(ctx, "team", "viewer@example.com")
+	require.NoError(t, err)
+	require.Equal(t, model.RoleViewer, restored.Role)
+
+	res, err := st.SearchEvents(ctx, "user@example.com", "meeting",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 0, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	err = st.InTransaction(ctx, func(ctx context.Context) error {
+		return st.DeleteEvent(ctx, event.ID)
+	})
+	require.NoError(t, err)
+	_, err = st.GetEvent(ctx, event.ID)
+	require.Error(t, err)
+}
+
+func testWatchChanges(t *testing.T, st storage.Control) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	event := model.Event{
+		ID:         "xxx",
+		Title:      "meeting 1",
+		StartTime:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		OwnerEmail: "user@example.com",
+	}
+
+	changes, err := st.WatchChanges(ctx, "user@example.com", 0)
+	require.NoError(t, err)
+
+	err = st.AddEvent(ctx, event)
+	require.NoError(t, err)
+	err = st.AddEvent(ctx, model.Event{ID: "other", Title: "other", OwnerEmail: "user2@example.com"})
+	require.NoError(t, err)
+	event.Title = "meeting 2"
+	err = st.UpdateEvent(ctx, event)
+	require.NoError(t, err)
+	err = st.DeleteEvent(ctx, event.ID)
+	require.NoError(t, err)
+
+	expected := []model.ChangeType{model.ChangeCreated, model.ChangeUpdated, model.ChangeDeleted}
+	received := make([]model.Change, 0)
+	for range expected {
+		select {
+		case change := <-changes:
+			received = append(received, change)
+		case <-time.After(time.Second):
+			require.FailNow(t, "change was not received")
+		}
+	}
+	for i, change := range received {
+		require.Equal(t, expected[i], change.Type)
+		require.Equal(t, event.ID, change.Event.ID)
+	}
+	require.Equal(t, "meeting 2", received[1].Event.Title)
+
+	resumed, err := st.WatchChanges(ctx, "user@example.com", received[0].Revision)
+	require.NoError(t, err)
+	for _, exp := range received[1:] {
+		select {
+		case change := <-resumed:
+			require.Equal(t, exp, change)
+		case <-time.After(time.Second):
+			require.FailNow(t, "change was not resumed")
+		}
+	}
+
+	cancel()
+	_, ok := <-changes
+	require.False(t, ok, "channel should be closed after cancel")
+}
+
+func testWatchChangesTransaction(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+
+	changes, err := st.WatchChanges(ctx, "user@example.com", 0)
+	require.NoError(t, err)
+
+	err = st.InTransaction(ctx, func(ctx context.Context) error {
+		event := model.Event{ID: "xxx", Title: "meeting", OwnerEmail: "user@example.com"}
+		if err := st.AddEvent(ctx, event); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	require.Error(t, err)
+
+	err = st.InTransaction(ctx, func(ctx context.Context) error {
+		return st.AddEvent(ctx, model.Event{ID: "xxx2", Title: "meeting", OwnerEmail: "user@example.com"})
+	})
+	require.NoError(t, err)
+
+	select {
+	case change := <-changes:
+		require.Equal(t, "xxx2", change.Event.ID, "rolled back change should not be published")
+	case <-time.After(time.Second):
+		require.FailNow(t, "change was not received")
+	}
+}
+
+// testWatchChangesCommitOrder checks a change committed after a later started write is not lost,
+// the watchers skip the revisions lower than the last one they have seen.
+func testWatchChangesCommitOrder(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	changes, err := st.WatchChanges(ctx, "user@example.com", 0)
+	require.NoError(t, err)
+
+	firstAdded := make(chan struct{})
+	firstDone := make(chan error, 1)
+	go func() {
+		firstDone <- st.InTransaction(ctx, func(ctx context.Context) error {
+			err := st.AddEvent(ctx, model.Event{ID: "first", Title: "meeting", OwnerEmail: "user@example.com"})
+			close(firstAdded)
+			if err != nil {
+				return err
+			}
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+	}()
+	<-firstAdded
+	require.NoError(t, st.AddEvent(ctx, model.Event{ID: "second", Title: "meeting", OwnerEmail: "user@example.com"}))
+	require.NoError(t, <-firstDone)
+
+	received := make([]model.Change, 0, 2)
+	for len(received) < 2 {
+		select {
+		case change := <-changes:
+			received = append(received, change)
+		case <-time.After(time.Second):
+			require.FailNow(t, "change was lost", "received %v", received)
+		}
+	}
+	require.Equal(t, "first", received[0].Event.ID)
+	require.Equal(t, "second", received[1].Event.ID)
+	require.Less(t, received[0].Revision, received[1].Revision)
+}
+
+// testWatchChangesOutOfRange checks a watcher cannot resume from a revision whose following changes are pruned.
+func testWatchChangesOutOfRange(t *testing.T, st storage.Control) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := st.WatchChanges(ctx, "user@example.com", 0)
+	require.NoError(t, err)
+
+	revisions := make([]int64, 0, 3)
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			require.NoError(t, st.DeleteChangesOlderThan(ctx, time.Now().Add(time.Hour)))
+		}
+		require.NoError(t, st.AddEvent(ctx, model.Event{ID: fmt.Sprintf("id%d", i), OwnerEmail: "user@example.com"}))
+		select {
+		case change := <-changes:
+			revisions = append(revisions, change.Revision)
+		case <-time.After(time.Second):
+			require.FailNow(t, "change was not received")
+		}
+	}
+
+	_, err = st.WatchChanges(ctx, "user@example.com", revisions[0])
+	require.ErrorAs(t, err, &customerrors.OutOfRange{})
+
+	resumed, err := st.WatchChanges(ctx, "user@example.com", revisions[1])
+	require.NoError(t, err)
+	select {
+	case change := <-resumed:
+		require.Equal(t, revisions[2], change.Revision)
+	case <-time.After(time.Second):
+		require.FailNow(t, "change was not resumed")
+	}
+}
+
+func testCalendars(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	team := model.Calendar{ID: "team", Name: "team", OwnerEmail: "lead@example.com"}
+	require.NoError(t, st.AddCalendar(ctx, team))
+
+	require.NoError(t, st.SetCalendarGrant(ctx, model.Grant{
+		CalendarID: "team", Email: "dev@example.com", Role: model.RoleViewer,
+	}))
+	require.NoError(t, st.SetCalendarGrant(ctx, model.Grant{
+		CalendarID: "team", Email: "dev@example.com", Role: model.RoleEditor,
+	}))
+	err := st.SetCalendarGrant(ctx, model.Grant{CalendarID: "unknown", Email: "dev@example.com"})
+	require.ErrorAs(t, err, &customerrors.NotFound{})
+
+	grant, err := st.GetCalendarGrant(ctx, "team", "dev@example.com")
+	require.NoError(t, err)
+	require.Equal(t, model.RoleEditor, grant.Role)
+
+	for _, email := range []string{"lead@example.com", "dev@example.com"} {
+		calendars, err := st.ListCalendars(ctx, email)
+		require.NoError(t, err)
+		require.Equal(t, []model.Calendar{team}, calendars)
+	}
+
+	start := time.Date(2024, 11, 25, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, st.AddEvent(ctx, model.Event{
+		ID: "1", StartTime: start, EndTime: start.Add(time.Hour), OwnerEmail: "lead@example.com", CalendarID: "team",
+	}))
+	require.NoError(t, st.AddEvent(ctx, model.Event{
+		ID: "2", StartTime: start, EndTime: start.Add(time.Hour), OwnerEmail: "lead@example.com",
+	}))
+	require.NoError(t, st.AddEvent(ctx, model.Event{
+		ID:         "3",
+		StartTime:  start.Add(time.Hour),
+		EndTime:    start.Add(2 * time.Hour),
+		OwnerEmail: "lead@example.com",
+		CalendarID: "team",
+	}))
+	events, err := st.ListCalendarEventsForPeriod(ctx, "team", start.Add(-time.Hour), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 1, "the event starting at the end is left out")
+	require.Equal(t, "1", events[0].ID)
+
+	require.NoError(t, st.DeleteCalendarGrant(ctx, "team", "dev@example.com"))
+	require.ErrorAs(t, st.DeleteCalendarGrant(ctx, "team", "dev@example.com"), &customerrors.NotFound{})
+
+	calendars, err := st.ListCalendars(ctx, "dev@example.com")
+	require.NoError(t, err)
+	require.Empty(t, calendars)
+}
+
+func testPurge(t *testing.T, st storage.Control) {
+	ctx := context.Background()
+	require.NoError(t, st.AddCalendar(ctx, model.Calendar{ID: "team", Name: "team", OwnerEmail: "c@example.com"}))
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	owners := []string{"a@example.com", "b@example.com", "a@example.com", "c@example.com"}
+	for i, owner := range owners {
+		event := model.Event{
+			ID:         fmt.Sprintf("id%d", i),
+			Title:      "meeting",
+			StartTime:  start.Add(time.Duration(i) * time.Hour),
+			EndTime:    start.Add(time.Duration(i+1) * time.Hour),
+			OwnerEmail: owner,
+		}
+		if owner == "c@example.com" {
+			event.CalendarID = "team"
+		}
+		require.NoError(t, st.AddEvent(ctx, event))
+	}
+
+	before := start.Add(3 * time.Hour)
+	events, err := st.ListEventsToPurge(ctx, model.PurgeFilter{Before: before}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id0", "id1", "id2"}, eventIDs(events))
+
+	filter := model.PurgeFilter{Before: before, ExcludeOwners: []string{"b@example.com"}}
+	events, err = st.ListEventsToPurge(ctx, filter, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id0"}, eventIDs(events))
+
+	filter.AfterTime, filter.AfterID = events[0].StartTime, events[0].ID
+	events, err = st.ListEventsToPurge(ctx, filter, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id2"}, eventIDs(events))
+
+	events, err = st.ListEventsToPurge(ctx, model.PurgeFilter{
+		Before:           start.Add(time.Hour * 5),
+		OwnerEmail:       "c@example.com",
+		ExcludeCalendars: []string{"team"},
+	}, 0)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	require.NoError(t, st.InTransaction(ctx, func(ctx context.Context) error {
+		if err := st.ArchiveEvents(ctx, []string{"id0", "id1"}); err != nil {
+			return err
+		}
+		return st.DeleteEvents(ctx, []string{"id0", "id1", "unknown"})
+	}))
+	_, err = st.GetEvent(ctx, "id0")
+	require.ErrorAs(t, err, &customerrors.NotFound{})
+	_, err = st.GetEvent(ctx, "id2")
+	require.NoError(t, err)
+}
+
+func eventIDs(events []model.Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, ev := range events {
+		ids = append(ids, ev.ID)
+	}
+	return ids
+}
@@ -0,0 +1,69 @@
+package tlsconfig
+
+import (
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// checkInterval limits how often the files are checked for the changes, the handshakes in between reuse the value.
+const checkInterval = 10 * time.Second
+
+// reloading holds the value loaded from the files, it is loaded again when their modification times change.
+// The value which fails to load, like the certificate written before its key, keeps the previous one.
+type reloading[T any] struct {
+	mu        sync.Mutex
+	paths     []string
+	load      func() (T, error)
+	value     T
+	modTimes  []time.Time
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+func newReloading[T any](load func() (T, error), paths ...string) (*reloading[T], error) {
+	r := &reloading[T]{paths: paths, load: load, now: time.Now}
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r.value = value
+	r.modTimes = r.stat()
+	r.checkedAt = r.now()
+	return r, nil
+}
+
+func (r *reloading[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checkedAt) < checkInterval {
+		return r.value
+	}
+	r.checkedAt = now
+
+	modTimes := r.stat()
+	if slices.EqualFunc(modTimes, r.modTimes, time.Time.Equal) {
+		return r.value
+	}
+	if value, err := r.load(); err == nil {
+		r.value = value
+		r.modTimes = modTimes
+	}
+	return r.value
+}
+
+// stat returns the modification times, the zero time for the missing files.
+func (r *reloading[T]) stat() []time.Time {
+	modTimes := make([]time.Time, 0, len(r.paths))
+	for _, path := range r.paths {
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		modTimes = append(modTimes, modTime)
+	}
+	return modTimes
+}
@@ -0,0 +1,130 @@
+package tlsconfig
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+)
+
+// Server provides the TLS configuration of the servers, the certificate and the CA
+// are read again when their files change, so the renewed certificates need no restart.
+type Server struct {
+	cert       *reloading[*tls.Certificate]
+	clientCAs  *reloading[*x509.CertPool]
+	clientAuth bool
+}
+
+func NewServer(c config.TLSConf) (*Server, error) {
+	cert, err := newReloading(func() (*tls.Certificate, error) { return loadKeyPair(c.CertFile, c.KeyFile) },
+		c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cert: cert, clientAuth: c.ClientAuth}
+	if c.CAFile != "" {
+		s.clientCAs, err = newReloading(func() (*x509.CertPool, error) { return loadPool(c.CAFile) }, c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Config is the configuration of the listeners, the clients are asked for the certificate if the CA is set
+// and must present one if the client auth is required.
+func (s *Server) Config() *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the gRPC server and the HTTP server both serve with the configuration returned for the client
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.cert.get(), nil
+		},
+	}
+	if s.clientCAs == nil {
+		return base
+	}
+
+	base.ClientAuth = tls.VerifyClientCertIfGiven
+	if s.clientAuth {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := base.Clone()
+			c.ClientCAs = s.clientCAs.get()
+			return c, nil
+		},
+	}
+}
+
+// LoopbackConfig is the configuration of the clients in the same process, like the gateway.
+// They trust exactly the certificate the server presents and present it as the client certificate,
+// so with the client auth required the server certificate must be allowed for the client authentication.
+func (s *Server) LoopbackConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the peer is verified in VerifyConnection, the loopback address matches no certificate name
+		InsecureSkipVerify: true, //nolint:gosec
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 ||
+				!bytes.Equal(state.PeerCertificates[0].Raw, s.cert.get().Leaf.Raw) {
+				return errors.New("the server certificate is not the one of this process")
+			}
+			return nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.cert.get(), nil
+		},
+	}
+}
+
+// NewClientConfig creates the configuration of the connections to the servers, the CA replaces the system roots.
+// The client certificate is read again when its files change.
+func NewClientConfig(c config.TLSConf) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.ServerName}
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := newReloading(func() (*tls.Certificate, error) { return loadKeyPair(c.CertFile, c.KeyFile) },
+			c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert.get(), nil
+		}
+	}
+	return cfg, nil
+}
+
+func loadKeyPair(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+func loadPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("failed to load CA: no certificates in %v", caFile)
+	}
+	return pool, nil
+}
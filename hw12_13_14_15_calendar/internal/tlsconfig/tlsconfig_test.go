@@ -0,0 +1,232 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavel-nekrasov/otus_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T) authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "calendar test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return authority{cert: cert, key: key}
+}
+
+// issue writes the certificate allowed for the server and the client authentication and its key to dir.
+func (a authority) issue(t *testing.T, dir, name string, serial int64) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func (a authority) write(t *testing.T, dir string) string {
+	t.Helper()
+	caFile := filepath.Join(dir, "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", a.cert.Raw)
+	return caFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+// handshake connects the client to the server and returns the serial number of the server certificate.
+func handshake(t *testing.T, server, client *tls.Config) (*big.Int, error) {
+	t.Helper()
+	lsn, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer lsn.Close()
+
+	go func() {
+		conn, err := lsn.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if err := conn.(*tls.Conn).Handshake(); err == nil {
+			conn.Write([]byte{1})
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", lsn.Addr().String(), client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// the rejected client certificate fails the first read with TLS 1.3
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber, nil
+}
+
+func TestServerClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	caFile := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", 10)
+	clientCert, clientKey := ca.issue(t, dir, "client", 20)
+	strangerCert, strangerKey := newAuthority(t).issue(t, dir, "stranger", 30)
+
+	server, err := NewServer(config.TLSConf{
+		Enabled: true, CertFile: serverCert, KeyFile: serverKey, CAFile: caFile, ClientAuth: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		client config.TLSConf
+		valid  bool
+	}{
+		{
+			name:   "trusted client",
+			client: config.TLSConf{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile, ServerName: "localhost"},
+			valid:  true,
+		},
+		{
+			name:   "no client certificate",
+			client: config.TLSConf{CAFile: caFile, ServerName: "localhost"},
+		},
+		{
+			name:   "client of another CA",
+			client: config.TLSConf{CertFile: strangerCert, KeyFile: strangerKey, CAFile: caFile, ServerName: "localhost"},
+		},
+		{
+			name:   "server of unknown CA",
+			client: config.TLSConf{CertFile: clientCert, KeyFile: clientKey, ServerName: "localhost"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := NewClientConfig(tc.client)
+			require.NoError(t, err)
+			serial, err := handshake(t, server.Config(), client)
+			if !tc.valid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(10), serial.Int64())
+		})
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	caFile := ca.write(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 10)
+
+	server, err := NewServer(config.TLSConf{Enabled: true, CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	now := time.Now()
+	server.cert.now = func() time.Time { return now }
+	client, err := NewClientConfig(config.TLSConf{CAFile: caFile, ServerName: "localhost"})
+	require.NoError(t, err)
+
+	ca.issue(t, dir, "server", 11)
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+
+	serial, err := handshake(t, server.Config(), client)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), serial.Int64(), "the files are checked once in the interval")
+
+	now = now.Add(checkInterval)
+	serial, err = handshake(t, server.Config(), client)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), serial.Int64())
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, modTime.Add(time.Minute), modTime.Add(time.Minute)))
+	now = now.Add(checkInterval)
+	serial, err = handshake(t, server.Config(), client)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), serial.Int64(), "the broken files keep the previous certificate")
+}
+
+func TestServerLoopback(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	caFile := ca.write(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 10)
+	otherCert, otherKey := ca.issue(t, dir, "other", 20)
+
+	server, err := NewServer(config.TLSConf{
+		Enabled: true, CertFile: certFile, KeyFile: keyFile, CAFile: caFile, ClientAuth: true,
+	})
+	require.NoError(t, err)
+	other, err := NewServer(config.TLSConf{Enabled: true, CertFile: otherCert, KeyFile: otherKey})
+	require.NoError(t, err)
+
+	serial, err := handshake(t, server.Config(), server.LoopbackConfig())
+	require.NoError(t, err)
+	require.Equal(t, int64(10), serial.Int64())
+
+	_, err = handshake(t, other.Config(), server.LoopbackConfig())
+	require.Error(t, err, "the loopback client trusts its own server only")
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.pem")
+	require.NoError(t, os.WriteFile(garbage, []byte("garbage"), 0o600))
+
+	_, err := NewServer(config.TLSConf{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: garbage})
+	require.ErrorContains(t, err, "failed to load certificate")
+	_, err = NewClientConfig(config.TLSConf{CAFile: garbage})
+	require.ErrorContains(t, err, "no certificates")
+}
@@ -0,0 +1,75 @@
+// Package webhook keeps the webhook calls from reaching the host and the internal network.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("must not point to a loopback, private or link-local address")
+
+// sharedAddressSpace is the carrier-grade NAT range, the cloud providers serve their internal endpoints from it.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Forbidden tells whether the address belongs to the host or to the internal network.
+func Forbidden(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// CheckHost rejects the host if any of its addresses is forbidden. The host which does not resolve now
+// is let through, the address it resolves to is checked again by the client calling the webhook.
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return check(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := check(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewClient creates the client calling the webhooks. It refuses to connect to the forbidden addresses,
+// also the ones the host resolves to only at the time of the call and the ones the call is redirected to.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return check(net.ParseIP(host))
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		// no proxy, the checked address would be the one of the proxy
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func check(ip net.IP) error {
+	if ip == nil || Forbidden(ip) {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForbidden(t *testing.T) {
+	tests := []struct {
+		ip        string
+		forbidden bool
+	}{
+		{ip: "127.0.0.1", forbidden: true},
+		{ip: "::1", forbidden: true},
+		{ip: "10.0.0.1", forbidden: true},
+		{ip: "172.16.5.4", forbidden: true},
+		{ip: "192.168.1.1", forbidden: true},
+		{ip: "169.254.169.254", forbidden: true},
+		{ip: "fe80::1", forbidden: true},
+		{ip: "fd00::1", forbidden: true},
+		{ip: "100.100.100.200", forbidden: true},
+		{ip: "0.0.0.0", forbidden: true},
+		{ip: "::ffff:127.0.0.1", forbidden: true},
+		{ip: "93.184.216.34"},
+		{ip: "2606:2800:220:1::"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			require.Equal(t, tc.forbidden, Forbidden(net.ParseIP(tc.ip)))
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	require.ErrorIs(t, CheckHost(ctx, "127.0.0.1"), ErrForbiddenAddress)
+	require.ErrorIs(t, CheckHost(ctx, "localhost"), ErrForbiddenAddress)
+	require.NoError(t, CheckHost(ctx, "93.184.216.34"))
+	require.NoError(t, CheckHost(ctx, "unresolvable.invalid"), "checked again when called")
+}
+
+func TestClientRefusesForbiddenAddress(t *testing.T) {
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lsn.Close()
+
+	resp, err := NewClient(0).Get("http://" + lsn.Addr().String()) //nolint:noctx
+	if resp != nil {
+		resp.Body.Close()
+	}
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+}
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE events
+ADD search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX events_search_vector_idx ON events USING gin (search_vector);
+
+-- +goose Down
+DROP INDEX events_search_vector_idx;
+
+ALTER TABLE events
+DROP search_vector;
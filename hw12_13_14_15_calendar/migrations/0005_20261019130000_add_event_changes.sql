@@ -0,0 +1,53 @@
+-- +goose Up
+CREATE TABLE event_changes (
+    revision     bigserial    primary key,
+    event_id     varchar(255) not null,
+    owner_email  varchar(255) not null,
+    change_type  varchar(16)  not null,
+    payload      jsonb        not null,
+    changed_at   timestamptz  not null default now()
+);
+
+CREATE INDEX event_changes_owner_revision_idx ON event_changes (owner_email, revision);
+
+-- +goose StatementBegin
+CREATE FUNCTION log_event_change() RETURNS trigger AS $$
+DECLARE
+    rev bigint;
+BEGIN
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.owner_email <> NEW.owner_email) THEN
+        INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+        VALUES (OLD.id, OLD.owner_email, 'deleted', to_jsonb(OLD) - 'search_vector')
+        RETURNING revision INTO rev;
+        PERFORM pg_notify('event_changes', rev::text);
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (
+        NEW.id,
+        NEW.owner_email,
+        CASE WHEN TG_OP = 'INSERT' OR OLD.owner_email <> NEW.owner_email THEN 'created' ELSE 'updated' END,
+        to_jsonb(NEW) - 'search_vector'
+    )
+    RETURNING revision INTO rev;
+    PERFORM pg_notify('event_changes', rev::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER events_log_change
+AFTER INSERT OR UPDATE OR DELETE ON events
+FOR EACH ROW EXECUTE FUNCTION log_event_change();
+
+-- +goose Down
+DROP TRIGGER events_log_change ON events;
+
+DROP FUNCTION log_event_change();
+
+DROP TABLE event_changes;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE calendars (
+    id           varchar(255) primary key,
+    name         varchar(255) not null,
+    owner_email  varchar(255) not null
+);
+
+CREATE INDEX calendars_owner_email_idx ON calendars (owner_email);
+
+CREATE TABLE calendar_grants (
+    calendar_id  varchar(255) not null references calendars (id) on delete cascade,
+    email        varchar(255) not null,
+    role         varchar(16)  not null,
+    primary key (calendar_id, email)
+);
+
+CREATE INDEX calendar_grants_email_idx ON calendar_grants (email);
+
+ALTER TABLE events ADD COLUMN calendar_id varchar(255) references calendars (id) on delete set null;
+
+CREATE INDEX events_calendar_id_idx ON events (calendar_id, start_time);
+
+-- +goose Down
+ALTER TABLE events DROP COLUMN calendar_id;
+
+DROP TABLE calendar_grants;
+
+DROP TABLE calendars;
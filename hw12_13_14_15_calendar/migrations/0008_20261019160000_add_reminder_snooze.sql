@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE event_reminders ADD snoozed_until timestamptz;
+
+DROP INDEX event_reminders_due_idx;
+
+CREATE INDEX event_reminders_due_idx ON event_reminders ((coalesce(snoozed_until, notify_time))) WHERE NOT notified_flag;
+
+-- +goose Down
+DROP INDEX event_reminders_due_idx;
+
+CREATE INDEX event_reminders_due_idx ON event_reminders (notify_time) WHERE NOT notified_flag;
+
+ALTER TABLE event_reminders DROP snoozed_until;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE events_archive (
+    id              varchar(255) primary key,
+    title           varchar(255) not null,
+    start_time      timestamptz  not null,
+    end_time        timestamptz  not null,
+    description     text,
+    notify_before   varchar(255),
+    notify_time     timestamptz,
+    owner_email     varchar(255) not null,
+    calendar_id     varchar(255),
+    reminders       jsonb        not null default '[]',
+    archived_at     timestamptz  not null default now()
+);
+
+CREATE INDEX events_archive_owner_start_idx ON events_archive (owner_email, start_time);
+
+CREATE INDEX events_start_time_idx ON events (start_time, id);
+
+-- +goose Down
+DROP INDEX events_start_time_idx;
+
+DROP TABLE events_archive;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- the IANA zone the event is planned in, empty for the events created without one
+ALTER TABLE events ADD time_zone varchar(64) not null default '';
+ALTER TABLE events_archive ADD time_zone varchar(64) not null default '';
+
+-- +goose Down
+ALTER TABLE events_archive DROP time_zone;
+ALTER TABLE events DROP time_zone;
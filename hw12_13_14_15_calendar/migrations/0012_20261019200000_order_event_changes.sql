@@ -0,0 +1,70 @@
+-- +goose Up
+-- the event writers commit one by one, a watcher skipping the revisions it has seen
+-- must not miss a change committed later with a lower revision
+-- the single lock limits the event writes to one transaction at a time across all the instances,
+-- the throughput is bounded by the latency of a write transaction
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION log_event_change() RETURNS trigger AS $$
+DECLARE
+    rev bigint;
+BEGIN
+    -- the writers hold the lock till the commit, so the revisions grow in the commit order
+    PERFORM pg_advisory_xact_lock(1701147252);
+
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.owner_email <> NEW.owner_email) THEN
+        INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+        VALUES (OLD.id, OLD.owner_email, 'deleted', to_jsonb(OLD) - 'search_vector')
+        RETURNING revision INTO rev;
+        PERFORM pg_notify('event_changes', rev::text);
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (
+        NEW.id,
+        NEW.owner_email,
+        CASE WHEN TG_OP = 'INSERT' OR OLD.owner_email <> NEW.owner_email THEN 'created' ELSE 'updated' END,
+        to_jsonb(NEW) - 'search_vector'
+    )
+    RETURNING revision INTO rev;
+    PERFORM pg_notify('event_changes', rev::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION log_event_change() RETURNS trigger AS $$
+DECLARE
+    rev bigint;
+BEGIN
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.owner_email <> NEW.owner_email) THEN
+        INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+        VALUES (OLD.id, OLD.owner_email, 'deleted', to_jsonb(OLD) - 'search_vector')
+        RETURNING revision INTO rev;
+        PERFORM pg_notify('event_changes', rev::text);
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (
+        NEW.id,
+        NEW.owner_email,
+        CASE WHEN TG_OP = 'INSERT' OR OLD.owner_email <> NEW.owner_email THEN 'created' ELSE 'updated' END,
+        to_jsonb(NEW) - 'search_vector'
+    )
+    RETURNING revision INTO rev;
+    PERFORM pg_notify('event_changes', rev::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+// Package migrations embeds the database schema migrations in the goose format.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite returns the same migrations written for the SQLite dialect, they keep the versions and names of FS.
+func SQLite() fs.FS {
+	sub, err := fs.Sub(sqliteFS, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
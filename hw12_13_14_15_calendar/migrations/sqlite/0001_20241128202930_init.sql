@@ -0,0 +1,19 @@
+-- +goose Up
+-- times are kept as RFC 3339 text in UTC with nanoseconds, so they compare in the chronological order
+CREATE table events (
+    id              varchar(255) primary key,
+    title           varchar(255) not null,
+    start_time      text         not null,
+    end_time        text         not null,
+    description     text,
+    notify_before   varchar(255),
+    owner_email     varchar(255) not null
+);
+
+INSERT INTO events (id, title, start_time, end_time, description, notify_before, owner_email)
+VALUES
+    ('event-1', 'event-1', '2024-11-28T12:00:00.000000000Z', '2024-11-28T12:30:00.000000000Z', 'description 1', null, 'user1@example.com'),
+    ('event-2', 'event-2', '2024-11-28T12:00:00.000000000Z', '2024-11-28T12:30:00.000000000Z', 'description 2', null, 'user2@example.com');
+
+-- +goose Down
+drop table events;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE events
+ADD notify_time text;
+
+-- +goose Down
+ALTER TABLE events
+DROP notify_time;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- the full text index reads the content from events and is kept in sync by the triggers
+CREATE VIRTUAL TABLE events_search USING fts5(
+    title,
+    description,
+    content = 'events',
+    content_rowid = 'rowid',
+    tokenize = 'unicode61 remove_diacritics 0'
+);
+
+INSERT INTO events_search (events_search) VALUES ('rebuild');
+
+-- +goose StatementBegin
+CREATE TRIGGER events_search_insert AFTER INSERT ON events BEGIN
+    INSERT INTO events_search (rowid, title, description) VALUES (NEW.rowid, NEW.title, NEW.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_search_delete AFTER DELETE ON events BEGIN
+    INSERT INTO events_search (events_search, rowid, title, description)
+    VALUES ('delete', OLD.rowid, OLD.title, OLD.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_search_update AFTER UPDATE OF title, description ON events BEGIN
+    INSERT INTO events_search (events_search, rowid, title, description)
+    VALUES ('delete', OLD.rowid, OLD.title, OLD.description);
+    INSERT INTO events_search (rowid, title, description) VALUES (NEW.rowid, NEW.title, NEW.description);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER events_search_update;
+
+DROP TRIGGER events_search_delete;
+
+DROP TRIGGER events_search_insert;
+
+DROP TABLE events_search;
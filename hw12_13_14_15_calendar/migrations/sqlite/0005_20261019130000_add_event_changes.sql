@@ -0,0 +1,67 @@
+-- +goose Up
+CREATE TABLE event_changes (
+    revision     integer      primary key autoincrement,
+    event_id     varchar(255) not null,
+    owner_email  varchar(255) not null,
+    change_type  varchar(16)  not null,
+    payload      text         not null,
+    changed_at   text         not null default (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z')
+);
+
+CREATE INDEX event_changes_owner_revision_idx ON event_changes (owner_email, revision);
+
+-- the storage publishes the logged changes after commit, SQLite has no notifications
+-- +goose StatementBegin
+CREATE TRIGGER events_log_insert AFTER INSERT ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (NEW.id, NEW.owner_email, 'created', json_object(
+        'id', NEW.id, 'title', NEW.title, 'start_time', NEW.start_time, 'end_time', NEW.end_time,
+        'description', NEW.description, 'notify_before', NEW.notify_before, 'notify_time', NEW.notify_time,
+        'owner_email', NEW.owner_email
+    ));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_update AFTER UPDATE ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    SELECT OLD.id, OLD.owner_email, 'deleted', json_object(
+        'id', OLD.id, 'title', OLD.title, 'start_time', OLD.start_time, 'end_time', OLD.end_time,
+        'description', OLD.description, 'notify_before', OLD.notify_before, 'notify_time', OLD.notify_time,
+        'owner_email', OLD.owner_email
+    )
+    WHERE OLD.owner_email <> NEW.owner_email;
+
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (
+        NEW.id,
+        NEW.owner_email,
+        CASE WHEN OLD.owner_email <> NEW.owner_email THEN 'created' ELSE 'updated' END,
+        json_object(
+            'id', NEW.id, 'title', NEW.title, 'start_time', NEW.start_time, 'end_time', NEW.end_time,
+            'description', NEW.description, 'notify_before', NEW.notify_before, 'notify_time', NEW.notify_time,
+            'owner_email', NEW.owner_email
+        )
+    );
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_delete AFTER DELETE ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (OLD.id, OLD.owner_email, 'deleted', json_object(
+        'id', OLD.id, 'title', OLD.title, 'start_time', OLD.start_time, 'end_time', OLD.end_time,
+        'description', OLD.description, 'notify_before', OLD.notify_before, 'notify_time', OLD.notify_time,
+        'owner_email', OLD.owner_email
+    ));
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER events_log_delete;
+
+DROP TRIGGER events_log_update;
+
+DROP TRIGGER events_log_insert;
+
+DROP TABLE event_changes;
@@ -0,0 +1,135 @@
+-- +goose Up
+CREATE TABLE calendars (
+    id           varchar(255) primary key,
+    name         varchar(255) not null,
+    owner_email  varchar(255) not null
+);
+
+CREATE INDEX calendars_owner_email_idx ON calendars (owner_email);
+
+CREATE TABLE calendar_grants (
+    calendar_id  varchar(255) not null references calendars (id) on delete cascade,
+    email        varchar(255) not null,
+    role         varchar(16)  not null,
+    primary key (calendar_id, email)
+);
+
+CREATE INDEX calendar_grants_email_idx ON calendar_grants (email);
+
+ALTER TABLE events ADD COLUMN calendar_id varchar(255) references calendars (id) on delete set null;
+
+CREATE INDEX events_calendar_id_idx ON events (calendar_id, start_time);
+
+-- the logged payload includes the calendar like the whole row does in postgres
+DROP TRIGGER events_log_delete;
+
+DROP TRIGGER events_log_update;
+
+DROP TRIGGER events_log_insert;
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_insert AFTER INSERT ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (NEW.id, NEW.owner_email, 'created', json_object(
+        'id', NEW.id, 'title', NEW.title, 'start_time', NEW.start_time, 'end_time', NEW.end_time,
+        'description', NEW.description, 'notify_before', NEW.notify_before, 'notify_time', NEW.notify_time,
+        'owner_email', NEW.owner_email, 'calendar_id', NEW.calendar_id
+    ));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_update AFTER UPDATE ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    SELECT OLD.id, OLD.owner_email, 'deleted', json_object(
+        'id', OLD.id, 'title', OLD.title, 'start_time', OLD.start_time, 'end_time', OLD.end_time,
+        'description', OLD.description, 'notify_before', OLD.notify_before, 'notify_time', OLD.notify_time,
+        'owner_email', OLD.owner_email, 'calendar_id', OLD.calendar_id
+    )
+    WHERE OLD.owner_email <> NEW.owner_email;
+
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (
+        NEW.id,
+        NEW.owner_email,
+        CASE WHEN OLD.owner_email <> NEW.owner_email THEN 'created' ELSE 'updated' END,
+        json_object(
+            'id', NEW.id, 'title', NEW.title, 'start_time', NEW.start_time, 'end_time', NEW.end_time,
+            'description', NEW.description, 'notify_before', NEW.notify_before, 'notify_time', NEW.notify_time,
+            'owner_email', NEW.owner_email, 'calendar_id', NEW.calendar_id
+        )
+    );
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_delete AFTER DELETE ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (OLD.id, OLD.owner_email, 'deleted', json_object(
+        'id', OLD.id, 'title', OLD.title, 'start_time', OLD.start_time, 'end_time', OLD.end_time,
+        'description', OLD.description, 'notify_before', OLD.notify_before, 'notify_time', OLD.notify_time,
+        'owner_email', OLD.owner_email, 'calendar_id', OLD.calendar_id
+    ));
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER events_log_delete;
+
+DROP TRIGGER events_log_update;
+
+DROP TRIGGER events_log_insert;
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_insert AFTER INSERT ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (NEW.id, NEW.owner_email, 'created', json_object(
+        'id', NEW.id, 'title', NEW.title, 'start_time', NEW.start_time, 'end_time', NEW.end_time,
+        'description', NEW.description, 'notify_before', NEW.notify_before, 'notify_time', NEW.notify_time,
+        'owner_email', NEW.owner_email
+    ));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_update AFTER UPDATE ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    SELECT OLD.id, OLD.owner_email, 'deleted', json_object(
+        'id', OLD.id, 'title', OLD.title, 'start_time', OLD.start_time, 'end_time', OLD.end_time,
+        'description', OLD.description, 'notify_before', OLD.notify_before, 'notify_time', OLD.notify_time,
+        'owner_email', OLD.owner_email
+    )
+    WHERE OLD.owner_email <> NEW.owner_email;
+
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (
+        NEW.id,
+        NEW.owner_email,
+        CASE WHEN OLD.owner_email <> NEW.owner_email THEN 'created' ELSE 'updated' END,
+        json_object(
+            'id', NEW.id, 'title', NEW.title, 'start_time', NEW.start_time, 'end_time', NEW.end_time,
+            'description', NEW.description, 'notify_before', NEW.notify_before, 'notify_time', NEW.notify_time,
+            'owner_email', NEW.owner_email
+        )
+    );
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER events_log_delete AFTER DELETE ON events BEGIN
+    INSERT INTO event_changes (event_id, owner_email, change_type, payload)
+    VALUES (OLD.id, OLD.owner_email, 'deleted', json_object(
+        'id', OLD.id, 'title', OLD.title, 'start_time', OLD.start_time, 'end_time', OLD.end_time,
+        'description', OLD.description, 'notify_before', OLD.notify_before, 'notify_time', OLD.notify_time,
+        'owner_email', OLD.owner_email
+    ));
+END;
+-- +goose StatementEnd
+
+DROP INDEX events_calendar_id_idx;
+
+ALTER TABLE events DROP COLUMN calendar_id;
+
+DROP TABLE calendar_grants;
+
+DROP TABLE calendars;
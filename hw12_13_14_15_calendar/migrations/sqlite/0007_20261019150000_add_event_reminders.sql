@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE event_reminders (
+    id             varchar(255) primary key,
+    event_id       varchar(255) not null references events (id) on delete cascade,
+    notify_before  varchar(255) not null,
+    channel        varchar(16)  not null,
+    target         varchar(255),
+    notify_time    text         not null,
+    notified_flag  boolean      not null default false
+);
+
+CREATE INDEX event_reminders_event_id_idx ON event_reminders (event_id);
+
+CREATE INDEX event_reminders_due_idx ON event_reminders (notify_time) WHERE NOT notified_flag;
+
+-- the single reminder of existing events becomes their email reminder
+INSERT INTO event_reminders (id, event_id, notify_before, channel, notify_time, notified_flag)
+SELECT lower(hex(randomblob(16))), id, notify_before, 'email', notify_time, coalesce(notified_flag, false)
+FROM events
+WHERE coalesce(notify_before, '') <> '' AND notify_time IS NOT NULL;
+
+ALTER TABLE events DROP COLUMN notified_flag;
+
+-- +goose Down
+ALTER TABLE events ADD notified_flag boolean default false;
+
+UPDATE events SET notified_flag = true
+WHERE id IN (SELECT event_id FROM event_reminders WHERE notified_flag);
+
+DROP TABLE event_reminders;
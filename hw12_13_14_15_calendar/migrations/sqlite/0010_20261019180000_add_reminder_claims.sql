@@ -0,0 +1,6 @@
+-- +goose Up
+-- a reminder is being dispatched by a scheduler until the claim expires
+ALTER TABLE event_reminders ADD claimed_until text;
+
+-- +goose Down
+ALTER TABLE event_reminders DROP claimed_until;
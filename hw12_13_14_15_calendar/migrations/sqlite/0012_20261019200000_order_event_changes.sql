@@ -0,0 +1,4 @@
+-- +goose Up
+-- SQLite runs one writer at a time, the revisions already grow in the commit order
+
+-- +goose Down
@@ -32,10 +32,14 @@ func TestAppIntegrationSuite(t *testing.T) {
 }
 
 func (s *CalendarIntegrationSuite) SetupSuite() {
-	s.calendarConfig = config.NewCalendarConfig("/app/configs/calendar_config.toml")
-	s.scheduleConfig = config.NewSchedulerConfig("/app/configs/scheduler_config.toml")
+	var err error
+	s.calendarConfig, err = config.NewCalendarConfig("/app/configs/calendar_config.toml", nil)
+	s.Require().NoError(err)
+	s.scheduleConfig, err = config.NewSchedulerConfig("/app/configs/scheduler_config.toml", nil)
+	s.Require().NoError(err)
 	s.logger = logger.New(s.calendarConfig.Logger.Level, s.calendarConfig.Logger.Output)
-	s.storage = storage.NewStorage(s.calendarConfig.Storage)
+	s.storage, err = storage.NewStorage(s.calendarConfig.Storage)
+	s.Require().NoError(err)
 }
 
 func (s *CalendarIntegrationSuite) SetupTest() {
@@ -219,5 +223,6 @@ func (s *CalendarIntegrationSuite) TestSenderSendNotification() {
 
 	dbEvent, err := s.storage.GetEvent(context.Background(), event.Id)
 	s.Suite.Require().NoError(err, "event not found")
-	s.Suite.Require().True(dbEvent.Notified, "should be notified")
+	s.Suite.Require().Len(dbEvent.Reminders, 1, "notify should create a reminder")
+	s.Suite.Require().True(dbEvent.Reminders[0].Notified, "should be notified")
 }